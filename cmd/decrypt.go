@@ -0,0 +1,168 @@
+package main
+
+import (
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"time"
+
+	"quanticfy-test/internal/audit"
+	"quanticfy-test/internal/config"
+	"quanticfy-test/internal/database"
+	"quanticfy-test/internal/exporter"
+	"quanticfy-test/internal/loader"
+	"quanticfy-test/internal/privacy"
+	"quanticfy-test/pkg/logger"
+)
+
+// runDecrypt implements `quanticfy decrypt -table <name> [flags]` and
+// `quanticfy decrypt -value <ciphertext> -key-id <id> -customer <id> [-column <name>]`
+func runDecrypt(args []string) {
+	fs := flag.NewFlagSet("decrypt", flag.ExitOnError)
+	table := fs.String("table", "", "export table to decrypt, e.g. test_export_20240101")
+	customerID := fs.Int64("customer", 0, "only decrypt this CustomerID (required with -value)")
+	value := fs.String("value", "", "decrypt a single encrypted value instead of a table")
+	keyID := fs.String("key-id", "", "EmailKeyID of -value")
+	column := fs.String("column", "Email", "column -value was read from: Email, FallbackContact or a contact column")
+	output := fs.String("out", "", "CSV file to write CustomerID and the decrypted columns to (default stdout)")
+	reason := fs.String("reason", "", "reason stored in the audit record")
+	auditLog := fs.String("audit-log", "", "audit log file (default AUDIT_LOG_PATH)")
+	fs.Parse(args)
+
+	if (*table == "") == (*value == "") || (*value != "" && (*keyID == "" || *customerID == 0)) {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	log := logger.Default()
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		log.Fatal("Failed to load configuration", logger.Err(err))
+	}
+	log = newLogger(cfg, logger.NewRunID())
+	if *auditLog == "" {
+		*auditLog = cfg.AuditLogPath
+	}
+	if *auditLog == "" {
+		log.Fatal("An audit log is required to decrypt: set AUDIT_LOG_PATH or -audit-log")
+	}
+	keyring, err := newKeyring(cfg)
+	if err != nil {
+		log.Fatal("Invalid configuration", logger.Err(err))
+	}
+	encryptor := privacy.NewEncryptor(keyring, cfg.EmailEncryptionDeterministic)
+	trail := audit.NewTrail(*auditLog, log)
+
+	if *value != "" {
+		plaintext, err := encryptor.Decrypt(*value, *keyID, *customerID, *column)
+		if err != nil {
+			log.Fatal("Failed to decrypt value", logger.Err(err))
+		}
+		record := audit.Record{
+			Time:       time.Now(),
+			Action:     "decrypt_value",
+			CustomerID: *customerID,
+			Reason:     *reason,
+			Details: map[string]interface{}{
+				"column": *column,
+				"key_id": *keyID,
+			},
+		}
+		// The value is only printed once its decryption is on record
+		if err := trail.Record(record); err != nil {
+			log.Fatal("Failed to write audit record", logger.Err(err))
+		}
+		fmt.Println(plaintext)
+		return
+	}
+
+	conn, err := database.NewConnection(newDBConfig(cfg))
+	if err != nil {
+		log.Fatal("Failed to connect to database", logger.Err(err))
+	}
+	defer conn.Close()
+
+	// Contact columns are named after their channel, whatever EXPORT_CHANNELS
+	// was when the table was written
+	channelTypes, err := loader.NewLoader(conn.DB, log).LoadChannelTypes()
+	if err != nil {
+		log.Fatal("Failed to load channel types", logger.Err(err))
+	}
+	channels := make([]string, 0, len(channelTypes))
+	for _, name := range channelTypes {
+		channels = append(channels, name)
+	}
+
+	columns, rows, err := exporter.NewExporter(conn.DB, log).ReadEncryptedRows(*table, channels, *customerID)
+	if err != nil {
+		log.Fatal("Failed to read encrypted rows", logger.Err(err))
+	}
+
+	records := make([][]string, 0, len(rows)+1)
+	records = append(records, append([]string{"CustomerID"}, columns...))
+	for _, row := range rows {
+		record := []string{strconv.FormatInt(row.CustomerID, 10)}
+		for i, encrypted := range row.Values {
+			plaintext, err := encryptor.Decrypt(encrypted, row.KeyID, row.CustomerID, columns[i])
+			if err != nil {
+				log.Fatal("Failed to decrypt value", "customer_id", row.CustomerID, "column", columns[i], logger.Err(err))
+			}
+			record = append(record, plaintext)
+		}
+		records = append(records, record)
+	}
+
+	record := audit.Record{
+		Time:       time.Now(),
+		Action:     "decrypt_table",
+		CustomerID: *customerID,
+		Reason:     *reason,
+		Details: map[string]interface{}{
+			"table":   *table,
+			"columns": columns,
+			"rows":    len(rows),
+			"output":  *output,
+		},
+	}
+	// As for single values, nothing is written before the audit record
+	if err := trail.Record(record); err != nil {
+		log.Fatal("Failed to write audit record", logger.Err(err))
+	}
+
+	var out io.Writer = os.Stdout
+	if *output != "" {
+		f, err := os.OpenFile(*output, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o600)
+		if err != nil {
+			log.Fatal("Failed to create output file", logger.Err(err))
+		}
+		defer f.Close()
+		out = f
+	}
+
+	w := csv.NewWriter(out)
+	if err := w.WriteAll(records); err != nil {
+		log.Fatal("Failed to write decrypted rows", logger.Err(err))
+	}
+
+	log.Info("Decrypted table", "table", *table, "columns", len(columns), logger.Rows(len(rows)))
+}
+
+// newKeyring reads the email encryption keys from EMAIL_ENCRYPTION_KEYS or
+// EMAIL_ENCRYPTION_KEY_FILE
+func newKeyring(cfg *config.Config) (*privacy.Keyring, error) {
+	switch {
+	case cfg.EmailEncryptionKeys != "":
+		keyring, err := privacy.ParseKeyring(cfg.EmailEncryptionKeys, cfg.EmailEncryptionKeyID)
+		if err != nil {
+			return nil, fmt.Errorf("EMAIL_ENCRYPTION_KEYS: %w", err)
+		}
+		return keyring, nil
+	case cfg.EmailEncryptionKeyFile != "":
+		return privacy.ReadKeyringFile(cfg.EmailEncryptionKeyFile, cfg.EmailEncryptionKeyID)
+	default:
+		return nil, fmt.Errorf("set EMAIL_ENCRYPTION_KEYS or EMAIL_ENCRYPTION_KEY_FILE")
+	}
+}
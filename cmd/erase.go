@@ -0,0 +1,109 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"strings"
+	"time"
+
+	"quanticfy-test/internal/audit"
+	"quanticfy-test/internal/config"
+	"quanticfy-test/internal/database"
+	"quanticfy-test/internal/exporter"
+	"quanticfy-test/pkg/logger"
+)
+
+// runErase implements `quanticfy erase -customer <id> [flags]`
+func runErase(args []string) {
+	fs := flag.NewFlagSet("erase", flag.ExitOnError)
+	customerID := fs.Int64("customer", 0, "CustomerID to erase")
+	reason := fs.String("reason", "right to be forgotten", "reason stored in the audit record")
+	files := fs.String("files", "", "comma-separated extra JSON or CSV outputs to clean (DQ_REPORT_PATH and OUTLIER_REPORT_PATH are always cleaned)")
+	suppress := fs.Bool("suppress", true, "add the customer to CustomerExclusion so later runs skip it")
+	auditLog := fs.String("audit-log", "", "audit log file (default AUDIT_LOG_PATH)")
+	fs.Parse(args)
+
+	if *customerID <= 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	log := logger.Default()
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		log.Fatal("Failed to load configuration", logger.Err(err))
+	}
+	log = newLogger(cfg, logger.NewRunID())
+	if *auditLog == "" {
+		*auditLog = cfg.AuditLogPath
+	}
+	if *auditLog == "" {
+		log.Fatal("An audit log is required to erase a customer: set AUDIT_LOG_PATH or -audit-log")
+	}
+
+	conn, err := database.NewConnection(newDBConfig(cfg))
+	if err != nil {
+		log.Fatal("Failed to connect to database", logger.Err(err))
+	}
+	defer conn.Close()
+
+	// The erase is recorded before anything is deleted, so that a failure
+	// part-way through still leaves a trace in the audit log
+	trail := audit.NewTrail(*auditLog, log)
+	started := audit.Record{
+		Time:       time.Now(),
+		Action:     "erase_started",
+		CustomerID: *customerID,
+		Reason:     *reason,
+		Details: map[string]interface{}{
+			"suppress": *suppress,
+		},
+	}
+	if err := trail.Record(started); err != nil {
+		log.Fatal("Failed to write audit record", logger.Err(err))
+	}
+
+	exp := exporter.NewExporter(conn.DB, log)
+	tables, err := exp.EraseCustomer(*customerID)
+	if err != nil {
+		log.Fatal("Failed to erase customer from tables", logger.Err(err))
+	}
+
+	var paths []string
+	for _, path := range append([]string{cfg.DQReportPath, cfg.OutlierReportPath}, strings.Split(*files, ",")...) {
+		if path = strings.TrimSpace(path); path != "" {
+			paths = append(paths, path)
+		}
+	}
+	fileEntries, err := exp.EraseCustomerFromFiles(paths, *customerID)
+	if err != nil {
+		log.Fatal("Failed to erase customer from files", logger.Err(err))
+	}
+
+	if *suppress {
+		if err := exp.SuppressCustomer(*customerID, *reason); err != nil {
+			log.Fatal("Failed to suppress customer", logger.Err(err))
+		}
+	}
+
+	var rows int64
+	for _, n := range tables {
+		rows += n
+	}
+	record := audit.Record{
+		Time:       time.Now(),
+		Action:     "erase",
+		CustomerID: *customerID,
+		Reason:     *reason,
+		Details: map[string]interface{}{
+			"tables":     tables,
+			"files":      fileEntries,
+			"suppressed": *suppress,
+		},
+	}
+	if err := trail.Record(record); err != nil {
+		log.Fatal("Failed to write audit record", logger.Err(err))
+	}
+
+	log.Info("Erased customer", "customer_id", *customerID, logger.Rows(int(rows)), "tables", len(tables), "files", len(fileEntries))
+}
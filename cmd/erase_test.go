@@ -0,0 +1,96 @@
+package main
+
+import (
+	"database/sql/driver"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+
+	"quanticfy-test/internal/config"
+	"quanticfy-test/internal/exporter"
+	"quanticfy-test/internal/loader"
+	"quanticfy-test/internal/models"
+	"quanticfy-test/internal/processor"
+	"quanticfy-test/pkg/logger"
+)
+
+// argRecorder records every argument sent to the mock database
+type argRecorder struct {
+	args []driver.Value
+}
+
+func (r *argRecorder) ConvertValue(v interface{}) (driver.Value, error) {
+	value, err := driver.DefaultParameterConverter.ConvertValue(v)
+	r.args = append(r.args, value)
+	return value, err
+}
+
+func TestErasedCustomerIsNotExported(t *testing.T) {
+	tests := []struct {
+		name             string
+		exclusionsFromDB bool
+		query            string
+	}{
+		{name: "table rules disabled", exclusionsFromDB: false, query: `FROM CustomerExclusion WHERE RuleType = 'customer_id'`},
+		{name: "table rules enabled", exclusionsFromDB: true, query: `FROM CustomerExclusion ORDER BY`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := &argRecorder{}
+			db, mock, err := sqlmock.New(sqlmock.ValueConverterOption(recorder))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer db.Close()
+
+			// erase -customer 42
+			exp := exporter.NewExporter(db, logger.Discard())
+			mock.ExpectExec(`INSERT INTO CustomerExclusion`).WithArgs("42", "demande RGPD").
+				WillReturnResult(sqlmock.NewResult(1, 1))
+			if err := exp.SuppressCustomer(42, "demande RGPD"); err != nil {
+				t.Fatalf("SuppressCustomer: %v", err)
+			}
+
+			// Next pipeline run
+			mock.ExpectQuery(tt.query).WillReturnRows(
+				sqlmock.NewRows([]string{"RuleType", "Value", "Reason"}).AddRow("customer_id", "42", "demande RGPD"))
+			cfg := &config.Config{ExclusionsFromDB: tt.exclusionsFromDB}
+			rules, err := loadExclusionRules(cfg, loader.NewLoader(db, logger.Discard()))
+			if err != nil {
+				t.Fatalf("loadExclusionRules: %v", err)
+			}
+
+			revenueMap := map[int64]*models.CustomerRevenue{
+				7:  {CustomerID: 7, Email: "a@example.com", Revenue: 10},
+				42: {CustomerID: 42, Email: "b@example.com", Revenue: 20},
+			}
+			kept, _ := processor.NewProcessor(0.5, logger.Discard()).ApplyExclusions(revenueMap, rules, nil)
+
+			columns := sqlmock.NewRows([]string{"COLUMN_NAME", "COLUMN_TYPE", "IS_NULLABLE"})
+			mock.ExpectExec(`CREATE TABLE IF NOT EXISTS test_export_\d{8} LIKE test_export_template`).
+				WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectQuery(`FROM information_schema.COLUMNS`).WillReturnRows(columns)
+			mock.ExpectQuery(`FROM information_schema.COLUMNS`).WillReturnRows(columns)
+			mock.ExpectExec(`DELETE FROM test_export_\d{8}`).WillReturnResult(sqlmock.NewResult(0, 1))
+			mock.ExpectExec(`INSERT INTO test_export_\d{8}`).WillReturnResult(sqlmock.NewResult(0, 1))
+			recorder.args = nil
+			if err := exp.ExportTopCustomers(kept); err != nil {
+				t.Fatalf("ExportTopCustomers: %v", err)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Fatal(err)
+			}
+
+			var exported []int64
+			for _, arg := range recorder.args {
+				if id, ok := arg.(int64); ok && (id == 7 || id == 42) {
+					exported = append(exported, id)
+				}
+			}
+			if len(exported) != 1 || exported[0] != 7 {
+				t.Errorf("exported customer IDs = %v, want [7]", exported)
+			}
+		})
+	}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"time"
+
+	"quanticfy-test/internal/config"
+	"quanticfy-test/internal/database"
+	"quanticfy-test/internal/generator"
+	"quanticfy-test/pkg/logger"
+)
+
+// runGenerate implements `quanticfy generate [flags]`
+func runGenerate(args []string) {
+	defaults := generator.DefaultConfig()
+
+	fs := flag.NewFlagSet("generate", flag.ExitOnError)
+	seed := fs.Int64("seed", defaults.Seed, "random seed; the same seed always produces the same data")
+	customers := fs.Int("customers", defaults.Customers, "number of customers")
+	contents := fs.Int("contents", defaults.Contents, "number of contents (products)")
+	orders := fs.Int("orders", defaults.Orders, "number of purchase orders")
+	maxLines := fs.Int("max-lines", defaults.MaxLinesPerOrder, "maximum lines per order")
+	from := fs.String("from", defaults.From.Format("2006-01-02"), "first event date (YYYY-MM-DD)")
+	to := fs.String("to", defaults.To.Format("2006-01-02"), "end of the event date range, exclusive (YYYY-MM-DD)")
+	missingEmail := fs.Float64("missing-email-rate", defaults.MissingEmailRate, "share of customers without an email")
+	missingPrice := fs.Float64("missing-price-rate", defaults.MissingPriceRate, "share of contents without a price")
+	phoneRate := fs.Float64("phone-rate", defaults.PhoneRate, "share of customers with a phone number")
+	multiEmail := fs.Float64("multi-email-rate", defaults.MultiEmailRate, "share of customers with a second email")
+	messyEmail := fs.Float64("messy-email-rate", defaults.MessyEmailRate, "share of email rows badly formatted or invalid")
+	duplicates := fs.Float64("duplicate-customer-rate", defaults.DuplicateCustomerRate, "share of customers reusing another customer's email")
+	alpha := fs.Float64("pareto-alpha", defaults.ParetoAlpha, "Pareto shape of customer spend (lower is more skewed)")
+	output := fs.String("output", "fixtures", "where to write the data: mysql or fixtures")
+	dir := fs.String("dir", "fixtures", "fixture directory when -output=fixtures")
+	truncate := fs.Bool("truncate", false, "delete existing source rows before inserting when -output=mysql")
+	fs.Parse(args)
+
+	cfg := defaults
+	cfg.Seed = *seed
+	cfg.Customers = *customers
+	cfg.Contents = *contents
+	cfg.Orders = *orders
+	cfg.MaxLinesPerOrder = *maxLines
+	cfg.MissingEmailRate = *missingEmail
+	cfg.MissingPriceRate = *missingPrice
+	cfg.PhoneRate = *phoneRate
+	cfg.MultiEmailRate = *multiEmail
+	cfg.MessyEmailRate = *messyEmail
+	cfg.DuplicateCustomerRate = *duplicates
+	cfg.ParetoAlpha = *alpha
+
+	log := logger.Default()
+	var err error
+	if cfg.From, err = time.Parse("2006-01-02", *from); err != nil {
+		log.Fatal("Invalid -from date", logger.Err(err))
+	}
+	if cfg.To, err = time.Parse("2006-01-02", *to); err != nil {
+		log.Fatal("Invalid -to date", logger.Err(err))
+	}
+
+	dataset, err := generator.Generate(cfg, log)
+	if err != nil {
+		log.Fatal("Failed to generate data", logger.Err(err))
+	}
+
+	switch *output {
+	case "fixtures":
+		if err := generator.WriteFixtures(*dir, dataset, log); err != nil {
+			log.Fatal("Failed to write fixtures", logger.Err(err))
+		}
+
+	case "mysql":
+		appCfg, err := config.LoadConfig()
+		if err != nil {
+			log.Fatal("Failed to load configuration", logger.Err(err))
+		}
+		log = newLogger(appCfg, logger.NewRunID())
+
+		conn, err := database.NewConnection(newDBConfig(appCfg))
+		if err != nil {
+			log.Fatal("Failed to connect to database", logger.Err(err))
+		}
+		defer conn.Close()
+
+		if err := generator.WriteMySQL(conn.DB, dataset, *truncate, log, newProgress(appCfg, log)); err != nil {
+			log.Fatal("Failed to write data to database", logger.Err(err))
+		}
+
+	default:
+		log.Error("Unknown -output (expected mysql or fixtures)", "output", *output)
+		os.Exit(2)
+	}
+
+	log.Info("Synthetic data generation completed successfully", "seed", cfg.Seed)
+}
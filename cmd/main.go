@@ -1,166 +1,885 @@
 package main
 
 import (
-	"log"
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
 	"time"
 
+	"quanticfy-test/internal/analytics"
+	"quanticfy-test/internal/audit"
 	"quanticfy-test/internal/config"
 	"quanticfy-test/internal/database"
 	"quanticfy-test/internal/exporter"
 	"quanticfy-test/internal/loader"
+	"quanticfy-test/internal/manifest"
+	"quanticfy-test/internal/metrics"
+	"quanticfy-test/internal/migrations"
+	"quanticfy-test/internal/models"
+	"quanticfy-test/internal/notify"
+	"quanticfy-test/internal/privacy"
 	"quanticfy-test/internal/processor"
+	"quanticfy-test/internal/quality"
+	"quanticfy-test/internal/window"
+	"quanticfy-test/pkg/logger"
+	"quanticfy-test/pkg/progress"
+	"quanticfy-test/pkg/tracing"
+
+	"go.opentelemetry.io/otel/trace"
 )
 
 func main() {
-	log.SetFlags(log.Ldate | log.Ltime | log.Lmicroseconds)
-	log.SetPrefix("[INFO] ")
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "run":
+			runPipeline(os.Args[2:])
+			return
+		case "migrate":
+			runMigrate(os.Args[2:])
+			return
+		case "generate":
+			runGenerate(os.Args[2:])
+			return
+		case "erase":
+			runErase(os.Args[2:])
+			return
+		case "decrypt":
+			runDecrypt(os.Args[2:])
+			return
+		case "notify":
+			runNotify(os.Args[2:])
+			return
+		default:
+			if strings.HasPrefix(os.Args[1], "-") {
+				runPipeline(os.Args[1:])
+				return
+			}
+			fmt.Fprintf(os.Stderr, "unknown command %q\n\n", os.Args[1])
+			printUsage()
+			os.Exit(2)
+		}
+	}
+
+	runPipeline(nil)
+}
 
-	log.Println("========================================")
-	log.Println("Quanticfy Data Processing - Starting")
-	log.Println("========================================")
+func printUsage() {
+	fmt.Fprintln(os.Stderr, "Usage:")
+	fmt.Fprintln(os.Stderr, "  quanticfy [run] [flags]          Load, compute and export top customers (-h for flags)")
+	fmt.Fprintln(os.Stderr, "  quanticfy migrate <up|down|status> [steps]")
+	fmt.Fprintln(os.Stderr, "                                   Manage the database schema")
+	fmt.Fprintln(os.Stderr, "  quanticfy generate [flags]       Generate synthetic source data (-h for flags)")
+	fmt.Fprintln(os.Stderr, "  quanticfy erase -customer <id>   Remove a customer from every output (-h for flags)")
+	fmt.Fprintln(os.Stderr, "  quanticfy decrypt -table <name>  Decrypt the emails of an encrypted export (-h for flags)")
+	fmt.Fprintln(os.Stderr, "  quanticfy notify test [flags]    Send a sample notification to the configured channels")
+}
+
+func runPipeline(args []string) {
+	fs := flag.NewFlagSet("run", flag.ExitOnError)
+	from := fs.String("from", "", "start of the analysis window, YYYY-MM-DD (overrides ANALYSIS_FROM)")
+	to := fs.String("to", "", "end of the analysis window, exclusive, YYYY-MM-DD (overrides ANALYSIS_TO)")
+	rolling := fs.String("window", "", "rolling analysis window such as 90d or 12m, or all (overrides ANALYSIS_WINDOW)")
+	revenueWindows := fs.String("windows", "", "comma-separated windows exported as CA_<name> columns (overrides REVENUE_WINDOWS)")
+	fs.Parse(args)
 
 	startTime := time.Now()
+	runID := logger.NewRunID()
+
+	// The configured logger needs the configuration; until then log as text
+	log := logger.Default().With(logger.RunIDKey, runID)
+	log.Info("Quanticfy data processing starting")
 
-	log.Println("Step 1/5: Loading configuration...")
+	log.Info("Step 1/5: Loading configuration")
+	configStartTime := time.Now()
 	cfg, err := config.LoadConfig()
 	if err != nil {
-		log.SetPrefix("[ERROR] ")
-		log.Fatalf("Failed to load configuration: %v", err)
+		log.Fatal("Failed to load configuration", logger.Err(err))
+	}
+	// Flags override the environment before anything, the run manifest
+	// included, reads the configuration
+	if *from != "" {
+		cfg.AnalysisFrom = *from
 	}
-	log.Printf("Configuration loaded successfully (DB: %s@%s:%s/%s, Quantile: %.1f%%)",
-		cfg.DBUser, cfg.DBHost, cfg.DBPort, cfg.DBName, cfg.Quantile*100)
+	if *to != "" {
+		cfg.AnalysisTo = *to
+	}
+	if *rolling != "" {
+		cfg.AnalysisWindow = *rolling
+	}
+	if *revenueWindows != "" {
+		cfg.RevenueWindows = strings.Split(*revenueWindows, ",")
+	}
+	configEndTime := time.Now()
+	log = newLogger(cfg, runID)
 
-	log.Println("\nStep 2/5: Connecting to database...")
-	dbConfig := database.DBConfig{
-		Host:     cfg.DBHost,
-		Port:     cfg.DBPort,
-		User:     cfg.DBUser,
-		Password: cfg.DBPassword,
-		Database: cfg.DBName,
+	// Metrics count the error lines of every logger derived from log
+	runMetrics := metrics.New(runID)
+	log = log.WithHandler(runMetrics.CountErrors)
+	if cfg.MetricsAddr != "" {
+		server, err := runMetrics.Serve(cfg.MetricsAddr, log)
+		if err != nil {
+			log.Fatal("Failed to start metrics endpoint", logger.Err(err))
+		}
+		defer metrics.Shutdown(server, 5*time.Second)
+	}
+	if cfg.MetricsTextfile != "" {
+		logger.OnFatal(func(logger.Failure) {
+			runMetrics.Finish(time.Since(startTime), false)
+			writeMetricsTextfile(runMetrics, cfg.MetricsTextfile, log)
+		})
 	}
 
-	conn, err := database.NewConnection(dbConfig)
+	// Tracing starts after the configuration is read; the run and LoadConfig
+	// spans are backdated to when they actually began
+	flushTraces, err := tracing.Setup(tracing.Options{
+		Exporter:    cfg.TraceExporter,
+		File:        cfg.TraceFile,
+		ServiceName: cfg.TraceServiceName,
+		RunID:       runID,
+	})
 	if err != nil {
-		log.SetPrefix("[ERROR] ")
-		log.Fatalf("Failed to connect to database: %v", err)
+		log.Fatal("Failed to set up tracing", logger.Err(err))
+	}
+	defer flushTraces(context.Background())
+	ctx, runSpan := tracing.StartAt(context.Background(), "run", startTime)
+	tracing.Record(ctx, "LoadConfig", configStartTime, configEndTime)
+	// phaseSpan is the span of the phase in progress, ended on a fatal error;
+	// it is nil between phases (no-op spans cannot be compared to runSpan)
+	var phaseSpan trace.Span
+	logger.OnFatal(func(failure logger.Failure) {
+		if phaseSpan != nil {
+			tracing.Fail(phaseSpan, failure)
+			phaseSpan.End()
+		}
+		tracing.Fail(runSpan, failure)
+		runSpan.End()
+		if err := flushTraces(context.Background()); err != nil {
+			log.Warn("Could not flush traces", logger.Err(err))
+		}
+	})
+
+	// The manifest is recorded at the end of the run, or by the fatal hook
+	// with the failed phase and error; historyDB is set once connected
+	runManifest := manifest.New(runID, softwareVersion(), startTime, cfg.Redacted())
+	runManifest.Quantile = cfg.Quantile
+	runManifest.PhaseDurations["config"] = configEndTime.Sub(configStartTime).Seconds()
+	if cfg.TraceExporter == tracing.ExporterFile {
+		runManifest.AddOutput(manifest.OutputFile, cfg.TraceFile, 0)
+	}
+	var historyDB *sql.DB
+	// Registered first so it runs after the manifest hook has finished the manifest
+	notifier := newNotifier(cfg)
+	logger.OnFatal(func(logger.Failure) {
+		sendNotification(notifier, notify.RunEvent(runManifest), log)
+	})
+	logger.OnFatal(func(failure logger.Failure) {
+		phase := failure.Phase
+		if phase == "" {
+			phase = "setup"
+		}
+		runManifest.Finish(time.Now(), phase, failure)
+		recordRun(cfg, runManifest, historyDB, log)
+	})
+
+	log.Info("Configuration loaded successfully", "db_user", cfg.DBUser, "db_host", cfg.DBHost,
+		"db_port", cfg.DBPort, "db_name", cfg.DBName, "quantile", cfg.Quantile)
+
+	analysisWindow, extraWindows, err := resolveWindows(cfg, startTime)
+	if err != nil {
+		log.Fatal("Invalid configuration", logger.Err(err))
+	}
+	log.Info("Analysis window", "range", analysisWindow.String())
+
+	var trendPolicy processor.TrendPolicy
+	if cfg.TrendFlags {
+		if trendPolicy, err = newTrendPolicy(cfg, analysisWindow, startTime); err != nil {
+			log.Fatal("Invalid configuration", logger.Err(err))
+		}
+		log.Info("Trend periods", "current", trendPolicy.Current.String(), "previous", trendPolicy.Previous.String())
+	}
+
+	emailPolicy := processor.EmailPolicy{
+		Name:                   cfg.EmailPolicy,
+		Placeholder:            cfg.EmailPlaceholder,
+		FallbackChannelTypeIDs: cfg.EmailFallbackChannelTypeIDs,
+	}
+	if err := emailPolicy.Validate(); err != nil {
+		log.Fatal("Invalid configuration", logger.Err(err))
+	}
+	if err := processor.ValidateEmailSelection(cfg.EmailSelection); err != nil {
+		log.Fatal("Invalid configuration", logger.Err(err))
+	}
+	outlierPolicy := processor.OutlierPolicy{
+		Method:           cfg.OutlierMethod,
+		Threshold:        cfg.OutlierThreshold,
+		MaxEventQuantity: cfg.OutlierMaxEventQuantity,
+		Exclude:          cfg.OutlierExclude,
+	}
+	if err := outlierPolicy.Validate(); err != nil {
+		log.Fatal("Invalid configuration", logger.Err(err))
+	}
+	if err := analytics.ValidateGranularity(cfg.TimeSeriesGranularity); err != nil {
+		log.Fatal("Invalid configuration", logger.Err(err))
+	}
+	var pseudonymizer *privacy.Pseudonymizer
+	if cfg.PrivacyMode {
+		if pseudonymizer, err = privacy.NewPseudonymizer(cfg.PrivacyHMACKey); err != nil {
+			log.Fatal("Invalid configuration", logger.Err(err))
+		}
+		log.Info("Privacy mode: exported emails are pseudonymized")
+	}
+	var encryptor *privacy.Encryptor
+	var blindIndex *privacy.Pseudonymizer
+	if cfg.EmailEncryption {
+		keyring, err := newKeyring(cfg)
+		if err != nil {
+			log.Fatal("Invalid configuration", logger.Err(err))
+		}
+		encryptor = privacy.NewEncryptor(keyring, cfg.EmailEncryptionDeterministic)
+		if cfg.EmailBlindIndexKey != "" {
+			if blindIndex, err = privacy.NewPseudonymizer(cfg.EmailBlindIndexKey); err != nil {
+				log.Fatal("Invalid configuration: EMAIL_BLIND_INDEX_KEY", logger.Err(err))
+			}
+		}
+		log.Info("Email encryption enabled", "key_id", keyring.ActiveKeyID(),
+			"deterministic", cfg.EmailEncryptionDeterministic, "blind_index", blindIndex != nil)
+	}
+
+	// Progress goes to a bar or to log lines, and its counters to the summary
+	throughput := progress.NewCounters()
+	reporter := progress.WithMetrics(progress.WithMetrics(newProgress(cfg, log), throughput), runMetrics)
+
+	log.Info("Step 2/5: Connecting to database")
+	conn, err := database.NewConnection(newDBConfig(cfg))
+	if err != nil {
+		log.Fatal("Failed to connect to database", logger.Err(err))
 	}
 	defer func() {
-		log.SetPrefix("[INFO] ")
-		log.Println("\nClosing database connection...")
 		if err := conn.Close(); err != nil {
-			log.SetPrefix("[WARNING] ")
-			log.Printf("Error closing database: %v", err)
+			log.Warn("Error closing database", logger.Err(err))
 		} else {
-			log.SetPrefix("[INFO] ")
-			log.Println("Database connection closed successfully")
+			log.Info("Database connection closed successfully")
 		}
 	}()
 
 	if err := conn.HealthCheck(); err != nil {
-		log.SetPrefix("[ERROR] ")
-		log.Fatalf("Database health check failed: %v", err)
+		log.Fatal("Database health check failed", logger.Err(err))
+	}
+	log.Info("Database connection established successfully")
+	historyDB = conn.DB
+
+	migrator, err := migrations.NewMigrator(conn.DB, log)
+	if err != nil {
+		log.Fatal("Failed to load migrations", logger.Err(err))
+	}
+	if err := migrator.CheckUpToDate(); err != nil {
+		log.Fatal("Database schema is out of date", logger.Err(err))
 	}
-	log.Println("Database connection established successfully")
 
 	var version string
 	err = conn.DB.QueryRow("SELECT VERSION()").Scan(&version)
 	if err != nil {
-		log.SetPrefix("[WARNING] ")
-		log.Printf("Could not query MySQL version: %v", err)
+		log.Warn("Could not query MySQL version", logger.Err(err))
 	} else {
-		log.SetPrefix("[INFO] ")
-		log.Printf("Connected to MySQL version: %s", version)
+		log.Info("Connected to MySQL", "version", version)
 	}
 
-	log.Println("\n========================================")
-	log.Println("Step 3/5: LOAD Phase")
-	log.Println("========================================")
+	loadLog := log.Phase("load")
+	loadLog.Info("Step 3/5: LOAD phase")
 	loadStartTime := time.Now()
+	loadCtx, loadSpan := tracing.Start(ctx, "load")
+	phaseSpan = loadSpan
+
+	dataLoader := loader.NewLoader(conn.DB, loadLog)
+	dataLoader.SetProgress(reporter)
+	dataLoader.SetTraceContext(loadCtx)
 
-	dataLoader := loader.NewLoader(conn.DB)
+	emailRecords, err := dataLoader.LoadCustomerEmails()
+	if err != nil {
+		loadLog.Fatal("Failed to load customer emails", logger.Err(err))
+	}
 
-	customerEmails, err := dataLoader.LoadCustomerEmails()
+	clientCustomerIDs, err := dataLoader.LoadCustomers()
 	if err != nil {
-		log.SetPrefix("[ERROR] ")
-		log.Fatalf("Failed to load customer emails: %v", err)
+		loadLog.Fatal("Failed to load customers", logger.Err(err))
+	}
+
+	clientContentIDs, err := dataLoader.LoadContents()
+	if err != nil {
+		loadLog.Fatal("Failed to load contents", logger.Err(err))
 	}
 
 	contentPrices, err := dataLoader.LoadContentPrices()
 	if err != nil {
-		log.SetPrefix("[ERROR] ")
-		log.Fatalf("Failed to load content prices: %v", err)
+		loadLog.Fatal("Failed to load content prices", logger.Err(err))
+	}
+
+	fallbackContacts := make(map[int16]map[int64]string)
+	if emailPolicy.Name == processor.EmailPolicyFallback {
+		for _, channelTypeID := range emailPolicy.FallbackChannelTypeIDs {
+			contacts, err := dataLoader.LoadCustomerChannel(channelTypeID)
+			if err != nil {
+				loadLog.Fatal("Failed to load fallback contacts", logger.Err(err))
+			}
+			fallbackContacts[channelTypeID] = contacts
+		}
+	}
+
+	var channelTypes map[int16]string
+	var customerData []models.CustomerData
+	var exportChannels []string
+	if len(cfg.ExportChannels) > 0 {
+		channelTypes, err = dataLoader.LoadChannelTypes()
+		if err != nil {
+			loadLog.Fatal("Failed to load channel types", logger.Err(err))
+		}
+		exportChannels, err = processor.ResolveChannelNames(cfg.ExportChannels, channelTypes)
+		if err != nil {
+			loadLog.Fatal("Invalid EXPORT_CHANNELS", logger.Err(err))
+		}
+		customerData, err = dataLoader.LoadCustomerData()
+		if err != nil {
+			loadLog.Fatal("Failed to load customer contact data", logger.Err(err))
+		}
+	}
+
+	var identityPhones map[int64]string
+	if cfg.IdentityResolution && cfg.IdentityMatchPhone {
+		identityPhones, err = dataLoader.LoadCustomerChannel(cfg.IdentityPhoneChannelType)
+		if err != nil {
+			loadLog.Fatal("Failed to load phones for identity resolution", logger.Err(err))
+		}
+	}
+
+	exclusionRules, err := loadExclusionRules(cfg, dataLoader)
+	if err != nil {
+		loadLog.Fatal("Failed to load exclusion rules", logger.Err(err))
 	}
 
-	sinceDate := time.Date(2020, 4, 1, 0, 0, 0, 0, time.UTC)
-	purchaseEvents, err := dataLoader.LoadPurchaseEvents(sinceDate)
+	var consents map[int64]string
+	if cfg.ConsentFromDB || cfg.ConsentFile != "" {
+		consents = make(map[int64]string)
+	}
+	if cfg.ConsentFromDB {
+		dbConsents, err := dataLoader.LoadConsents(cfg.ConsentChannel)
+		if err != nil {
+			loadLog.Fatal("Failed to load consents", logger.Err(err))
+		}
+		for id, status := range dbConsents {
+			consents[id] = status
+		}
+	}
+	if cfg.ConsentFile != "" {
+		// The file is read last so it can override the table
+		fileConsents, err := dataLoader.ReadConsentFile(cfg.ConsentFile, cfg.ConsentChannel)
+		if err != nil {
+			loadLog.Fatal("Failed to read consents", logger.Err(err))
+		}
+		for id, status := range fileConsents {
+			consents[id] = status
+		}
+	}
+
+	// Load every event needed by the analysis and revenue windows at once;
+	// the analysis window is applied again in the COMPUTE phase
+	loadWindows := append([]window.Window{analysisWindow}, extraWindows...)
+	if cfg.TrendFlags {
+		loadWindows = append(loadWindows, trendPolicy.Previous, trendPolicy.Current)
+	}
+	loadWindow := window.Union(loadWindows...)
+	loadedEvents, err := dataLoader.LoadPurchaseEvents(loadWindow.From, loadWindow.To)
 	if err != nil {
-		log.SetPrefix("[ERROR] ")
-		log.Fatalf("Failed to load purchase events: %v", err)
+		loadLog.Fatal("Failed to load purchase events", logger.Err(err))
 	}
 
-	log.SetPrefix("[INFO] ")
-	log.Printf("LOAD Phase completed in %v", time.Since(loadStartTime))
+	for _, read := range dataLoader.RowsRead() {
+		runManifest.AddInput(read.Query, read.Table, read.Rows)
+		runMetrics.RowsLoaded.WithLabelValues(read.Table, read.Query).Set(float64(read.Rows))
+	}
+	runManifest.SetWatermark(processor.Watermark(loadedEvents))
+	runManifest.PhaseDurations["load"] = time.Since(loadStartTime).Seconds()
+	runMetrics.PhaseDuration.WithLabelValues("load").Set(runManifest.PhaseDurations["load"])
+	loadSpan.End()
+	loadLog.Info("LOAD phase completed", logger.Duration(time.Since(loadStartTime)))
 
-	log.Println("\n========================================")
-	log.Println("Step 4/5: COMPUTE Phase")
-	log.Println("========================================")
+	computeLog := log.Phase("compute")
+	computeLog.Info("Step 4/5: COMPUTE phase")
 	computeStartTime := time.Now()
+	computeCtx, computeSpan := tracing.Start(ctx, "compute")
+	phaseSpan = computeSpan
 
-	proc := processor.NewProcessor(cfg.Quantile)
+	proc := processor.NewProcessor(cfg.Quantile, computeLog)
+	proc.SetProgress(reporter)
+	proc.SetTraceContext(computeCtx)
+	proc.SetEmailMasking(cfg.LogMaskEmails)
+
+	purchaseEvents := loadedEvents
+	if len(loadWindows) > 1 {
+		purchaseEvents = proc.FilterEvents(loadedEvents, analysisWindow)
+	}
+
+	customerEmails, invalidEmails := proc.SelectEmails(emailRecords, cfg.EmailSelection)
 
 	revenueMap, err := proc.CalculateCustomerRevenue(purchaseEvents, contentPrices, customerEmails)
 	if err != nil {
-		log.SetPrefix("[ERROR] ")
-		log.Fatalf("Failed to calculate customer revenue: %v", err)
+		computeLog.Fatal("Failed to calculate customer revenue", logger.Err(err))
+	}
+
+	dqReport := proc.DataQualityReport()
+	dqReport.RecordInvalidEmails(invalidEmails)
+	dqReport.RecordOrphans(proc.FindOrphans(purchaseEvents, clientCustomerIDs, clientContentIDs))
+	dqReport.AttachClientContentIDs(clientContentIDs)
+	if cfg.LogMaskEmails {
+		dqReport.MaskEmails()
+	}
+	dqReport.Log(computeLog)
+	if cfg.DQReportPath != "" {
+		if err := dqReport.WriteJSON(cfg.DQReportPath); err != nil {
+			computeLog.Warn("Could not write data quality report", logger.Err(err))
+		} else {
+			computeLog.Info("Data quality report written", "path", cfg.DQReportPath)
+			runManifest.AddOutput(manifest.OutputFile, cfg.DQReportPath, 0)
+		}
+	}
+	if breaches := dqReport.Check(quality.Thresholds{
+		MaxMissingPriceRate:    cfg.DQMaxMissingPriceRate,
+		MaxMissingEmailRate:    cfg.DQMaxMissingEmailRate,
+		MaxInvalidQuantityRate: cfg.DQMaxInvalidQuantityRate,
+		MaxFutureEventRate:     cfg.DQMaxFutureEventRate,
+	}); len(breaches) > 0 {
+		for _, breach := range breaches {
+			computeLog.Error("Data quality check failed", "breach", breach)
+		}
+		sendNotification(notifier, notify.BreachEvent(runManifest, "compute", breaches), computeLog)
+		computeLog.Fatal("Aborting run: data quality thresholds exceeded", "breaches", len(breaches))
+	}
+
+	if cfg.IdentityResolution {
+		revenueMap = proc.ResolveIdentities(revenueMap, identityPhones)
+	}
+
+	var orders []models.OrderRevenue
+	if cfg.OrderMetrics {
+		orders = proc.CalculateOrders(purchaseEvents, contentPrices)
+		proc.AttachOrderMetrics(revenueMap, proc.CalculateOrderMetrics(orders))
+	}
+
+	var exclusionCounts []processor.ExclusionCount
+	if len(exclusionRules) > 0 {
+		revenueMap, exclusionCounts = proc.ApplyExclusions(revenueMap, exclusionRules, customerEmails)
+	}
+
+	if outlierPolicy.Enabled() {
+		outlierReport := proc.DetectOutliers(revenueMap, purchaseEvents, outlierPolicy)
+		if cfg.LogMaskEmails {
+			outlierReport.MaskEmails()
+		}
+		if cfg.OutlierReportPath != "" {
+			if err := outlierReport.WriteJSON(cfg.OutlierReportPath); err != nil {
+				computeLog.Warn("Could not write outlier report", logger.Err(err))
+			} else {
+				computeLog.Info("Outlier report written", "path", cfg.OutlierReportPath)
+				runManifest.AddOutput(manifest.OutputFile, cfg.OutlierReportPath, len(outlierReport.Outliers))
+			}
+		}
+
+		if outlierPolicy.Exclude && len(outlierReport.Outliers) > 0 {
+			records := make([]audit.Record, 0, len(outlierReport.Outliers))
+			for _, outlier := range outlierReport.Outliers {
+				records = append(records, audit.Record{
+					Time:       outlierReport.GeneratedAt,
+					Action:     "exclude_outlier",
+					CustomerID: outlier.CustomerID,
+					Reason:     strings.Join(outlier.Reasons, "; "),
+					Details:    outlier,
+				})
+			}
+			if err := audit.NewTrail(cfg.AuditLogPath, computeLog).Record(records...); err != nil {
+				computeLog.Fatal("Failed to record outlier exclusions", logger.Err(err))
+			}
+			if cfg.AuditLogPath != "" {
+				runManifest.AddOutput(manifest.OutputFile, cfg.AuditLogPath, len(records))
+			}
+			revenueMap = proc.ExcludeOutliers(revenueMap, outlierReport)
+		}
 	}
 
 	topCustomers, err := proc.GetTopQuantileCustomers(revenueMap)
 	if err != nil {
-		log.SetPrefix("[ERROR] ")
-		log.Fatalf("Failed to get top customers: %v", err)
+		computeLog.Fatal("Failed to get top customers", logger.Err(err))
 	}
 
 	quantileStats, err := proc.CalculateQuantileStats(revenueMap)
 	if err != nil {
-		log.SetPrefix("[ERROR] ")
-		log.Fatalf("Failed to calculate quantile stats: %v", err)
+		computeLog.Fatal("Failed to calculate quantile stats", logger.Err(err))
 	}
 	_ = quantileStats
 
-	log.SetPrefix("[INFO] ")
-	log.Printf("COMPUTE Phase completed in %v", time.Since(computeStartTime))
+	var trendStatuses map[string]int
+	if cfg.TrendFlags {
+		trendStatuses = proc.CalculateTrends(topCustomers, loadedEvents, contentPrices, trendPolicy)
+	}
+
+	// Consent applies to every output, so it filters the top customers before
+	// the email policy routes them to the export, a fallback contact or the
+	// unreachable table. The threshold and analytics keep the whole top.
+	contactable := topCustomers
+	var consentStats *models.ConsentStats
+	if consents != nil {
+		var stats models.ConsentStats
+		contactable, stats = proc.ApplyConsent(topCustomers, consents)
+		consentStats = &stats
+	}
+
+	exportCustomers, unreachableCustomers, emailStats := proc.ApplyEmailPolicy(contactable, emailPolicy, fallbackContacts)
 
-	log.Println("\n========================================")
-	log.Println("Step 5/5: EXPORT Phase")
-	log.Println("========================================")
+	var contentReport *analytics.ContentReport
+	if cfg.ContentAnalytics {
+		contentAnalyzer := analytics.NewContentAnalyzer(cfg.ContentTopN, cfg.ParetoThreshold, computeLog)
+		contentReport = contentAnalyzer.Analyze(purchaseEvents, contentPrices, clientContentIDs,
+			analytics.TopSegment(topCustomers))
+	}
+
+	var timeSeries *analytics.TimeSeriesReport
+	if cfg.TimeSeries {
+		timeSeriesAnalyzer := analytics.NewTimeSeriesAnalyzer(cfg.TimeSeriesGranularity, computeLog)
+		timeSeries = timeSeriesAnalyzer.Analyze(purchaseEvents, contentPrices, analytics.TopSegment(topCustomers))
+		if cfg.TimeSeriesCSVPath != "" {
+			if err := timeSeries.WriteCSV(cfg.TimeSeriesCSVPath); err != nil {
+				computeLog.Warn("Could not write time series", logger.Err(err))
+			} else {
+				computeLog.Info("Time series written", "path", cfg.TimeSeriesCSVPath)
+				runManifest.AddOutput(manifest.OutputFile, cfg.TimeSeriesCSVPath, 0)
+			}
+		}
+	}
+
+	var basketReport *analytics.BasketReport
+	if cfg.BasketAnalysis {
+		basketAnalyzer := analytics.NewBasketAnalyzer(cfg.BasketMinSupport, cfg.BasketMinConfidence, cfg.BasketMaxItemset, computeLog)
+		if cfg.BasketTopSegmentOnly {
+			basketReport = basketAnalyzer.Analyze(purchaseEvents, analytics.TopSegment(topCustomers), "top")
+		} else {
+			basketReport = basketAnalyzer.Analyze(purchaseEvents, nil, "all")
+		}
+	}
+
+	if len(extraWindows) > 0 {
+		windowRevenue := proc.CalculateWindowRevenue(loadedEvents, contentPrices, extraWindows)
+		proc.AttachWindowRevenue(exportCustomers, windowRevenue, extraWindows)
+		proc.AttachWindowRevenue(unreachableCustomers, windowRevenue, extraWindows)
+	}
+
+	proc.AttachClientCustomerIDs(exportCustomers, clientCustomerIDs)
+	proc.AttachClientCustomerIDs(unreachableCustomers, clientCustomerIDs)
+
+	if len(exportChannels) > 0 {
+		contactProfiles := proc.BuildContactProfiles(customerData, channelTypes)
+		proc.AttachContactProfiles(exportCustomers, contactProfiles, exportChannels)
+		proc.AttachContactProfiles(unreachableCustomers, contactProfiles, nil)
+	}
+
+	revenueThreshold := processor.RevenueThreshold(topCustomers)
+	runManifest.Customers = len(revenueMap)
+	runManifest.TopCustomers = len(topCustomers)
+	runManifest.SetRevenueThreshold(revenueThreshold)
+	runManifest.PhaseDurations["compute"] = time.Since(computeStartTime).Seconds()
+	runMetrics.CustomersProcessed.Set(float64(len(revenueMap)))
+	runMetrics.TopCustomers.Set(float64(len(topCustomers)))
+	runMetrics.RevenueThreshold.Set(revenueThreshold)
+	runMetrics.PhaseDuration.WithLabelValues("compute").Set(runManifest.PhaseDurations["compute"])
+	computeSpan.End()
+	computeLog.Info("COMPUTE phase completed", logger.Duration(time.Since(computeStartTime)))
+
+	exportLog := log.Phase("export")
+	exportLog.Info("Step 5/5: EXPORT phase")
 	exportStartTime := time.Now()
+	exportCtx, exportSpan := tracing.Start(ctx, "export")
+	phaseSpan = exportSpan
 
-	exp := exporter.NewExporter(conn.DB)
+	exp := exporter.NewExporter(conn.DB, exportLog)
+	exp.SetProgress(reporter)
+	exp.SetTraceContext(exportCtx)
+	if pseudonymizer != nil {
+		exp.PseudonymizeEmails(pseudonymizer, cfg.EmailPlaceholder)
+	}
+	if encryptor != nil {
+		if err := exp.EncryptEmails(encryptor, blindIndex); err != nil {
+			exportLog.Fatal("Invalid export columns", logger.Err(err))
+		}
+	}
+	if cfg.ExportClientIDs {
+		if err := exp.AddClientIDColumn(); err != nil {
+			exportLog.Fatal("Invalid export columns", logger.Err(err))
+		}
+	}
+	if cfg.OrderMetrics {
+		if err := exp.AddOrderColumns(); err != nil {
+			exportLog.Fatal("Invalid export columns", logger.Err(err))
+		}
+	}
+	if cfg.IdentityResolution {
+		if err := exp.AddMergedIDsColumn(); err != nil {
+			exportLog.Fatal("Invalid export columns", logger.Err(err))
+		}
+	}
+	if cfg.TrendFlags {
+		if err := exp.AddTrendColumns(); err != nil {
+			exportLog.Fatal("Invalid export columns", logger.Err(err))
+		}
+	}
+	if len(extraWindows) > 0 {
+		names := make([]string, len(extraWindows))
+		for i, w := range extraWindows {
+			names[i] = w.Name
+		}
+		if err := exp.AddWindowColumns(names); err != nil {
+			exportLog.Fatal("Invalid export columns", logger.Err(err))
+		}
+	}
+	if err := exp.AddContactColumns(exportChannels); err != nil {
+		exportLog.Fatal("Invalid EXPORT_CHANNELS", logger.Err(err))
+	}
 
-	err = exp.ExportTopCustomers(topCustomers)
+	err = exp.ExportTopCustomers(exportCustomers)
 	if err != nil {
-		log.SetPrefix("[ERROR] ")
-		log.Fatalf("Failed to export top customers: %v", err)
+		exportLog.Fatal("Failed to export top customers", logger.Err(err))
+	}
+
+	if emailPolicy.Name == processor.EmailPolicyUnreachable {
+		if err := exp.ExportUnreachableCustomers(unreachableCustomers); err != nil {
+			exportLog.Fatal("Failed to export unreachable customers", logger.Err(err))
+		}
+	}
+
+	if pseudonymizer != nil && cfg.PrivacyMappingTable {
+		if err := exp.ExportEmailPseudonyms(exportCustomers); err != nil {
+			exportLog.Fatal("Failed to export email pseudonyms", logger.Err(err))
+		}
+	}
+
+	if cfg.OrderMetrics {
+		if err := exp.ExportOrders(orders); err != nil {
+			exportLog.Fatal("Failed to export orders", logger.Err(err))
+		}
+	}
+
+	if contentReport != nil {
+		if err := exp.ExportContentRevenue(contentReport); err != nil {
+			exportLog.Fatal("Failed to export content revenue", logger.Err(err))
+		}
+	}
+
+	if timeSeries != nil {
+		if err := exp.ExportRevenueTimeSeries(timeSeries); err != nil {
+			exportLog.Fatal("Failed to export revenue time series", logger.Err(err))
+		}
+	}
+
+	if basketReport != nil {
+		if err := exp.ExportAssociationRules(basketReport, clientContentIDs); err != nil {
+			exportLog.Fatal("Failed to export association rules", logger.Err(err))
+		}
 	}
 
 	tableName := time.Now().Format("20060102")
 	err = exp.GetExportStats("test_export_" + tableName)
 	if err != nil {
-		log.SetPrefix("[WARNING] ")
-		log.Printf("Could not get export stats: %v", err)
+		exportLog.Warn("Could not get export stats", logger.Err(err))
 	}
 
-	log.SetPrefix("[INFO] ")
-	log.Printf("EXPORT Phase completed in %v", time.Since(exportStartTime))
+	written := exp.RowsWritten()
+	tables := make([]string, 0, len(written))
+	for table := range written {
+		tables = append(tables, table)
+	}
+	sort.Strings(tables)
+	for _, table := range tables {
+		runManifest.AddOutput(manifest.OutputTable, table, written[table])
+		runMetrics.ExportRows.WithLabelValues(table).Set(float64(written[table]))
+	}
+	runManifest.PhaseDurations["export"] = time.Since(exportStartTime).Seconds()
+	runMetrics.PhaseDuration.WithLabelValues("export").Set(runManifest.PhaseDurations["export"])
+	exportSpan.End()
+	phaseSpan = nil
+	exportLog.Info("EXPORT phase completed", logger.Duration(time.Since(exportStartTime)))
 
 	duration := time.Since(startTime)
-	log.Println("\n========================================")
-	log.Println("Summary")
-	log.Println("========================================")
-	log.Printf("Total customers processed: %d", len(revenueMap))
-	log.Printf("Top customers (%.1f%%): %d", cfg.Quantile*100, len(topCustomers))
-	log.Printf("Total execution time: %v", duration)
-	log.Println("========================================")
-	log.Println("Process completed successfully!")
-	log.Println("========================================")
-}
\ No newline at end of file
+	log.Info("Summary", "customers", len(revenueMap))
+	if consentStats != nil {
+		log.Info("Summary: marketing consent", "channel", cfg.ConsentChannel, "opted_in", consentStats.OptedIn,
+			"opted_out", consentStats.OptedOut, "unknown", consentStats.Unknown)
+	}
+	if exclusionCounts != nil {
+		excluded := 0
+		for _, c := range exclusionCounts {
+			excluded += c.Customers
+		}
+		log.Info("Summary: exclusions", "rules", len(exclusionCounts), "excluded", excluded)
+	}
+	log.Info("Summary: top customers", "quantile", cfg.Quantile, "customers", len(topCustomers))
+	log.Info("Summary: email policy", "policy", emailStats.Policy, "exported", len(exportCustomers),
+		"with_email", emailStats.WithEmail, "placeholder", emailStats.Placeholder, "null", emailStats.Null,
+		"fallback", emailStats.Fallback, "excluded", emailStats.Excluded, "unreachable", emailStats.Unreachable)
+	if trendStatuses != nil {
+		log.Info("Summary: top customer trends", "growing", trendStatuses[processor.TrendGrowing],
+			"stable", trendStatuses[processor.TrendStable], "declining", trendStatuses[processor.TrendDeclining],
+			"at_risk", trendStatuses[processor.TrendAtRisk])
+	}
+	for _, counter := range throughput.Snapshot() {
+		log.Info("Summary: throughput", "operation", counter.Operation, logger.Rows(counter.Done),
+			logger.Duration(counter.Elapsed), "rows_per_second", int(counter.Rate()))
+	}
+	runMetrics.Finish(duration, true)
+	if cfg.MetricsTextfile != "" && writeMetricsTextfile(runMetrics, cfg.MetricsTextfile, log) {
+		runManifest.AddOutput(manifest.OutputFile, cfg.MetricsTextfile, 0)
+	}
+	runManifest.Finish(time.Now(), "", nil)
+	recordRun(cfg, runManifest, conn.DB, log)
+	sendNotification(notifier, notify.RunEvent(runManifest), log)
+	runSpan.End()
+	log.Info("Process completed successfully", logger.Duration(duration))
+}
+
+// writeMetricsTextfile writes the run metrics for node_exporter; a failure
+// only loses monitoring, so it does not fail the run
+func writeMetricsTextfile(m *metrics.Metrics, path string, log *logger.Logger) bool {
+	if err := m.WriteTextfile(path); err != nil {
+		log.Warn("Could not write metrics textfile", logger.Err(err))
+		return false
+	}
+	log.Info("Metrics textfile written", "path", path)
+	return true
+}
+
+// resolveWindows builds the analysis window and the extra revenue windows,
+// rolling windows being relative to now
+func resolveWindows(cfg *config.Config, now time.Time) (window.Window, []window.Window, error) {
+	var analysis window.Window
+	if cfg.AnalysisWindow != "" {
+		w, err := window.Parse("analysis="+cfg.AnalysisWindow, now)
+		if err != nil {
+			return window.Window{}, nil, err
+		}
+		analysis = w
+	} else {
+		from, err := window.ParseDate(cfg.AnalysisFrom)
+		if err != nil {
+			return window.Window{}, nil, fmt.Errorf("ANALYSIS_FROM: %w", err)
+		}
+		to, err := window.ParseDate(cfg.AnalysisTo)
+		if err != nil {
+			return window.Window{}, nil, fmt.Errorf("ANALYSIS_TO: %w", err)
+		}
+		if analysis, err = window.Between("analysis", from, to); err != nil {
+			return window.Window{}, nil, err
+		}
+	}
+
+	extra, err := window.ParseList(cfg.RevenueWindows, now)
+	if err != nil {
+		return window.Window{}, nil, err
+	}
+	return analysis, extra, nil
+}
+
+// loadExclusionRules reads the exclusion rules of CustomerExclusion and
+// EXCLUSIONS_FILE. The customer_id rules of erased customers are always read
+// from the table, even with EXCLUSIONS_FROM_DB=false.
+func loadExclusionRules(cfg *config.Config, dataLoader *loader.Loader) ([]models.ExclusionRule, error) {
+	var rules []models.ExclusionRule
+	var err error
+	if cfg.ExclusionsFromDB {
+		rules, err = dataLoader.LoadExclusions()
+	} else {
+		rules, err = dataLoader.LoadSuppressions()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.ExclusionsFile != "" {
+		fileRules, err := dataLoader.ReadExclusionFile(cfg.ExclusionsFile)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, fileRules...)
+	}
+
+	if err := processor.ValidateExclusionRules(rules); err != nil {
+		return nil, fmt.Errorf("invalid exclusion rules: %w", err)
+	}
+	return rules, nil
+}
+
+// newTrendPolicy builds the trend periods: TrendPeriod before the reference
+// date, and the same length before that
+func newTrendPolicy(cfg *config.Config, analysisWindow window.Window, now time.Time) (processor.TrendPolicy, error) {
+	if !window.IsRolling(cfg.TrendPeriod) {
+		return processor.TrendPolicy{}, fmt.Errorf("TREND_PERIOD must be a rolling length such as 90d or 3m, got %q", cfg.TrendPeriod)
+	}
+
+	asOf := now
+	if !analysisWindow.To.IsZero() {
+		asOf = analysisWindow.To
+	}
+	if cfg.TrendAsOf != "" {
+		date, err := window.ParseDate(cfg.TrendAsOf)
+		if err != nil {
+			return processor.TrendPolicy{}, fmt.Errorf("TREND_AS_OF: %w", err)
+		}
+		asOf = date
+	}
+
+	current, err := window.Parse("current="+cfg.TrendPeriod, asOf)
+	if err != nil {
+		return processor.TrendPolicy{}, err
+	}
+	previous, err := window.Parse("previous="+cfg.TrendPeriod, current.From)
+	if err != nil {
+		return processor.TrendPolicy{}, err
+	}
+
+	policy := processor.TrendPolicy{
+		Current:       current,
+		Previous:      previous,
+		AtRiskDays:    cfg.TrendAtRiskDays,
+		GrowingRate:   cfg.TrendGrowingRate,
+		DecliningRate: cfg.TrendDecliningRate,
+	}
+	return policy, policy.Validate()
+}
+
+// newLogger builds the logger configured by LOG_LEVEL and LOG_FORMAT, tagging
+// every line with runID, and routes the standard log package through it
+func newLogger(cfg *config.Config, runID string) *logger.Logger {
+	log, err := logger.New(logger.Options{Level: cfg.LogLevel, Format: cfg.LogFormat, RunID: runID})
+	if err != nil {
+		logger.Default().Fatal("Invalid configuration", logger.Err(err))
+	}
+	logger.SetDefault(log)
+	return log
+}
+
+// newProgress builds the progress reporter selected by PROGRESS
+func newProgress(cfg *config.Config, log *logger.Logger) progress.Reporter {
+	reporter, err := progress.New(cfg.ProgressMode, log, time.Duration(cfg.ProgressLogInterval)*time.Second)
+	if err != nil {
+		log.Fatal("Invalid configuration", logger.Err(err))
+	}
+	return reporter
+}
+
+func newDBConfig(cfg *config.Config) database.DBConfig {
+	return database.DBConfig{
+		Host:     cfg.DBHost,
+		Port:     cfg.DBPort,
+		User:     cfg.DBUser,
+		Password: cfg.DBPassword,
+		Database: cfg.DBName,
+	}
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"database/sql"
+	"runtime/debug"
+
+	"quanticfy-test/internal/config"
+	"quanticfy-test/internal/exporter"
+	"quanticfy-test/internal/manifest"
+	"quanticfy-test/pkg/logger"
+)
+
+// version is set at build time with -ldflags "-X main.version=1.2.3"
+var version = "dev"
+
+// softwareVersion is version, followed by the VCS revision when the binary
+// was built from a git checkout
+func softwareVersion() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return version
+	}
+	var revision, modified string
+	for _, setting := range info.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			revision = setting.Value
+		case "vcs.modified":
+			if setting.Value == "true" {
+				modified = "-dirty"
+			}
+		}
+	}
+	if revision == "" {
+		return version
+	}
+	if len(revision) > 12 {
+		revision = revision[:12]
+	}
+	return version + "+" + revision + modified
+}
+
+// recordRun writes the manifest of a finished run to RUN_MANIFEST_DIR and to
+// run_history. db is nil when the run failed before connecting. Failures
+// are only logged so they never hide the outcome of the run itself.
+func recordRun(cfg *config.Config, m *manifest.Manifest, db *sql.DB, log *logger.Logger) {
+	if cfg.RunManifestDir != "" {
+		path := m.Path(cfg.RunManifestDir)
+		if err := m.WriteJSON(path); err != nil {
+			log.Warn("Could not write run manifest", logger.Err(err))
+		} else {
+			log.Info("Run manifest written", "path", path)
+		}
+	}
+	if cfg.RunHistory && db != nil {
+		if err := exporter.NewExporter(db, log).ExportRunHistory(m); err != nil {
+			log.Warn("Could not record run history", logger.Err(err))
+		}
+	}
+}
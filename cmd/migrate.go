@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+
+	"quanticfy-test/internal/config"
+	"quanticfy-test/internal/database"
+	"quanticfy-test/internal/migrations"
+	"quanticfy-test/pkg/logger"
+)
+
+// runMigrate implements `quanticfy migrate <up|down|status> [steps]`
+func runMigrate(args []string) {
+	if len(args) == 0 {
+		printUsage()
+		os.Exit(2)
+	}
+
+	log := logger.Default()
+	action := args[0]
+	steps := 0
+	if len(args) > 1 {
+		n, err := strconv.Atoi(args[1])
+		if err != nil || n < 0 {
+			log.Fatal("Invalid step count", "steps", args[1])
+		}
+		steps = n
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		log.Fatal("Failed to load configuration", logger.Err(err))
+	}
+	log = newLogger(cfg, logger.NewRunID())
+
+	conn, err := database.NewConnection(newDBConfig(cfg))
+	if err != nil {
+		log.Fatal("Failed to connect to database", logger.Err(err))
+	}
+	defer conn.Close()
+
+	migrator, err := migrations.NewMigrator(conn.DB, log)
+	if err != nil {
+		log.Fatal("Failed to load migrations", logger.Err(err))
+	}
+
+	switch action {
+	case "up":
+		applied, err := migrator.Up(steps)
+		if err != nil {
+			log.Fatal("Migration failed", "applied", applied, logger.Err(err))
+		}
+		log.Info("Applied migrations", "applied", applied)
+
+	case "down":
+		rolledBack, err := migrator.Down(steps)
+		if err != nil {
+			log.Fatal("Rollback failed", "rolled_back", rolledBack, logger.Err(err))
+		}
+		log.Info("Rolled back migrations", "rolled_back", rolledBack)
+
+	case "status":
+		statuses, err := migrator.Status()
+		if err != nil {
+			log.Fatal("Failed to read migration status", logger.Err(err))
+		}
+		for _, status := range statuses {
+			state := "pending"
+			if status.Applied {
+				state = "applied " + status.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%04d_%-32s %s\n", status.Version, status.Name, state)
+		}
+
+	default:
+		fmt.Fprintf(os.Stderr, "unknown migrate action %q\n\n", action)
+		printUsage()
+		os.Exit(2)
+	}
+}
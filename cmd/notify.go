@@ -0,0 +1,108 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"quanticfy-test/internal/config"
+	"quanticfy-test/internal/manifest"
+	"quanticfy-test/internal/notify"
+	"quanticfy-test/internal/quality"
+	"quanticfy-test/pkg/logger"
+)
+
+// newNotifier builds the dispatcher of the configured channels; without any
+// channel it sends nothing
+func newNotifier(cfg *config.Config) *notify.Dispatcher {
+	d := notify.NewDispatcher(cfg.NotifyEvents, time.Duration(cfg.NotifyTimeout)*time.Second)
+	client := &http.Client{}
+	if cfg.NotifyWebhookURL != "" {
+		d.Add(notify.NewWebhook(cfg.NotifyWebhookURL, client))
+	}
+	if cfg.NotifySlackWebhookURL != "" {
+		d.Add(notify.NewSlack(cfg.NotifySlackWebhookURL, client))
+	}
+	if cfg.NotifySMTPAddr != "" {
+		d.Add(notify.NewEmail(notify.SMTPConfig{
+			Addr:     cfg.NotifySMTPAddr,
+			Username: cfg.NotifySMTPUsername,
+			Password: cfg.NotifySMTPPassword,
+			From:     cfg.NotifyEmailFrom,
+			To:       cfg.NotifyEmailTo,
+		}))
+	}
+	return d
+}
+
+// sendNotification only warns when a channel fails: the run outcome is
+// already decided and logged
+func sendNotification(d *notify.Dispatcher, event notify.Event, log *logger.Logger) {
+	if err := d.Send(event); err != nil {
+		log.Warn("Could not send notification", "event", event.Kind, logger.Err(err))
+		return
+	}
+	log.Debug("Notification sent", "event", event.Kind, "channels", d.Channels())
+}
+
+// runNotify implements `quanticfy notify test`
+func runNotify(args []string) {
+	if len(args) == 0 || args[0] != "test" {
+		fmt.Fprintln(os.Stderr, "Usage:")
+		fmt.Fprintln(os.Stderr, "  quanticfy notify test [-event success|failure|dq_breach]")
+		fmt.Fprintln(os.Stderr, "                                   Send a sample event to the configured channels")
+		os.Exit(2)
+	}
+
+	fs := flag.NewFlagSet("notify test", flag.ExitOnError)
+	kind := fs.String("event", notify.EventFailure, "event to send: "+strings.Join(notify.Kinds, ", "))
+	fs.Parse(args[1:])
+	if !slices.Contains(notify.Kinds, *kind) {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	log := logger.Default()
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		log.Fatal("Failed to load configuration", logger.Err(err))
+	}
+	runID := logger.NewRunID()
+	log = newLogger(cfg, runID)
+	// The test event must go out even when the kind is filtered for runs
+	cfg.NotifyEvents = notify.Kinds
+	notifier := newNotifier(cfg)
+	if len(notifier.Channels()) == 0 {
+		log.Fatal("No notification channel configured (NOTIFY_WEBHOOK_URL, NOTIFY_SLACK_WEBHOOK_URL or NOTIFY_SMTP_ADDR)")
+	}
+
+	if err := notifier.Send(sampleEvent(runID, *kind)); err != nil {
+		log.Fatal("Failed to send test notification", logger.Err(err))
+	}
+	log.Info("Test notification sent", "event", *kind, "channels", notifier.Channels())
+}
+
+// sampleEvent is an event of the given kind for a made-up run
+func sampleEvent(runID, kind string) notify.Event {
+	now := time.Now()
+	m := manifest.New(runID, softwareVersion(), now.Add(-time.Minute), nil)
+	m.Inputs["CustomerEventData"] = 1000
+	m.Customers = 400
+	m.TopCustomers = 10
+	m.SetRevenueThreshold(1234.5)
+	m.AddOutput(manifest.OutputTable, "test_export_"+now.Format("20060102"), 10)
+
+	switch kind {
+	case notify.EventDQBreach:
+		return notify.BreachEvent(m, "compute", []quality.Breach{{Metric: "missing_price_rate", Rate: 0.05, Threshold: 0.01}})
+	case notify.EventFailure:
+		m.Finish(now, "load", fmt.Errorf("test notification"))
+	default:
+		m.Finish(now, "", nil)
+	}
+	return notify.RunEvent(m)
+}
@@ -0,0 +1,346 @@
+package analytics
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"quanticfy-test/internal/models"
+	"quanticfy-test/pkg/logger"
+)
+
+// AssociationRule is "customers who buy Antecedent also buy Consequent"
+type AssociationRule struct {
+	Antecedent []int32
+	Consequent []int32
+	// Count is the number of orders containing both sides
+	Count      int
+	Support    float64
+	Confidence float64
+	Lift       float64
+}
+
+// BasketReport holds the frequent itemsets' association rules for one segment
+type BasketReport struct {
+	Segment      string
+	Transactions int
+	Itemsets     int
+	Rules        []AssociationRule
+}
+
+// BasketAnalyzer mines association rules with FP-Growth over orders
+// (CustomerEventData lines grouped by EventID)
+type BasketAnalyzer struct {
+	minSupport    float64
+	minConfidence float64
+	maxItemset    int
+	log           *logger.Logger
+}
+
+func NewBasketAnalyzer(minSupport, minConfidence float64, maxItemset int, log *logger.Logger) *BasketAnalyzer {
+	if maxItemset < 2 {
+		maxItemset = 2
+	}
+	return &BasketAnalyzer{minSupport: minSupport, minConfidence: minConfidence, maxItemset: maxItemset, log: log}
+}
+
+// Analyze mines rules over every order, or only over orders of customers in
+// segment when it is not nil. segmentName labels the result ("all", "top").
+func (a *BasketAnalyzer) Analyze(
+	events []models.CustomerEventData,
+	segment map[int64]bool,
+	segmentName string,
+) *BasketReport {
+
+	a.log.Info("Mining association rules", "segment", segmentName, "min_support", a.minSupport,
+		"min_confidence", a.minConfidence, "max_itemset", a.maxItemset)
+	startTime := time.Now()
+
+	transactions := buildTransactions(events, segment)
+	report := &BasketReport{Segment: segmentName, Transactions: len(transactions)}
+	if len(transactions) == 0 {
+		a.log.Warn("No orders to mine")
+		return report
+	}
+
+	minCount := int(a.minSupport * float64(len(transactions)))
+	if minCount < 1 {
+		minCount = 1
+	}
+
+	paths := make([]weightedPath, len(transactions))
+	for i, items := range transactions {
+		paths[i] = weightedPath{items: items, count: 1}
+	}
+
+	var itemsets []itemset
+	mineFPTree(newFPTree(paths, minCount), nil, minCount, a.maxItemset, &itemsets)
+	report.Itemsets = len(itemsets)
+
+	counts := make(map[string]int, len(itemsets))
+	for _, set := range itemsets {
+		counts[ItemsetKey(set.items)] = set.count
+	}
+
+	total := float64(len(transactions))
+	for _, set := range itemsets {
+		if len(set.items) < 2 {
+			continue
+		}
+		for _, antecedent := range properSubsets(set.items) {
+			consequent := difference(set.items, antecedent)
+			confidence := float64(set.count) / float64(counts[ItemsetKey(antecedent)])
+			if confidence < a.minConfidence {
+				continue
+			}
+			consequentSupport := float64(counts[ItemsetKey(consequent)]) / total
+			report.Rules = append(report.Rules, AssociationRule{
+				Antecedent: antecedent,
+				Consequent: consequent,
+				Count:      set.count,
+				Support:    float64(set.count) / total,
+				Confidence: confidence,
+				Lift:       confidence / consequentSupport,
+			})
+		}
+	}
+
+	sort.Slice(report.Rules, func(i, j int) bool {
+		ri, rj := report.Rules[i], report.Rules[j]
+		if ri.Lift != rj.Lift {
+			return ri.Lift > rj.Lift
+		}
+		if ri.Confidence != rj.Confidence {
+			return ri.Confidence > rj.Confidence
+		}
+		return ri.Key() < rj.Key()
+	})
+
+	a.log.Info("Mined association rules", "itemsets", report.Itemsets, logger.Rows(len(report.Rules)),
+		"orders", report.Transactions, logger.Duration(time.Since(startTime)))
+
+	shown := len(report.Rules)
+	if shown > 10 {
+		shown = 10
+	}
+	for _, rule := range report.Rules[:shown] {
+		a.log.Info("Association rule", "antecedent", ItemsetKey(rule.Antecedent), "consequent", ItemsetKey(rule.Consequent),
+			"support", rule.Support, "confidence", rule.Confidence, "lift", rule.Lift)
+	}
+
+	return report
+}
+
+// Key identifies a rule by its two sides
+func (r AssociationRule) Key() string {
+	return ItemsetKey(r.Antecedent) + "=>" + ItemsetKey(r.Consequent)
+}
+
+// buildTransactions returns the distinct ContentIDs of each order, sorted
+func buildTransactions(events []models.CustomerEventData, segment map[int64]bool) [][]int32 {
+	byOrder := make(map[int64]map[int32]bool)
+	var orderIDs []int64
+	for _, event := range events {
+		if segment != nil && !segment[event.CustomerID] {
+			continue
+		}
+		items, exists := byOrder[event.EventID]
+		if !exists {
+			items = make(map[int32]bool)
+			byOrder[event.EventID] = items
+			orderIDs = append(orderIDs, event.EventID)
+		}
+		items[event.ContentID] = true
+	}
+
+	sort.Slice(orderIDs, func(i, j int) bool { return orderIDs[i] < orderIDs[j] })
+
+	transactions := make([][]int32, 0, len(orderIDs))
+	for _, id := range orderIDs {
+		items := make([]int32, 0, len(byOrder[id]))
+		for item := range byOrder[id] {
+			items = append(items, item)
+		}
+		sort.Slice(items, func(i, j int) bool { return items[i] < items[j] })
+		transactions = append(transactions, items)
+	}
+	return transactions
+}
+
+// itemset is a frequent set of ContentIDs (sorted) with its order count
+type itemset struct {
+	items []int32
+	count int
+}
+
+// weightedPath is a transaction, or a conditional pattern base path, with its count
+type weightedPath struct {
+	items []int32
+	count int
+}
+
+type fpNode struct {
+	item     int32
+	count    int
+	parent   *fpNode
+	children map[int32]*fpNode
+	// next links the nodes holding the same item
+	next *fpNode
+}
+
+type fpTree struct {
+	root    *fpNode
+	headers map[int32]*fpNode
+	counts  map[int32]int
+	// items lists the frequent items, least frequent first (mining order)
+	items []int32
+}
+
+// newFPTree builds an FP-tree from weighted paths, keeping items reaching minCount
+func newFPTree(paths []weightedPath, minCount int) *fpTree {
+	counts := make(map[int32]int)
+	for _, path := range paths {
+		for _, item := range path.items {
+			counts[item] += path.count
+		}
+	}
+
+	tree := &fpTree{
+		root:    &fpNode{children: make(map[int32]*fpNode)},
+		headers: make(map[int32]*fpNode),
+		counts:  make(map[int32]int),
+	}
+	for item, count := range counts {
+		if count >= minCount {
+			tree.counts[item] = count
+			tree.items = append(tree.items, item)
+		}
+	}
+
+	// Most frequent first inside the tree; ties broken by ContentID for determinism
+	more := func(a, b int32) bool {
+		if tree.counts[a] != tree.counts[b] {
+			return tree.counts[a] > tree.counts[b]
+		}
+		return a < b
+	}
+	sort.Slice(tree.items, func(i, j int) bool { return more(tree.items[j], tree.items[i]) })
+
+	for _, path := range paths {
+		items := make([]int32, 0, len(path.items))
+		for _, item := range path.items {
+			if _, frequent := tree.counts[item]; frequent {
+				items = append(items, item)
+			}
+		}
+		sort.Slice(items, func(i, j int) bool { return more(items[i], items[j]) })
+		tree.insert(items, path.count)
+	}
+
+	return tree
+}
+
+func (t *fpTree) insert(items []int32, count int) {
+	node := t.root
+	for _, item := range items {
+		child, exists := node.children[item]
+		if !exists {
+			child = &fpNode{item: item, parent: node, children: make(map[int32]*fpNode)}
+			child.next = t.headers[item]
+			t.headers[item] = child
+			node.children[item] = child
+		}
+		child.count += count
+		node = child
+	}
+}
+
+// mineFPTree appends every frequent itemset of the tree, extended by suffix, to out
+func mineFPTree(tree *fpTree, suffix []int32, minCount, maxLen int, out *[]itemset) {
+	for _, item := range tree.items {
+		items := make([]int32, 0, len(suffix)+1)
+		items = append(items, suffix...)
+		items = append(items, item)
+		sort.Slice(items, func(i, j int) bool { return items[i] < items[j] })
+		*out = append(*out, itemset{items: items, count: tree.counts[item]})
+
+		if len(items) >= maxLen {
+			continue
+		}
+
+		// Conditional pattern base: the prefix path of every node holding item
+		var base []weightedPath
+		for node := tree.headers[item]; node != nil; node = node.next {
+			var path []int32
+			for parent := node.parent; parent != nil && parent.parent != nil; parent = parent.parent {
+				path = append(path, parent.item)
+			}
+			if len(path) > 0 {
+				base = append(base, weightedPath{items: path, count: node.count})
+			}
+		}
+		if len(base) == 0 {
+			continue
+		}
+
+		conditional := newFPTree(base, minCount)
+		if len(conditional.items) > 0 {
+			mineFPTree(conditional, items, minCount, maxLen, out)
+		}
+	}
+}
+
+// properSubsets returns every non-empty proper subset of a sorted itemset
+func properSubsets(items []int32) [][]int32 {
+	n := len(items)
+	subsets := make([][]int32, 0, (1<<n)-2)
+	for mask := 1; mask < (1<<n)-1; mask++ {
+		var subset []int32
+		for i := 0; i < n; i++ {
+			if mask&(1<<i) != 0 {
+				subset = append(subset, items[i])
+			}
+		}
+		subsets = append(subsets, subset)
+	}
+	return subsets
+}
+
+// difference returns the items of set not in subset; both are sorted
+func difference(set, subset []int32) []int32 {
+	var result []int32
+	j := 0
+	for _, item := range set {
+		if j < len(subset) && subset[j] == item {
+			j++
+			continue
+		}
+		result = append(result, item)
+	}
+	return result
+}
+
+// ItemsetKey formats a sorted itemset as "12,34,56"
+func ItemsetKey(items []int32) string {
+	parts := make([]string, len(items))
+	for i, item := range items {
+		parts[i] = strconv.FormatInt(int64(item), 10)
+	}
+	return strings.Join(parts, ",")
+}
+
+// ClientItemsetKey formats an itemset with client-side ContentIDs, falling
+// back to the internal ID when the client ID is unknown
+func ClientItemsetKey(items []int32, clientContentIDs map[int32]int64) string {
+	parts := make([]string, len(items))
+	for i, item := range items {
+		if clientID, ok := clientContentIDs[item]; ok {
+			parts[i] = strconv.FormatInt(clientID, 10)
+		} else {
+			parts[i] = fmt.Sprintf("internal:%d", item)
+		}
+	}
+	return strings.Join(parts, ",")
+}
@@ -0,0 +1,156 @@
+package analytics
+
+import (
+	"math"
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"quanticfy-test/internal/models"
+	"quanticfy-test/pkg/logger"
+)
+
+// orders builds purchase lines: one order per slice of ContentIDs, all bought
+// by customer 1 unless customers gives another one per order
+func orders(baskets [][]int32, customers ...int64) []models.CustomerEventData {
+	var events []models.CustomerEventData
+	for i, basket := range baskets {
+		customerID := int64(1)
+		if i < len(customers) {
+			customerID = customers[i]
+		}
+		for _, item := range basket {
+			events = append(events, models.CustomerEventData{EventID: int64(i + 1), CustomerID: customerID, ContentID: item})
+		}
+	}
+	return events
+}
+
+// bruteForceItemsets counts every subset (up to maxLen items) of every
+// transaction and keeps those reaching minCount
+func bruteForceItemsets(transactions [][]int32, minCount, maxLen int) map[string]int {
+	counts := make(map[string]int)
+	for _, items := range transactions {
+		for mask := 1; mask < 1<<len(items); mask++ {
+			var subset []int32
+			for i, item := range items {
+				if mask&(1<<i) != 0 {
+					subset = append(subset, item)
+				}
+			}
+			if len(subset) <= maxLen {
+				counts[ItemsetKey(subset)]++
+			}
+		}
+	}
+	for key, count := range counts {
+		if count < minCount {
+			delete(counts, key)
+		}
+	}
+	return counts
+}
+
+func TestFPGrowthMatchesBruteForce(t *testing.T) {
+	rng := rand.New(rand.NewSource(3))
+	var baskets [][]int32
+	for i := 0; i < 300; i++ {
+		var basket []int32
+		for item := int32(1); item <= 8; item++ {
+			// Low IDs are popular, so itemsets of several sizes are frequent
+			if rng.Float64() < 0.6/float64(item) {
+				basket = append(basket, item)
+			}
+		}
+		// Item 9 is only ever bought with item 1
+		if len(basket) > 0 && basket[0] == 1 && rng.Float64() < 0.5 {
+			basket = append(basket, 9)
+		}
+		// Duplicate lines in one order count once
+		if len(basket) > 0 {
+			basket = append(basket, basket[0])
+		}
+		baskets = append(baskets, basket)
+	}
+	transactions := buildTransactions(orders(baskets), nil)
+
+	for _, tt := range []struct{ minCount, maxLen int }{{1, 2}, {3, 3}, {10, 4}, {60, 3}} {
+		var mined []itemset
+		mineFPTree(newFPTree(toPaths(transactions), tt.minCount), nil, tt.minCount, tt.maxLen, &mined)
+
+		got := make(map[string]int, len(mined))
+		for _, set := range mined {
+			if _, duplicate := got[ItemsetKey(set.items)]; duplicate {
+				t.Errorf("minCount %d: itemset %v mined twice", tt.minCount, set.items)
+			}
+			got[ItemsetKey(set.items)] = set.count
+		}
+		if want := bruteForceItemsets(transactions, tt.minCount, tt.maxLen); !reflect.DeepEqual(got, want) {
+			t.Errorf("minCount %d maxLen %d: FP-Growth found %d itemsets, brute force %d",
+				tt.minCount, tt.maxLen, len(got), len(want))
+		}
+	}
+}
+
+func toPaths(transactions [][]int32) []weightedPath {
+	paths := make([]weightedPath, len(transactions))
+	for i, items := range transactions {
+		paths[i] = weightedPath{items: items, count: 1}
+	}
+	return paths
+}
+
+func TestAnalyzeRules(t *testing.T) {
+	// 10 orders: bread (1) in 6, butter (2) in 5, bread and butter together in 4
+	baskets := [][]int32{
+		{1, 2}, {1, 2}, {1, 2}, {1, 2, 3},
+		{1}, {1, 3},
+		{2},
+		{3}, {3}, {4},
+	}
+	report := NewBasketAnalyzer(0.3, 0.6, 3, logger.Discard()).Analyze(orders(baskets), nil, "all")
+
+	if report.Transactions != 10 {
+		t.Fatalf("transactions = %d, want 10", report.Transactions)
+	}
+	// Frequent (>= 3 orders): {1}, {2}, {3}, {1,2}
+	if report.Itemsets != 4 {
+		t.Errorf("itemsets = %d, want 4", report.Itemsets)
+	}
+	// 1=>2 has confidence 4/6, 2=>1 has 4/5; both have lift 4/10 / (6/10 * 5/10)
+	if len(report.Rules) != 2 {
+		t.Fatalf("got rules %+v, want 1=>2 and 2=>1", report.Rules)
+	}
+	first, second := report.Rules[0], report.Rules[1]
+	if first.Key() != "2=>1" || second.Key() != "1=>2" {
+		t.Errorf("rules ordered %s, %s; want 2=>1 (higher confidence) first", first.Key(), second.Key())
+	}
+	for _, rule := range report.Rules {
+		if rule.Count != 4 || math.Abs(rule.Support-0.4) > 1e-9 || math.Abs(rule.Lift-4.0/3) > 1e-9 {
+			t.Errorf("rule %s: count %d support %v lift %v, want 4, 0.4, 1.333", rule.Key(), rule.Count, rule.Support, rule.Lift)
+		}
+	}
+	if math.Abs(first.Confidence-0.8) > 1e-9 || math.Abs(second.Confidence-4.0/6) > 1e-9 {
+		t.Errorf("confidences %v and %v, want 0.8 and 0.667", first.Confidence, second.Confidence)
+	}
+}
+
+func TestAnalyzeSegment(t *testing.T) {
+	baskets := [][]int32{{1, 2}, {1, 2}, {3, 4}, {3, 4}}
+	report := NewBasketAnalyzer(0.5, 0.5, 2, logger.Discard()).Analyze(orders(baskets, 7, 7, 8, 8), map[int64]bool{8: true}, "top")
+	if report.Transactions != 2 {
+		t.Fatalf("transactions = %d, want the 2 orders of customer 8", report.Transactions)
+	}
+	for _, rule := range report.Rules {
+		if rule.Key() != "3=>4" && rule.Key() != "4=>3" {
+			t.Errorf("rule %s mixes in orders outside the segment", rule.Key())
+		}
+	}
+}
+
+func TestClientItemsetKey(t *testing.T) {
+	got := ClientItemsetKey([]int32{1, 2}, map[int32]int64{1: 500003})
+	if want := "500003,internal:2"; got != want {
+		t.Errorf("ClientItemsetKey = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,174 @@
+package analytics
+
+import (
+	"sort"
+	"time"
+
+	"quanticfy-test/internal/models"
+	"quanticfy-test/pkg/logger"
+)
+
+// ContentRevenue holds revenue analytics for one ContentID
+type ContentRevenue struct {
+	ContentID       int32
+	ClientContentID int64
+	Rank            int
+	Revenue         float64
+	Units           int
+	Customers       int
+	RevenueShare    float64
+	CumulativeShare float64
+
+	// Top-quantile customers compared with everyone else
+	TopSegmentRevenue float64
+	TopSegmentUnits   int
+	TopSegmentShare   float64
+	OtherShare        float64
+	// Lift is TopSegmentShare / OtherShare: above 1 means over-represented in the top segment
+	Lift float64
+}
+
+// ContentReport is the product-level revenue analysis of a run
+type ContentReport struct {
+	Contents     []ContentRevenue
+	TotalRevenue float64
+
+	// ParetoThreshold is the revenue share studied (e.g. 0.8), reached by
+	// ParetoProducts products, i.e. ParetoProductShare of all sold products
+	ParetoThreshold    float64
+	ParetoProducts     int
+	ParetoProductShare float64
+}
+
+type ContentAnalyzer struct {
+	topN            int
+	paretoThreshold float64
+	log             *logger.Logger
+}
+
+func NewContentAnalyzer(topN int, paretoThreshold float64, log *logger.Logger) *ContentAnalyzer {
+	return &ContentAnalyzer{topN: topN, paretoThreshold: paretoThreshold, log: log}
+}
+
+// TopSegment returns the CustomerIDs of the top customers, including every
+// member merged into them by identity resolution
+func TopSegment(topCustomers map[int64]*models.CustomerRevenue) map[int64]bool {
+	segment := make(map[int64]bool, len(topCustomers))
+	for id, customer := range topCustomers {
+		segment[id] = true
+		for _, memberID := range customer.MemberIDs {
+			segment[memberID] = true
+		}
+	}
+	return segment
+}
+
+// Analyze computes revenue and units per content, the cumulative Pareto curve
+// and the top-segment product mix. Unknown prices count as 0, as in the processor.
+func (a *ContentAnalyzer) Analyze(
+	events []models.CustomerEventData,
+	prices map[int32]float64,
+	clientContentIDs map[int32]int64,
+	topSegment map[int64]bool,
+) *ContentReport {
+
+	a.log.Info("Analyzing revenue by content")
+	startTime := time.Now()
+
+	byContent := make(map[int32]*ContentRevenue)
+	customers := make(map[int32]map[int64]bool)
+	var topTotal, otherTotal float64
+
+	for _, event := range events {
+		content, exists := byContent[event.ContentID]
+		if !exists {
+			content = &ContentRevenue{
+				ContentID:       event.ContentID,
+				ClientContentID: clientContentIDs[event.ContentID],
+			}
+			byContent[event.ContentID] = content
+			customers[event.ContentID] = make(map[int64]bool)
+		}
+
+		revenue := float64(event.Quantity) * prices[event.ContentID]
+		content.Revenue += revenue
+		content.Units += int(event.Quantity)
+		customers[event.ContentID][event.CustomerID] = true
+
+		if topSegment[event.CustomerID] {
+			content.TopSegmentRevenue += revenue
+			content.TopSegmentUnits += int(event.Quantity)
+			topTotal += revenue
+		} else {
+			otherTotal += revenue
+		}
+	}
+
+	report := &ContentReport{
+		Contents:        make([]ContentRevenue, 0, len(byContent)),
+		ParetoThreshold: a.paretoThreshold,
+	}
+	for id, content := range byContent {
+		content.Customers = len(customers[id])
+		report.TotalRevenue += content.Revenue
+		report.Contents = append(report.Contents, *content)
+	}
+
+	sort.Slice(report.Contents, func(i, j int) bool {
+		if report.Contents[i].Revenue != report.Contents[j].Revenue {
+			return report.Contents[i].Revenue > report.Contents[j].Revenue
+		}
+		return report.Contents[i].ContentID < report.Contents[j].ContentID
+	})
+
+	cumulative := 0.0
+	for i := range report.Contents {
+		c := &report.Contents[i]
+		c.Rank = i + 1
+		cumulative += c.Revenue
+		if report.TotalRevenue > 0 {
+			c.RevenueShare = c.Revenue / report.TotalRevenue
+			c.CumulativeShare = cumulative / report.TotalRevenue
+		}
+		if report.ParetoProducts == 0 && c.CumulativeShare >= a.paretoThreshold {
+			report.ParetoProducts = c.Rank
+		}
+
+		if topTotal > 0 {
+			c.TopSegmentShare = c.TopSegmentRevenue / topTotal
+		}
+		if otherTotal > 0 {
+			c.OtherShare = (c.Revenue - c.TopSegmentRevenue) / otherTotal
+		}
+		if c.OtherShare > 0 {
+			c.Lift = c.TopSegmentShare / c.OtherShare
+		}
+	}
+	if len(report.Contents) > 0 {
+		report.ParetoProductShare = float64(report.ParetoProducts) / float64(len(report.Contents))
+	}
+
+	a.log.Info("Analyzed contents", logger.Rows(len(report.Contents)), logger.Duration(time.Since(startTime)))
+	a.logReport(report)
+
+	return report
+}
+
+func (a *ContentAnalyzer) logReport(report *ContentReport) {
+	a.log.Info("Pareto", "products", report.ParetoProducts, "contents", len(report.Contents),
+		"product_share", report.ParetoProductShare, "revenue_share", report.ParetoThreshold)
+
+	topN := a.topN
+	if topN < 0 {
+		topN = 0
+	}
+	if topN > len(report.Contents) {
+		topN = len(report.Contents)
+	}
+
+	for _, c := range report.Contents[:topN] {
+		a.log.Info("Top content", "rank", c.Rank, "content_id", c.ContentID, "client_content_id", c.ClientContentID,
+			"revenue", c.Revenue, "revenue_share", c.RevenueShare, "units", c.Units, "customers", c.Customers,
+			"top_segment_lift", c.Lift)
+	}
+}
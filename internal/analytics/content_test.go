@@ -0,0 +1,33 @@
+package analytics
+
+import (
+	"testing"
+
+	"quanticfy-test/pkg/logger"
+)
+
+func TestContentAnalyzerTopN(t *testing.T) {
+	events := orders([][]int32{{1, 2}, {2, 3}})
+	for i := range events {
+		events[i].Quantity = 1
+	}
+	prices := map[int32]float64{1: 10, 2: 20, 3: 5}
+
+	tests := []struct {
+		name string
+		topN int
+	}{
+		{"negative", -1},
+		{"zero", 0},
+		{"fewer than the contents", 2},
+		{"more than the contents", 10},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := NewContentAnalyzer(tt.topN, 0.8, logger.Discard()).Analyze(events, prices, nil, nil)
+			if len(report.Contents) != 3 {
+				t.Errorf("contents = %d, want 3", len(report.Contents))
+			}
+		})
+	}
+}
@@ -0,0 +1,215 @@
+package analytics
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+
+	"quanticfy-test/internal/models"
+	"quanticfy-test/pkg/logger"
+)
+
+// Time bucket sizes for the revenue time series
+const (
+	GranularityDay   = "day"
+	GranularityWeek  = "week"
+	GranularityMonth = "month"
+)
+
+// Segments of the revenue time series
+const (
+	SegmentAll = "all"
+	SegmentTop = "top"
+)
+
+func ValidateGranularity(granularity string) error {
+	switch granularity {
+	case GranularityDay, GranularityWeek, GranularityMonth:
+		return nil
+	default:
+		return fmt.Errorf("unknown time series granularity %q", granularity)
+	}
+}
+
+// BucketStart truncates t (in UTC) to the start of its day, ISO week (Monday) or month
+func BucketStart(t time.Time, granularity string) time.Time {
+	t = t.UTC()
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	switch granularity {
+	case GranularityWeek:
+		offset := (int(day.Weekday()) + 6) % 7
+		return day.AddDate(0, 0, -offset)
+	case GranularityMonth:
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+	default:
+		return day
+	}
+}
+
+func nextBucket(start time.Time, granularity string) time.Time {
+	switch granularity {
+	case GranularityWeek:
+		return start.AddDate(0, 0, 7)
+	case GranularityMonth:
+		return start.AddDate(0, 1, 0)
+	default:
+		return start.AddDate(0, 0, 1)
+	}
+}
+
+// TimeBucket holds the activity of one segment over one period
+type TimeBucket struct {
+	Segment         string
+	PeriodStart     time.Time
+	Revenue         float64
+	Orders          int
+	Units           int
+	ActiveCustomers int
+}
+
+// TimeSeriesReport lists the buckets of every segment, by segment then period.
+// Periods without activity are included with zero values so the series is continuous.
+type TimeSeriesReport struct {
+	Granularity string
+	Buckets     []TimeBucket
+}
+
+type TimeSeriesAnalyzer struct {
+	granularity string
+	log         *logger.Logger
+}
+
+func NewTimeSeriesAnalyzer(granularity string, log *logger.Logger) *TimeSeriesAnalyzer {
+	return &TimeSeriesAnalyzer{granularity: granularity, log: log}
+}
+
+// bucketAccumulator collects one bucket's distinct orders and customers
+type bucketAccumulator struct {
+	bucket    TimeBucket
+	orders    map[int64]bool
+	customers map[int64]bool
+}
+
+// Analyze aggregates revenue, orders, units and active customers per period,
+// for every customer and for the top segment. Unknown prices count as 0, as
+// in the processor.
+func (a *TimeSeriesAnalyzer) Analyze(
+	events []models.CustomerEventData,
+	prices map[int32]float64,
+	topSegment map[int64]bool,
+) *TimeSeriesReport {
+
+	a.log.Info("Aggregating revenue", "granularity", a.granularity)
+	startTime := time.Now()
+
+	report := &TimeSeriesReport{Granularity: a.granularity}
+	if len(events) == 0 {
+		a.log.Warn("No events to aggregate")
+		return report
+	}
+
+	buckets := map[string]map[time.Time]*bucketAccumulator{
+		SegmentAll: make(map[time.Time]*bucketAccumulator),
+		SegmentTop: make(map[time.Time]*bucketAccumulator),
+	}
+	add := func(segment string, start time.Time, event models.CustomerEventData, revenue float64) {
+		acc, exists := buckets[segment][start]
+		if !exists {
+			acc = &bucketAccumulator{
+				bucket:    TimeBucket{Segment: segment, PeriodStart: start},
+				orders:    make(map[int64]bool),
+				customers: make(map[int64]bool),
+			}
+			buckets[segment][start] = acc
+		}
+		acc.bucket.Revenue += revenue
+		acc.bucket.Units += int(event.Quantity)
+		acc.orders[event.EventID] = true
+		acc.customers[event.CustomerID] = true
+	}
+
+	first := BucketStart(events[0].EventDate, a.granularity)
+	last := first
+	for _, event := range events {
+		start := BucketStart(event.EventDate, a.granularity)
+		if start.Before(first) {
+			first = start
+		}
+		if start.After(last) {
+			last = start
+		}
+
+		revenue := float64(event.Quantity) * prices[event.ContentID]
+		add(SegmentAll, start, event, revenue)
+		if topSegment[event.CustomerID] {
+			add(SegmentTop, start, event, revenue)
+		}
+	}
+
+	for _, segment := range []string{SegmentAll, SegmentTop} {
+		for start := first; !start.After(last); start = nextBucket(start, a.granularity) {
+			bucket := TimeBucket{Segment: segment, PeriodStart: start}
+			if acc, exists := buckets[segment][start]; exists {
+				bucket = acc.bucket
+				bucket.Orders = len(acc.orders)
+				bucket.ActiveCustomers = len(acc.customers)
+			}
+			report.Buckets = append(report.Buckets, bucket)
+		}
+	}
+
+	periods := len(report.Buckets) / 2
+	a.log.Info("Aggregated revenue", "events", len(events), logger.Rows(periods), "granularity", a.granularity,
+		"first_period", first.Format("2006-01-02"), "last_period", last.Format("2006-01-02"),
+		logger.Duration(time.Since(startTime)))
+
+	return report
+}
+
+// WriteCSV writes the time series to path, one row per segment and period
+func (r *TimeSeriesReport) WriteCSV(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("error creating time series file: %w", err)
+	}
+	defer f.Close()
+
+	w := csv.NewWriter(f)
+	header := []string{"Granularity", "Segment", "PeriodStart", "Revenue", "Orders", "Units", "ActiveCustomers"}
+	if err := w.Write(header); err != nil {
+		return fmt.Errorf("error writing time series file: %w", err)
+	}
+
+	buckets := make([]TimeBucket, len(r.Buckets))
+	copy(buckets, r.Buckets)
+	sort.SliceStable(buckets, func(i, j int) bool {
+		if !buckets[i].PeriodStart.Equal(buckets[j].PeriodStart) {
+			return buckets[i].PeriodStart.Before(buckets[j].PeriodStart)
+		}
+		return buckets[i].Segment < buckets[j].Segment
+	})
+
+	for _, b := range buckets {
+		record := []string{
+			r.Granularity,
+			b.Segment,
+			b.PeriodStart.Format("2006-01-02"),
+			strconv.FormatFloat(b.Revenue, 'f', 2, 64),
+			strconv.Itoa(b.Orders),
+			strconv.Itoa(b.Units),
+			strconv.Itoa(b.ActiveCustomers),
+		}
+		if err := w.Write(record); err != nil {
+			return fmt.Errorf("error writing time series file: %w", err)
+		}
+	}
+
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return fmt.Errorf("error writing time series file: %w", err)
+	}
+	return f.Close()
+}
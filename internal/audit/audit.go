@@ -0,0 +1,54 @@
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"quanticfy-test/pkg/logger"
+)
+
+// Record is one audited action on a customer
+type Record struct {
+	Time       time.Time   `json:"time"`
+	Action     string      `json:"action"`
+	CustomerID int64       `json:"customer_id"`
+	Reason     string      `json:"reason"`
+	Details    interface{} `json:"details,omitempty"`
+}
+
+// Trail writes audit records to the log and, when path is set, appends them
+// as JSON lines to path so they outlive the run's output
+type Trail struct {
+	path string
+	log  *logger.Logger
+}
+
+func NewTrail(path string, log *logger.Logger) *Trail {
+	return &Trail{path: path, log: log}
+}
+
+// Record logs and stores the given records
+func (t *Trail) Record(records ...Record) error {
+	for _, r := range records {
+		t.log.Info("Audit", "action", r.Action, "customer_id", r.CustomerID, "reason", r.Reason)
+	}
+	if t.path == "" || len(records) == 0 {
+		return nil
+	}
+
+	f, err := os.OpenFile(t.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("error opening audit log: %w", err)
+	}
+	defer f.Close()
+
+	enc := json.NewEncoder(f)
+	for _, r := range records {
+		if err := enc.Encode(r); err != nil {
+			return fmt.Errorf("error writing audit log: %w", err)
+		}
+	}
+	return f.Close()
+}
@@ -3,8 +3,15 @@ package config
 import (
 	"fmt"
 	"os"
+	"slices"
+	"strconv"
 	"strings"
 
+	"quanticfy-test/internal/notify"
+	"quanticfy-test/pkg/logger"
+	"quanticfy-test/pkg/progress"
+	"quanticfy-test/pkg/tracing"
+
 	"github.com/joho/godotenv"
 )
 
@@ -16,6 +23,147 @@ type Config struct {
 	DBName     string
 	Quantile   float64
 	SkipDB     bool
+
+	// Analysis window: [AnalysisFrom, AnalysisTo) as YYYY-MM-DD (empty = open),
+	// or AnalysisWindow as a rolling length such as "90d" or "12m"
+	AnalysisFrom   string
+	AnalysisTo     string
+	AnalysisWindow string
+	// RevenueWindows are extra windows exported as CA_<name> columns (e.g. 90d,365d,all)
+	RevenueWindows []string
+
+	// Trend flags: revenue over the last TrendPeriod (e.g. "3m") before
+	// TrendAsOf (default: end of the analysis window, else the run date)
+	// compared with the period before
+	TrendFlags         bool
+	TrendPeriod        string
+	TrendAsOf          string
+	TrendAtRiskDays    int
+	TrendGrowingRate   float64
+	TrendDecliningRate float64
+
+	// Outliers: statistical method on log revenue (none, iqr, mad, zscore),
+	// bulk purchase rule, review report and optional exclusion from the ranking
+	OutlierMethod           string
+	OutlierThreshold        float64
+	OutlierMaxEventQuantity int
+	OutlierExclude          bool
+	OutlierReportPath       string
+
+	// Exclusion lists: rules from the CustomerExclusion table and/or a CSV file
+	ExclusionsFromDB bool
+	ExclusionsFile   string
+
+	// Consent: only customers opted in to ConsentChannel are exported when a
+	// source (CustomerConsent table and/or CSV file) is configured
+	ConsentFromDB  bool
+	ConsentFile    string
+	ConsentChannel string
+
+	// Logging: LOG_LEVEL is debug, info, warn or error; LOG_FORMAT is text or json
+	LogLevel  string
+	LogFormat string
+
+	// Progress: PROGRESS is auto, bar, log or none; in log mode a line is
+	// written every ProgressLogInterval seconds
+	ProgressMode        string
+	ProgressLogInterval int
+
+	// Metrics: MetricsAddr serves /metrics during the run (empty = off);
+	// MetricsTextfile is written at the end for node_exporter (empty = off)
+	MetricsAddr     string
+	MetricsTextfile string
+
+	// Tracing: TRACE_EXPORTER is none, stdout or file (spans appended to
+	// TRACE_FILE); OTEL_SERVICE_NAME names the service in the spans
+	TraceExporter    string
+	TraceFile        string
+	TraceServiceName string
+
+	// Run manifest: RunManifestDir receives run_<run ID>.json (empty = off);
+	// RunHistory records the manifest in the run_history table
+	RunManifestDir string
+	RunHistory     bool
+
+	// Notifications: NotifyEvents (success, failure, dq_breach) are sent to
+	// every configured channel, each given NotifyTimeout seconds
+	NotifyEvents          []string
+	NotifyTimeout         int
+	NotifyWebhookURL      string
+	NotifySlackWebhookURL string
+	NotifySMTPAddr        string
+	NotifySMTPUsername    string
+	NotifySMTPPassword    string
+	NotifyEmailFrom       string
+	NotifyEmailTo         []string
+
+	// AuditLogPath receives audited actions as JSON lines (empty = log only)
+	AuditLogPath string
+
+	// Privacy mode: exported emails are replaced by their HMAC-SHA256 under
+	// PrivacyHMACKey; the mapping back to emails goes to test_email_pseudonyms
+	PrivacyMode         bool
+	PrivacyHMACKey      string
+	PrivacyMappingTable bool
+	// LogMaskEmails logs emails as j***@domain.com (defaults to PrivacyMode or
+	// EmailEncryption)
+	LogMaskEmails bool
+
+	// Email encryption: exported emails are encrypted with AES-256-GCM under
+	// the active key ("id:base64key" entries from EMAIL_ENCRYPTION_KEYS or a
+	// key file); EmailBlindIndexKey adds an HMAC column to join on
+	EmailEncryption              bool
+	EmailEncryptionKeys          string
+	EmailEncryptionKeyFile       string
+	EmailEncryptionKeyID         string
+	EmailEncryptionDeterministic bool
+	EmailBlindIndexKey           string
+
+	// Data quality: report destination and maximum acceptable rates (negative disables a check)
+	DQReportPath             string
+	DQMaxMissingPriceRate    float64
+	DQMaxMissingEmailRate    float64
+	DQMaxInvalidQuantityRate float64
+	DQMaxFutureEventRate     float64
+
+	// EmailSelection picks one email per customer: recent, primary or frequent
+	EmailSelection string
+
+	// Top customers without an email: placeholder, exclude, null, fallback or unreachable
+	EmailPolicy                 string
+	EmailPlaceholder            string
+	EmailFallbackChannelTypeIDs []int16
+
+	// Identity resolution merges CustomerIDs sharing an email (and optionally a phone)
+	IdentityResolution       bool
+	IdentityMatchPhone       bool
+	IdentityPhoneChannelType int16
+
+	// ExportClientIDs adds the client-side ClientCustomerID to the export
+	ExportClientIDs bool
+
+	// OrderMetrics enables order-level aggregation by EventID
+	OrderMetrics bool
+
+	// Content analytics: revenue per ContentID, top products and Pareto curve
+	ContentAnalytics bool
+	ContentTopN      int
+	ParetoThreshold  float64
+
+	// Basket analysis: association rules between ContentIDs bought in the same order
+	BasketAnalysis       bool
+	BasketMinSupport     float64
+	BasketMinConfidence  float64
+	BasketMaxItemset     int
+	BasketTopSegmentOnly bool
+
+	// Time series: revenue, orders and active customers per day, week or month
+	TimeSeries            bool
+	TimeSeriesGranularity string
+	TimeSeriesCSVPath     string
+
+	// ExportChannels are ChannelType names exported as extra contact columns
+	ExportChannels []string
 }
 
 
@@ -33,7 +181,222 @@ func LoadConfig() (*Config, error) {
 		DBPassword: getEnv("DB_PASSWORD", ""),
 		DBName:     getEnv("DB_NAME", "quanticfy_test"),
 		Quantile:   0.025, // Default quantile value (2.5%)
-		SkipDB:     getEnvBool("SKIP_DB", false),
+
+		AnalysisFrom:   getEnv("ANALYSIS_FROM", "2020-04-01"),
+		AnalysisTo:     getEnv("ANALYSIS_TO", ""),
+		AnalysisWindow: getEnv("ANALYSIS_WINDOW", ""),
+		RevenueWindows: getEnvList("REVENUE_WINDOWS"),
+
+		TrendPeriod: getEnv("TREND_PERIOD", "3m"),
+		TrendAsOf:   getEnv("TREND_AS_OF", ""),
+
+		OutlierMethod:     strings.ToLower(getEnv("OUTLIER_METHOD", "none")),
+		OutlierReportPath: getEnv("OUTLIER_REPORT_PATH", ""),
+		AuditLogPath:      getEnv("AUDIT_LOG_PATH", ""),
+
+		LogLevel:  strings.ToLower(getEnv("LOG_LEVEL", "info")),
+		LogFormat: strings.ToLower(getEnv("LOG_FORMAT", logger.FormatText)),
+
+		ProgressMode: strings.ToLower(getEnv("PROGRESS", progress.ModeAuto)),
+
+		MetricsAddr:     getEnv("METRICS_ADDR", ""),
+		MetricsTextfile: getEnv("METRICS_TEXTFILE", ""),
+
+		TraceExporter:    strings.ToLower(getEnv("TRACE_EXPORTER", tracing.ExporterNone)),
+		TraceFile:        getEnv("TRACE_FILE", ""),
+		TraceServiceName: getEnv("OTEL_SERVICE_NAME", "quanticfy"),
+
+		RunManifestDir: getEnv("RUN_MANIFEST_DIR", ""),
+
+		NotifyEvents:          getEnvList("NOTIFY_EVENTS"),
+		NotifyWebhookURL:      getEnv("NOTIFY_WEBHOOK_URL", ""),
+		NotifySlackWebhookURL: getEnv("NOTIFY_SLACK_WEBHOOK_URL", ""),
+		NotifySMTPAddr:        getEnv("NOTIFY_SMTP_ADDR", ""),
+		NotifySMTPUsername:    getEnv("NOTIFY_SMTP_USERNAME", ""),
+		NotifySMTPPassword:    os.Getenv("NOTIFY_SMTP_PASSWORD"),
+		NotifyEmailFrom:       getEnv("NOTIFY_EMAIL_FROM", ""),
+		NotifyEmailTo:         getEnvList("NOTIFY_EMAIL_TO"),
+
+		PrivacyHMACKey: os.Getenv("PRIVACY_HMAC_KEY"),
+
+		EmailEncryptionKeys:    os.Getenv("EMAIL_ENCRYPTION_KEYS"),
+		EmailEncryptionKeyFile: getEnv("EMAIL_ENCRYPTION_KEY_FILE", ""),
+		EmailEncryptionKeyID:   getEnv("EMAIL_ENCRYPTION_KEY_ID", ""),
+		EmailBlindIndexKey:     os.Getenv("EMAIL_BLIND_INDEX_KEY"),
+
+		ConsentFile:    getEnv("CONSENT_FILE", ""),
+		ConsentChannel: strings.ToLower(getEnv("CONSENT_CHANNEL", "email")),
+
+		ExclusionsFile: getEnv("EXCLUSIONS_FILE", ""),
+
+		DQReportPath: getEnv("DQ_REPORT_PATH", ""),
+
+		EmailSelection:   strings.ToLower(getEnv("EMAIL_SELECTION", "recent")),
+		EmailPolicy:      strings.ToLower(getEnv("EMAIL_POLICY", "placeholder")),
+		EmailPlaceholder: getEnv("EMAIL_PLACEHOLDER", "no-email@unknown.com"),
+		ExportChannels:   getEnvList("EXPORT_CHANNELS"),
+
+		TimeSeriesGranularity: strings.ToLower(getEnv("TIMESERIES_GRANULARITY", "month")),
+		TimeSeriesCSVPath:     getEnv("TIMESERIES_CSV_PATH", ""),
+	}
+
+	// Boolean and numeric settings fail the configuration when they do not parse
+	boolSettings := []struct {
+		key          string
+		target       *bool
+		defaultValue bool
+	}{
+		{"SKIP_DB", &config.SkipDB, false},
+		{"TREND_FLAGS", &config.TrendFlags, false},
+		{"OUTLIER_EXCLUDE", &config.OutlierExclude, false},
+		{"RUN_HISTORY", &config.RunHistory, true},
+		{"PRIVACY_MODE", &config.PrivacyMode, false},
+		{"PRIVACY_MAPPING_TABLE", &config.PrivacyMappingTable, true},
+		{"EMAIL_ENCRYPTION", &config.EmailEncryption, false},
+		{"EMAIL_ENCRYPTION_DETERMINISTIC", &config.EmailEncryptionDeterministic, false},
+		{"CONSENT_FROM_DB", &config.ConsentFromDB, false},
+		{"EXCLUSIONS_FROM_DB", &config.ExclusionsFromDB, false},
+		{"EXPORT_CLIENT_IDS", &config.ExportClientIDs, false},
+		{"ORDER_METRICS", &config.OrderMetrics, false},
+		{"CONTENT_ANALYTICS", &config.ContentAnalytics, false},
+		{"BASKET_ANALYSIS", &config.BasketAnalysis, false},
+		{"BASKET_TOP_SEGMENT_ONLY", &config.BasketTopSegmentOnly, false},
+		{"TIMESERIES", &config.TimeSeries, false},
+		{"IDENTITY_RESOLUTION", &config.IdentityResolution, false},
+		{"IDENTITY_MATCH_PHONE", &config.IdentityMatchPhone, false},
+	}
+	for _, setting := range boolSettings {
+		if *setting.target, err = getEnvBool(setting.key, setting.defaultValue); err != nil {
+			return nil, err
+		}
+	}
+	intSettings := []struct {
+		key          string
+		target       *int
+		defaultValue int
+	}{
+		{"TREND_AT_RISK_DAYS", &config.TrendAtRiskDays, 90},
+		{"OUTLIER_MAX_EVENT_QUANTITY", &config.OutlierMaxEventQuantity, 0},
+		{"PROGRESS_LOG_INTERVAL", &config.ProgressLogInterval, 10},
+		{"NOTIFY_TIMEOUT", &config.NotifyTimeout, 10},
+		{"CONTENT_TOP_N", &config.ContentTopN, 20},
+		{"BASKET_MAX_ITEMSET", &config.BasketMaxItemset, 3},
+	}
+	for _, setting := range intSettings {
+		if *setting.target, err = getEnvInt(setting.key, setting.defaultValue); err != nil {
+			return nil, err
+		}
+	}
+	floatSettings := []struct {
+		key          string
+		target       *float64
+		defaultValue float64
+	}{
+		{"TREND_GROWING_RATE", &config.TrendGrowingRate, 0.1},
+		{"TREND_DECLINING_RATE", &config.TrendDecliningRate, 0.1},
+		{"OUTLIER_THRESHOLD", &config.OutlierThreshold, 0},
+		{"DQ_MAX_MISSING_PRICE_RATE", &config.DQMaxMissingPriceRate, -1},
+		{"DQ_MAX_MISSING_EMAIL_RATE", &config.DQMaxMissingEmailRate, -1},
+		{"DQ_MAX_INVALID_QUANTITY_RATE", &config.DQMaxInvalidQuantityRate, -1},
+		{"DQ_MAX_FUTURE_EVENT_RATE", &config.DQMaxFutureEventRate, -1},
+		{"PARETO_THRESHOLD", &config.ParetoThreshold, 0.8},
+		{"BASKET_MIN_SUPPORT", &config.BasketMinSupport, 0.005},
+		{"BASKET_MIN_CONFIDENCE", &config.BasketMinConfidence, 0.1},
+	}
+	for _, setting := range floatSettings {
+		if *setting.target, err = getEnvFloat(setting.key, setting.defaultValue); err != nil {
+			return nil, err
+		}
+	}
+
+	phoneChannel, err := getEnvInt16List("IDENTITY_PHONE_CHANNEL_TYPE", []int16{2})
+	if err != nil {
+		return nil, err
+	}
+	if len(phoneChannel) != 1 {
+		return nil, fmt.Errorf("IDENTITY_PHONE_CHANNEL_TYPE must be a single channel type")
+	}
+	config.IdentityPhoneChannelType = phoneChannel[0]
+
+	fallbackChannels, err := getEnvInt16List("EMAIL_FALLBACK_CHANNEL_TYPES", []int16{2})
+	if err != nil {
+		return nil, err
+	}
+	config.EmailFallbackChannelTypeIDs = fallbackChannels
+
+	if _, err := logger.ParseLevel(config.LogLevel); err != nil {
+		return nil, fmt.Errorf("LOG_LEVEL: %w", err)
+	}
+	if config.LogFormat != logger.FormatText && config.LogFormat != logger.FormatJSON {
+		return nil, fmt.Errorf("LOG_FORMAT must be %s or %s, got %q", logger.FormatText, logger.FormatJSON, config.LogFormat)
+	}
+	switch config.ProgressMode {
+	case progress.ModeAuto, progress.ModeBar, progress.ModeLog, progress.ModeNone:
+	default:
+		return nil, fmt.Errorf("PROGRESS must be %s, %s, %s or %s, got %q",
+			progress.ModeAuto, progress.ModeBar, progress.ModeLog, progress.ModeNone, config.ProgressMode)
+	}
+	if config.ProgressLogInterval <= 0 {
+		return nil, fmt.Errorf("PROGRESS_LOG_INTERVAL must be a positive number of seconds")
+	}
+	switch config.TraceExporter {
+	case tracing.ExporterNone, tracing.ExporterStdout:
+	case tracing.ExporterFile:
+		if config.TraceFile == "" {
+			return nil, fmt.Errorf("TRACE_FILE is required when TRACE_EXPORTER is %s", tracing.ExporterFile)
+		}
+	default:
+		return nil, fmt.Errorf("TRACE_EXPORTER must be %s, %s or %s, got %q",
+			tracing.ExporterNone, tracing.ExporterStdout, tracing.ExporterFile, config.TraceExporter)
+	}
+
+	if config.NotifyEvents == nil {
+		config.NotifyEvents = slices.Clone(notify.Kinds)
+	}
+	for i, kind := range config.NotifyEvents {
+		config.NotifyEvents[i] = strings.ToLower(kind)
+		if !slices.Contains(notify.Kinds, config.NotifyEvents[i]) {
+			return nil, fmt.Errorf("NOTIFY_EVENTS entries must be %s, got %q", strings.Join(notify.Kinds, ", "), kind)
+		}
+	}
+	if config.NotifyTimeout <= 0 {
+		return nil, fmt.Errorf("NOTIFY_TIMEOUT must be a positive number of seconds")
+	}
+	if config.NotifySMTPAddr != "" && (config.NotifyEmailFrom == "" || len(config.NotifyEmailTo) == 0) {
+		return nil, fmt.Errorf("NOTIFY_EMAIL_FROM and NOTIFY_EMAIL_TO are required when NOTIFY_SMTP_ADDR is set")
+	}
+
+	config.LogMaskEmails, err = getEnvBool("LOG_MASK_EMAILS", config.PrivacyMode || config.EmailEncryption)
+	if err != nil {
+		return nil, err
+	}
+	if config.PrivacyMode && config.PrivacyHMACKey == "" {
+		return nil, fmt.Errorf("PRIVACY_HMAC_KEY environment variable is required when PRIVACY_MODE is enabled")
+	}
+	if config.EmailEncryption {
+		if config.PrivacyMode {
+			return nil, fmt.Errorf("EMAIL_ENCRYPTION and PRIVACY_MODE cannot be enabled together")
+		}
+		if !config.LogMaskEmails {
+			return nil, fmt.Errorf("LOG_MASK_EMAILS cannot be disabled when EMAIL_ENCRYPTION is enabled")
+		}
+		if (config.EmailEncryptionKeys == "") == (config.EmailEncryptionKeyFile == "") {
+			return nil, fmt.Errorf("EMAIL_ENCRYPTION requires exactly one of EMAIL_ENCRYPTION_KEYS or EMAIL_ENCRYPTION_KEY_FILE")
+		}
+	}
+
+	if config.ContentTopN < 0 {
+		return nil, fmt.Errorf("CONTENT_TOP_N must not be negative, got %d", config.ContentTopN)
+	}
+	if config.ParetoThreshold <= 0 || config.ParetoThreshold > 1 {
+		return nil, fmt.Errorf("PARETO_THRESHOLD must be in (0, 1], got %v", config.ParetoThreshold)
+	}
+
+	if config.BasketMinSupport <= 0 || config.BasketMinSupport > 1 {
+		return nil, fmt.Errorf("BASKET_MIN_SUPPORT must be in (0, 1], got %v", config.BasketMinSupport)
+	}
+	if config.BasketMinConfidence < 0 || config.BasketMinConfidence > 1 {
+		return nil, fmt.Errorf("BASKET_MIN_CONFIDENCE must be in [0, 1], got %v", config.BasketMinConfidence)
 	}
 
 	if !config.SkipDB {
@@ -48,6 +411,30 @@ func LoadConfig() (*Config, error) {
 	return config, nil
 }
 
+// redacted replaces a secret in Redacted, keeping unset secrets empty
+const redacted = "[REDACTED]"
+
+// Redacted returns a copy of the configuration whose passwords and keys are
+// replaced by [REDACTED], safe to write to a manifest
+func (c *Config) Redacted() Config {
+	clean := *c
+	for _, secret := range []*string{
+		&clean.DBPassword,
+		&clean.PrivacyHMACKey,
+		&clean.EmailEncryptionKeys,
+		&clean.EmailBlindIndexKey,
+		&clean.NotifySMTPPassword,
+		// webhook URLs embed their token
+		&clean.NotifyWebhookURL,
+		&clean.NotifySlackWebhookURL,
+	} {
+		if *secret != "" {
+			*secret = redacted
+		}
+	}
+	return clean
+}
+
 func getEnv(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
 		return value
@@ -55,17 +442,69 @@ func getEnv(key, defaultValue string) string {
 	return defaultValue
 }
 
-func getEnvBool(key string, defaultValue bool) bool {
+func getEnvBool(key string, defaultValue bool) (bool, error) {
 	val := strings.TrimSpace(strings.ToLower(os.Getenv(key)))
 	if val == "" {
-		return defaultValue
+		return defaultValue, nil
 	}
 	switch val {
 	case "1", "true", "yes", "y", "on":
-		return true
+		return true, nil
 	case "0", "false", "no", "n", "off":
-		return false
+		return false, nil
 	default:
-		return defaultValue
+		return false, fmt.Errorf("%s: invalid boolean %q", key, os.Getenv(key))
+	}
+}
+
+func getEnvInt(key string, defaultValue int) (int, error) {
+	val := strings.TrimSpace(os.Getenv(key))
+	if val == "" {
+		return defaultValue, nil
+	}
+	n, err := strconv.Atoi(val)
+	if err != nil {
+		return 0, fmt.Errorf("%s: invalid integer %q", key, val)
+	}
+	return n, nil
+}
+
+func getEnvFloat(key string, defaultValue float64) (float64, error) {
+	val := strings.TrimSpace(os.Getenv(key))
+	if val == "" {
+		return defaultValue, nil
+	}
+	f, err := strconv.ParseFloat(val, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%s: invalid number %q", key, val)
+	}
+	return f, nil
+}
+
+// getEnvInt16List parses a comma-separated list of small integers
+func getEnvInt16List(key string, defaultValue []int16) ([]int16, error) {
+	val := strings.TrimSpace(os.Getenv(key))
+	if val == "" {
+		return defaultValue, nil
+	}
+	var values []int16
+	for _, part := range strings.Split(val, ",") {
+		n, err := strconv.ParseInt(strings.TrimSpace(part), 10, 16)
+		if err != nil {
+			return nil, fmt.Errorf("%s: invalid value %q", key, part)
+		}
+		values = append(values, int16(n))
+	}
+	return values, nil
+}
+
+// getEnvList parses a comma-separated list, dropping empty entries
+func getEnvList(key string) []string {
+	var values []string
+	for _, part := range strings.Split(os.Getenv(key), ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			values = append(values, part)
+		}
 	}
+	return values
 }
@@ -0,0 +1,159 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGetEnvNumbers(t *testing.T) {
+	tests := []struct {
+		value   string
+		wantInt int
+		wantErr bool
+	}{
+		{"", 7, false},
+		{" 42 ", 42, false},
+		{"-3", -3, false},
+		{"ten", 0, true},
+		{"1.5", 0, true},
+	}
+	for _, tt := range tests {
+		t.Setenv("TEST_INT", tt.value)
+		got, err := getEnvInt("TEST_INT", 7)
+		if (err != nil) != tt.wantErr || got != tt.wantInt {
+			t.Errorf("getEnvInt(%q) = %d, %v; want %d, error %v", tt.value, got, err, tt.wantInt, tt.wantErr)
+		}
+	}
+
+	t.Setenv("TEST_FLOAT", "0.25")
+	if got, err := getEnvFloat("TEST_FLOAT", 1); got != 0.25 || err != nil {
+		t.Errorf("getEnvFloat(0.25) = %v, %v", got, err)
+	}
+	t.Setenv("TEST_FLOAT", "5%")
+	if _, err := getEnvFloat("TEST_FLOAT", 1); err == nil {
+		t.Error("getEnvFloat accepted 5%")
+	}
+}
+
+func TestGetEnvBool(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    bool
+		wantErr bool
+	}{
+		{"", true, false},
+		{"false", false, false},
+		{" Yes ", true, false},
+		{"0", false, false},
+		{"off", false, false},
+		{"ture", false, true},
+		{"yess", false, true},
+	}
+	for _, tt := range tests {
+		t.Setenv("TEST_BOOL", tt.value)
+		got, err := getEnvBool("TEST_BOOL", true)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("getEnvBool(%q) = %v, %v; want %v, error %v", tt.value, got, err, tt.want, tt.wantErr)
+		}
+		if err != nil && !strings.Contains(err.Error(), "TEST_BOOL") {
+			t.Errorf("getEnvBool(%q) error %q does not name the variable", tt.value, err)
+		}
+	}
+}
+
+func TestLoadConfigRejectsInvalidValues(t *testing.T) {
+	t.Setenv("SKIP_DB", "true")
+	if _, err := LoadConfig(); err != nil {
+		t.Fatalf("LoadConfig with defaults: %v", err)
+	}
+
+	for key, value := range map[string]string{
+		"TREND_AT_RISK_DAYS":        "90d",
+		"DQ_MAX_MISSING_PRICE_RATE": "1%",
+		"PRIVACY_MODE":              "ture",
+		"EMAIL_ENCRYPTION":          "yess",
+		"LOG_MASK_EMAILS":           "maybe",
+	} {
+		t.Run(key, func(t *testing.T) {
+			t.Setenv(key, value)
+			if _, err := LoadConfig(); err == nil {
+				t.Errorf("LoadConfig accepted %s=%q", key, value)
+			}
+		})
+	}
+}
+
+func TestLoadConfigLogMasking(t *testing.T) {
+	encryption := map[string]string{"EMAIL_ENCRYPTION": "true", "EMAIL_ENCRYPTION_KEYS": "k1:" + strings.Repeat("ab", 32)}
+	tests := []struct {
+		name     string
+		env      map[string]string
+		wantMask bool
+		wantErr  bool
+	}{
+		{name: "default", env: nil, wantMask: false},
+		{name: "privacy mode", env: map[string]string{"PRIVACY_MODE": "true", "PRIVACY_HMAC_KEY": strings.Repeat("k", 16)}, wantMask: true},
+		{name: "encryption", env: encryption, wantMask: true},
+		{name: "encryption with masking", env: merge(encryption, map[string]string{"LOG_MASK_EMAILS": "true"}), wantMask: true},
+		{name: "encryption without masking", env: merge(encryption, map[string]string{"LOG_MASK_EMAILS": "false"}), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("SKIP_DB", "true")
+			for key, value := range tt.env {
+				t.Setenv(key, value)
+			}
+			cfg, err := LoadConfig()
+			if tt.wantErr {
+				if err == nil {
+					t.Error("LoadConfig succeeded, want an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadConfig: %v", err)
+			}
+			if cfg.LogMaskEmails != tt.wantMask {
+				t.Errorf("LogMaskEmails = %v, want %v", cfg.LogMaskEmails, tt.wantMask)
+			}
+		})
+	}
+}
+
+func merge(a, b map[string]string) map[string]string {
+	merged := make(map[string]string, len(a)+len(b))
+	for key, value := range a {
+		merged[key] = value
+	}
+	for key, value := range b {
+		merged[key] = value
+	}
+	return merged
+}
+
+func TestLoadConfigContentAnalyticsBounds(t *testing.T) {
+	tests := []struct {
+		key     string
+		value   string
+		wantErr bool
+	}{
+		{"CONTENT_TOP_N", "0", false},
+		{"CONTENT_TOP_N", "-1", true},
+		{"PARETO_THRESHOLD", "1", false},
+		{"PARETO_THRESHOLD", "0.5", false},
+		{"PARETO_THRESHOLD", "0", true},
+		{"PARETO_THRESHOLD", "-0.2", true},
+		{"PARETO_THRESHOLD", "1.5", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.key+"="+tt.value, func(t *testing.T) {
+			t.Setenv("SKIP_DB", "true")
+			t.Setenv(tt.key, tt.value)
+			if _, err := LoadConfig(); (err != nil) != tt.wantErr {
+				t.Errorf("LoadConfig error = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
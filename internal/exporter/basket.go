@@ -0,0 +1,53 @@
+package exporter
+
+import (
+	"fmt"
+	"time"
+
+	"quanticfy-test/internal/analytics"
+	"quanticfy-test/pkg/logger"
+)
+
+// associationRulesTemplateTable is created by the migrations and holds the basket analysis schema
+const associationRulesTemplateTable = "test_association_rules_template"
+
+// ExportAssociationRules writes the basket analysis rules to test_association_rules_YYYYMMDD
+func (e *Exporter) ExportAssociationRules(report *analytics.BasketReport, clientContentIDs map[int32]int64) error {
+	startTime := time.Now()
+
+	tableName := fmt.Sprintf("test_association_rules_%s", time.Now().Format("20060102"))
+	e.log.Info("Exporting association rules", "table", tableName)
+	if err := e.createTableLike(tableName, associationRulesTemplateTable, nil); err != nil {
+		return fmt.Errorf("error creating association rules table: %w", err)
+	}
+	if err := e.clearTable(tableName); err != nil {
+		return err
+	}
+
+	if len(report.Rules) == 0 {
+		e.log.Warn("No association rules to export", "table", tableName)
+		return nil
+	}
+
+	rows := make([][]interface{}, 0, len(report.Rules))
+	for i, rule := range report.Rules {
+		var clientAntecedent, clientConsequent interface{}
+		if clientContentIDs != nil {
+			clientAntecedent = analytics.ClientItemsetKey(rule.Antecedent, clientContentIDs)
+			clientConsequent = analytics.ClientItemsetKey(rule.Consequent, clientContentIDs)
+		}
+		rows = append(rows, []interface{}{
+			report.Segment, analytics.ItemsetKey(rule.Antecedent), analytics.ItemsetKey(rule.Consequent),
+			clientAntecedent, clientConsequent, i + 1, rule.Count, rule.Support, rule.Confidence, rule.Lift,
+		})
+	}
+
+	columns := []string{"Segment", "Antecedent", "Consequent", "ClientAntecedent", "ClientConsequent",
+		"RuleRank", "Orders", "Support", "Confidence", "Lift"}
+	if err := e.batchInsert(tableName, columns, rows, "Exporting rules"); err != nil {
+		return fmt.Errorf("error inserting association rules: %w", err)
+	}
+
+	e.log.Info("Exported association rules", "table", tableName, logger.Rows(len(report.Rules)), logger.Duration(time.Since(startTime)))
+	return nil
+}
@@ -0,0 +1,340 @@
+package exporter
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"quanticfy-test/internal/models"
+	"quanticfy-test/internal/privacy"
+)
+
+// columnDef is a column name with its full SQL definition
+type columnDef struct {
+	Name       string
+	Definition string
+}
+
+// exportColumn is a column written by the exporter with the function that
+// extracts its value from a customer
+type exportColumn struct {
+	columnDef
+	value func(*models.CustomerRevenue) interface{}
+	// personal extracts the value of a column holding personal data, which is
+	// pseudonymized or encrypted when enabled; it replaces value
+	personal func(*models.CustomerRevenue) string
+}
+
+// baseColumns are the columns of test_export_template written on every export
+var baseColumns = []exportColumn{
+	{
+		columnDef: columnDef{Name: "CustomerID"},
+		value:     func(c *models.CustomerRevenue) interface{} { return c.CustomerID },
+	},
+	{
+		columnDef: columnDef{Name: "Email"},
+		personal:  func(c *models.CustomerRevenue) string { return c.Email },
+	},
+	{
+		columnDef: columnDef{Name: "CA"},
+		value:     func(c *models.CustomerRevenue) interface{} { return c.Revenue },
+	},
+	{
+		columnDef: columnDef{Name: "FallbackChannelTypeID"},
+		value: func(c *models.CustomerRevenue) interface{} {
+			return nullableChannelType(c.FallbackChannelTypeID)
+		},
+	},
+	{
+		columnDef: columnDef{Name: "FallbackContact"},
+		personal:  func(c *models.CustomerRevenue) string { return c.FallbackContact },
+	},
+}
+
+// insertColumns returns the base columns followed by the configured extra columns
+func (e *Exporter) insertColumns() []exportColumn {
+	columns := make([]exportColumn, 0, len(baseColumns)+len(e.extraColumns))
+	columns = append(columns, baseColumns...)
+	columns = append(columns, e.extraColumns...)
+	for i := range columns {
+		name, personal := columns[i].Name, columns[i].personal
+		if personal == nil {
+			continue
+		}
+		columns[i].value = func(c *models.CustomerRevenue) interface{} {
+			return nullableString(e.protect(c, name, personal(c)))
+		}
+	}
+	return columns
+}
+
+// PseudonymizeEmails makes the exports store the keyed hash of each email and
+// contact instead of the value. The email placeholder, which identifies no
+// one, is written as is.
+func (e *Exporter) PseudonymizeEmails(pseudonymizer *privacy.Pseudonymizer, placeholder string) {
+	e.pseudonymizer = pseudonymizer
+	e.placeholder = placeholder
+}
+
+// protect returns the value of a personal data column as it must be
+// written: pseudonymized in privacy mode, encrypted with email encryption
+func (e *Exporter) protect(c *models.CustomerRevenue, column, value string) string {
+	switch {
+	case value == "":
+		return ""
+	case e.pseudonymizer != nil:
+		if value == e.placeholder {
+			return value
+		}
+		return e.pseudonymizer.Pseudonymize(value)
+	case e.encryptor != nil:
+		return e.encrypted[c.CustomerID][column]
+	}
+	return value
+}
+
+// templateOnlyColumns are columns of test_export_template the exporter does
+// not write
+var templateOnlyColumns = []string{"InsertDate", "UpdateDate"}
+
+// addColumn registers an extra column, rejecting names already in use. MySQL
+// column names are case-insensitive, so are the comparisons.
+func (e *Exporter) addColumn(col exportColumn) error {
+	if !validColumnName.MatchString(col.Name) {
+		return fmt.Errorf("invalid export column name %q", col.Name)
+	}
+	for _, name := range templateOnlyColumns {
+		if strings.EqualFold(name, col.Name) {
+			return fmt.Errorf("export column %q is reserved", col.Name)
+		}
+	}
+	for _, existing := range e.insertColumns() {
+		if strings.EqualFold(existing.Name, col.Name) {
+			return fmt.Errorf("export column %q is already defined", col.Name)
+		}
+	}
+	e.extraColumns = append(e.extraColumns, col)
+	return nil
+}
+
+var (
+	validColumnName  = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_]*$`)
+	nonIdentifierRun = regexp.MustCompile(`[^A-Za-z0-9_]+`)
+)
+
+// AddContactColumns adds one VARCHAR column per channel name (as in the
+// ChannelType table) holding the customer's most recent value for that channel.
+// A channel whose name collides with another export column, such as Email, is
+// rejected; add the contact columns last so the error names the channel.
+func (e *Exporter) AddContactColumns(channels []string) error {
+	for _, channel := range channels {
+		channel := channel
+		col := exportColumn{
+			columnDef: columnDef{
+				Name:       contactColumnName(channel),
+				Definition: "varchar(600) NULL",
+			},
+			personal: func(c *models.CustomerRevenue) string {
+				return c.Contacts.Value(channel)
+			},
+		}
+		if err := e.addColumn(col); err != nil {
+			return fmt.Errorf("channel %q: %w", channel, err)
+		}
+	}
+	return nil
+}
+
+// contactColumnName is the export column of a channel name
+func contactColumnName(channel string) string {
+	return nonIdentifierRun.ReplaceAllString(channel, "")
+}
+
+// AddClientIDColumn adds ClientCustomerID, the client-side customer identifier
+func (e *Exporter) AddClientIDColumn() error {
+	return e.addColumn(exportColumn{
+		columnDef: columnDef{Name: "ClientCustomerID", Definition: "bigint NULL"},
+		value: func(c *models.CustomerRevenue) interface{} {
+			if c.ClientCustomerID == 0 {
+				return nil
+			}
+			return c.ClientCustomerID
+		},
+	})
+}
+
+// AddMergedIDsColumn adds MergedCustomerIDs, the comma-separated CustomerIDs
+// merged by identity resolution (NULL for customers that were not merged)
+func (e *Exporter) AddMergedIDsColumn() error {
+	return e.addColumn(exportColumn{
+		columnDef: columnDef{Name: "MergedCustomerIDs", Definition: "text NULL"},
+		value: func(c *models.CustomerRevenue) interface{} {
+			if len(c.MemberIDs) < 2 {
+				return nil
+			}
+			ids := make([]string, len(c.MemberIDs))
+			for i, id := range c.MemberIDs {
+				ids[i] = strconv.FormatInt(id, 10)
+			}
+			return strings.Join(ids, ",")
+		},
+	})
+}
+
+// AddWindowColumns adds one CA_<name> revenue column per analysis window
+func (e *Exporter) AddWindowColumns(names []string) error {
+	for _, name := range names {
+		name := name
+		col := exportColumn{
+			columnDef: columnDef{Name: "CA_" + name, Definition: "decimal(12,2) NULL"},
+			value: func(c *models.CustomerRevenue) interface{} {
+				if c.WindowRevenue == nil {
+					return nil
+				}
+				return c.WindowRevenue[name]
+			},
+		}
+		if err := e.addColumn(col); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AddTrendColumns adds the trend comparison: CurrentPeriodCA, PreviousPeriodCA,
+// GrowthRate, LastPurchaseDate, DaysSinceLastPurchase and TrendStatus
+func (e *Exporter) AddTrendColumns() error {
+	trendValue := func(get func(t *models.CustomerTrend) interface{}) func(c *models.CustomerRevenue) interface{} {
+		return func(c *models.CustomerRevenue) interface{} {
+			if c.Trend == nil {
+				return nil
+			}
+			return get(c.Trend)
+		}
+	}
+
+	columns := []exportColumn{
+		{
+			columnDef: columnDef{Name: "CurrentPeriodCA", Definition: "decimal(12,2) NULL"},
+			value:     trendValue(func(t *models.CustomerTrend) interface{} { return t.CurrentRevenue }),
+		},
+		{
+			columnDef: columnDef{Name: "PreviousPeriodCA", Definition: "decimal(12,2) NULL"},
+			value:     trendValue(func(t *models.CustomerTrend) interface{} { return t.PreviousRevenue }),
+		},
+		{
+			columnDef: columnDef{Name: "GrowthRate", Definition: "decimal(12,4) NULL"},
+			value: trendValue(func(t *models.CustomerTrend) interface{} {
+				if !t.HasGrowthRate {
+					return nil
+				}
+				return t.GrowthRate
+			}),
+		},
+		{
+			columnDef: columnDef{Name: "LastPurchaseDate", Definition: "datetime NULL"},
+			value: trendValue(func(t *models.CustomerTrend) interface{} {
+				if t.LastPurchaseDate.IsZero() {
+					return nil
+				}
+				return t.LastPurchaseDate
+			}),
+		},
+		{
+			columnDef: columnDef{Name: "DaysSinceLastPurchase", Definition: "int NULL"},
+			value: trendValue(func(t *models.CustomerTrend) interface{} {
+				if t.LastPurchaseDate.IsZero() {
+					return nil
+				}
+				return t.DaysSinceLastPurchase
+			}),
+		},
+		{
+			columnDef: columnDef{Name: "TrendStatus", Definition: "varchar(16) NULL"},
+			value:     trendValue(func(t *models.CustomerTrend) interface{} { return t.Status }),
+		},
+	}
+
+	for _, col := range columns {
+		if err := e.addColumn(col); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AddOrderColumns adds the order-level metrics: OrderCount, AvgOrderValue,
+// ItemsPerOrder, FirstOrderDate and LastOrderDate
+func (e *Exporter) AddOrderColumns() error {
+	columns := []exportColumn{
+		{
+			columnDef: columnDef{Name: "OrderCount", Definition: "int NULL"},
+			value: func(c *models.CustomerRevenue) interface{} {
+				if c.Orders == nil {
+					return nil
+				}
+				return c.Orders.OrderCount
+			},
+		},
+		{
+			columnDef: columnDef{Name: "AvgOrderValue", Definition: "decimal(12,2) NULL"},
+			value: func(c *models.CustomerRevenue) interface{} {
+				if c.Orders == nil {
+					return nil
+				}
+				return c.Orders.AvgOrderValue()
+			},
+		},
+		{
+			columnDef: columnDef{Name: "ItemsPerOrder", Definition: "decimal(8,2) NULL"},
+			value: func(c *models.CustomerRevenue) interface{} {
+				if c.Orders == nil {
+					return nil
+				}
+				return c.Orders.ItemsPerOrder()
+			},
+		},
+		{
+			columnDef: columnDef{Name: "FirstOrderDate", Definition: "datetime NULL"},
+			value: func(c *models.CustomerRevenue) interface{} {
+				if c.Orders == nil {
+					return nil
+				}
+				return c.Orders.FirstOrderDate
+			},
+		},
+		{
+			columnDef: columnDef{Name: "LastOrderDate", Definition: "datetime NULL"},
+			value: func(c *models.CustomerRevenue) interface{} {
+				if c.Orders == nil {
+					return nil
+				}
+				return c.Orders.LastOrderDate
+			},
+		},
+	}
+
+	for _, col := range columns {
+		if err := e.addColumn(col); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// nullableString maps an empty string to NULL
+func nullableString(value string) interface{} {
+	if value == "" {
+		return nil
+	}
+	return value
+}
+
+// nullableChannelType maps the zero ChannelTypeID to NULL
+func nullableChannelType(channelTypeID int16) interface{} {
+	if channelTypeID == 0 {
+		return nil
+	}
+	return channelTypeID
+}
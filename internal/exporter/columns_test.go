@@ -0,0 +1,138 @@
+package exporter
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"quanticfy-test/internal/models"
+	"quanticfy-test/internal/privacy"
+	"quanticfy-test/pkg/logger"
+)
+
+// rowValues returns the values the exporter writes for a customer, by column
+func rowValues(e *Exporter, c *models.CustomerRevenue) map[string]interface{} {
+	values := make(map[string]interface{})
+	for _, col := range e.insertColumns() {
+		values[col.Name] = col.value(c)
+	}
+	return values
+}
+
+func TestPseudonymizedColumns(t *testing.T) {
+	pseudonymizer, err := privacy.NewPseudonymizer("0123456789abcdef")
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := NewExporter(nil, logger.Discard())
+	e.PseudonymizeEmails(pseudonymizer, "no-email@unknown.com")
+	if err := e.AddContactColumns([]string{"SMS"}); err != nil {
+		t.Fatal(err)
+	}
+
+	contacts := &models.ContactProfile{CustomerID: 1, Channels: map[string][]string{"SMS": {"+33600000001"}}}
+	values := rowValues(e, &models.CustomerRevenue{
+		CustomerID:      1,
+		Email:           "jane@example.com",
+		FallbackContact: "1 rue de la Paix",
+		Contacts:        contacts,
+	})
+	for column, clear := range map[string]string{
+		"Email":           "jane@example.com",
+		"FallbackContact": "1 rue de la Paix",
+		"SMS":             "+33600000001",
+	} {
+		if got, want := values[column], pseudonymizer.Pseudonymize(clear); got != want {
+			t.Errorf("%s = %v, want the pseudonym %s", column, got, want)
+		}
+	}
+
+	values = rowValues(e, &models.CustomerRevenue{CustomerID: 2, Email: "no-email@unknown.com"})
+	if values["Email"] != "no-email@unknown.com" {
+		t.Errorf("placeholder written as %v, want it as is", values["Email"])
+	}
+	if values["FallbackContact"] != nil || values["SMS"] != nil {
+		t.Errorf("missing contacts written as %v and %v, want NULL", values["FallbackContact"], values["SMS"])
+	}
+}
+
+func TestEncryptedColumns(t *testing.T) {
+	keyring, err := privacy.ParseKeyring("k1:"+base64.StdEncoding.EncodeToString([]byte(strings.Repeat("1", 32))), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	encryptor := privacy.NewEncryptor(keyring, false)
+	e := NewExporter(nil, logger.Discard())
+	if err := e.EncryptEmails(encryptor, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.AddContactColumns([]string{"SMS"}); err != nil {
+		t.Fatal(err)
+	}
+
+	jane := &models.CustomerRevenue{
+		CustomerID:      1,
+		Email:           "jane@example.com",
+		FallbackContact: "1 rue de la Paix",
+		Contacts:        &models.ContactProfile{CustomerID: 1, Channels: map[string][]string{"SMS": {"+33600000001"}}},
+	}
+	nobody := &models.CustomerRevenue{CustomerID: 2}
+	if err := e.encryptValues([]*models.CustomerRevenue{jane, nobody}); err != nil {
+		t.Fatalf("encryptValues: %v", err)
+	}
+
+	values := rowValues(e, jane)
+	for column, clear := range map[string]string{
+		"Email":           "jane@example.com",
+		"FallbackContact": "1 rue de la Paix",
+		"SMS":             "+33600000001",
+	} {
+		encrypted, _ := values[column].(string)
+		if got, err := encryptor.Decrypt(encrypted, "k1", 1, column); err != nil || got != clear {
+			t.Errorf("%s decrypts to %q, %v; want %q", column, got, err, clear)
+		}
+	}
+	if values["EmailKeyID"] != "k1" {
+		t.Errorf("EmailKeyID = %v, want k1", values["EmailKeyID"])
+	}
+
+	values = rowValues(e, nobody)
+	for _, column := range []string{"Email", "FallbackContact", "SMS", "EmailKeyID"} {
+		if values[column] != nil {
+			t.Errorf("%s = %v for a customer without contacts, want NULL", column, values[column])
+		}
+	}
+}
+
+func TestAddContactColumnsRejectsCollisions(t *testing.T) {
+	tests := []struct {
+		channel string
+		wantErr bool
+	}{
+		{"Phone", false},
+		{"Push Token", false},
+		{"Email", true},
+		{"email", true},
+		{"InsertDate", true},
+		{"ClientCustomerID", true},
+		{"---", true},
+	}
+	for _, tt := range tests {
+		e := NewExporter(nil, logger.Discard())
+		if err := e.AddClientIDColumn(); err != nil {
+			t.Fatal(err)
+		}
+		err := e.AddContactColumns([]string{tt.channel})
+		if (err != nil) != tt.wantErr {
+			t.Errorf("AddContactColumns(%q) = %v, want error %v", tt.channel, err, tt.wantErr)
+		}
+		if err != nil && !strings.Contains(err.Error(), tt.channel) {
+			t.Errorf("error %q does not name the channel %q", err, tt.channel)
+		}
+	}
+
+	e := NewExporter(nil, logger.Discard())
+	if err := e.AddContactColumns([]string{"Phone", "PHONE"}); err == nil {
+		t.Error("AddContactColumns accepted the same channel twice")
+	}
+}
@@ -0,0 +1,57 @@
+package exporter
+
+import (
+	"fmt"
+	"time"
+
+	"quanticfy-test/internal/analytics"
+	"quanticfy-test/pkg/logger"
+)
+
+// contentRevenueTemplateTable is created by the migrations and holds the content analytics schema
+const contentRevenueTemplateTable = "test_content_revenue_template"
+
+// ExportContentRevenue writes the content analytics to test_content_revenue_YYYYMMDD
+func (e *Exporter) ExportContentRevenue(report *analytics.ContentReport) error {
+	startTime := time.Now()
+
+	tableName := fmt.Sprintf("test_content_revenue_%s", time.Now().Format("20060102"))
+	e.log.Info("Exporting content revenue analytics", "table", tableName)
+	if err := e.createTableLike(tableName, contentRevenueTemplateTable, nil); err != nil {
+		return fmt.Errorf("error creating content revenue table: %w", err)
+	}
+	if err := e.clearTable(tableName); err != nil {
+		return err
+	}
+
+	if len(report.Contents) == 0 {
+		e.log.Warn("No contents to export", "table", tableName)
+		return nil
+	}
+
+	rows := make([][]interface{}, 0, len(report.Contents))
+	for _, c := range report.Contents {
+		var clientID, lift interface{}
+		if c.ClientContentID != 0 {
+			clientID = c.ClientContentID
+		}
+		if c.OtherShare > 0 {
+			lift = c.Lift
+		}
+		rows = append(rows, []interface{}{
+			c.ContentID, clientID, c.Rank, c.Revenue, c.Units, c.Customers,
+			c.RevenueShare, c.CumulativeShare,
+			c.TopSegmentRevenue, c.TopSegmentUnits, c.TopSegmentShare, c.OtherShare, lift,
+		})
+	}
+
+	columns := []string{"ContentID", "ClientContentID", "RevenueRank", "Revenue", "Units", "Customers",
+		"RevenueShare", "CumulativeShare",
+		"TopSegmentRevenue", "TopSegmentUnits", "TopSegmentShare", "OtherShare", "Lift"}
+	if err := e.batchInsert(tableName, columns, rows, "Exporting contents"); err != nil {
+		return fmt.Errorf("error inserting content revenue: %w", err)
+	}
+
+	e.log.Info("Exported content revenue", "table", tableName, logger.Rows(len(report.Contents)), logger.Duration(time.Since(startTime)))
+	return nil
+}
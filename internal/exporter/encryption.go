@@ -0,0 +1,142 @@
+package exporter
+
+import (
+	"fmt"
+	"strings"
+
+	"quanticfy-test/internal/models"
+	"quanticfy-test/internal/privacy"
+)
+
+// EncryptEmails makes the exports store the AES-GCM encryption of each email
+// and contact (FallbackContact and the contact columns) with the ID of the key
+// used in EmailKeyID. With a blind index, EmailIndex holds the keyed hash of
+// the email so consumers can join without decrypting.
+func (e *Exporter) EncryptEmails(encryptor *privacy.Encryptor, blindIndex *privacy.Pseudonymizer) error {
+	if e.pseudonymizer != nil {
+		return fmt.Errorf("emails cannot be both pseudonymized and encrypted")
+	}
+	e.encryptor = encryptor
+
+	err := e.addColumn(exportColumn{
+		columnDef: columnDef{Name: "EmailKeyID", Definition: "varchar(32) NULL"},
+		value: func(c *models.CustomerRevenue) interface{} {
+			for _, encrypted := range e.encrypted[c.CustomerID] {
+				if encrypted != "" {
+					return encryptor.ActiveKeyID()
+				}
+			}
+			return nil
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	if blindIndex == nil {
+		return nil
+	}
+	return e.addColumn(exportColumn{
+		columnDef: columnDef{Name: "EmailIndex", Definition: "char(64) NULL"},
+		value: func(c *models.CustomerRevenue) interface{} {
+			return nullableString(blindIndex.Pseudonymize(c.Email))
+		},
+	})
+}
+
+// encryptValues encrypts the personal data columns of the customers about to
+// be written
+func (e *Exporter) encryptValues(customers []*models.CustomerRevenue) error {
+	e.encrypted = make(map[int64]map[string]string, len(customers))
+	for _, c := range customers {
+		e.encrypted[c.CustomerID] = make(map[string]string)
+	}
+	for _, col := range e.insertColumns() {
+		if col.personal == nil {
+			continue
+		}
+		for _, c := range customers {
+			encrypted, err := e.encryptor.Encrypt(col.personal(c), c.CustomerID, col.Name)
+			if err != nil {
+				return fmt.Errorf("error encrypting %s of customer %d: %w", col.Name, c.CustomerID, err)
+			}
+			e.encrypted[c.CustomerID][col.Name] = encrypted
+		}
+	}
+	return nil
+}
+
+// EncryptedRow holds the encrypted personal data of one export row, in the
+// order of the columns returned by ReadEncryptedRows
+type EncryptedRow struct {
+	CustomerID int64
+	KeyID      string
+	Values     []string
+}
+
+// ReadEncryptedRows reads the encrypted columns of an export table: Email,
+// FallbackContact and the contact column of every channel in channels the
+// table has. Only one customer is read when customerID is not 0. Rows
+// without an EmailKeyID were not encrypted and are skipped.
+func (e *Exporter) ReadEncryptedRows(tableName string, channels []string, customerID int64) ([]string, []EncryptedRow, error) {
+	if !validColumnName.MatchString(tableName) {
+		return nil, nil, fmt.Errorf("invalid table name %q", tableName)
+	}
+
+	tableColumns, err := e.tableColumns(tableName)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(tableColumns) == 0 {
+		return nil, nil, fmt.Errorf("table %s does not exist", tableName)
+	}
+	contactColumns := make(map[string]bool, len(channels))
+	for _, channel := range channels {
+		contactColumns[strings.ToLower(contactColumnName(channel))] = true
+	}
+	columns := []string{"Email", "FallbackContact"}
+	for _, col := range tableColumns {
+		name := strings.ToLower(col.Name)
+		if contactColumns[name] && name != "email" && name != "fallbackcontact" {
+			columns = append(columns, col.Name)
+		}
+	}
+
+	selected := make([]string, 0, len(columns))
+	for _, name := range columns {
+		selected = append(selected, fmt.Sprintf("COALESCE(%s, '')", name))
+	}
+	query := fmt.Sprintf(`SELECT CustomerID, EmailKeyID, %s FROM %s WHERE EmailKeyID IS NOT NULL`,
+		strings.Join(selected, ", "), tableName)
+	var args []interface{}
+	if customerID != 0 {
+		query += " AND CustomerID = ?"
+		args = append(args, customerID)
+	}
+	query += " ORDER BY CustomerID"
+
+	rows, err := e.db.Query(query, args...)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error reading encrypted rows from %s: %w", tableName, err)
+	}
+	defer rows.Close()
+
+	var encrypted []EncryptedRow
+	for rows.Next() {
+		row := EncryptedRow{Values: make([]string, len(columns))}
+		dest := []interface{}{&row.CustomerID, &row.KeyID}
+		for i := range row.Values {
+			dest = append(dest, &row.Values[i])
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return nil, nil, fmt.Errorf("error scanning encrypted row: %w", err)
+		}
+		encrypted = append(encrypted, row)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, nil, fmt.Errorf("error iterating encrypted rows: %w", err)
+	}
+
+	return columns, encrypted, nil
+}
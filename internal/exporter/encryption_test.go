@@ -0,0 +1,49 @@
+package exporter
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+
+	"quanticfy-test/pkg/logger"
+)
+
+func TestReadEncryptedRows(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	tableColumns := sqlmock.NewRows([]string{"COLUMN_NAME", "COLUMN_TYPE", "IS_NULLABLE"})
+	for _, name := range []string{"CustomerID", "Email", "CA", "FallbackContact", "Phone", "EmailKeyID"} {
+		tableColumns.AddRow(name, "varchar(600)", "YES")
+	}
+	mock.ExpectQuery(`FROM information_schema.COLUMNS`).WithArgs("test_export_20240101").WillReturnRows(tableColumns)
+	mock.ExpectQuery(`SELECT CustomerID, EmailKeyID, COALESCE\(Email, ''\), COALESCE\(FallbackContact, ''\), COALESCE\(Phone, ''\) FROM test_export_20240101 WHERE EmailKeyID IS NOT NULL AND CustomerID = \?`).
+		WithArgs(42).
+		WillReturnRows(sqlmock.NewRows([]string{"CustomerID", "EmailKeyID", "Email", "FallbackContact", "Phone"}).
+			AddRow(42, "k2", "e", "", "p"))
+
+	// Email is a channel too but already a base column; Postal has no column
+	e := NewExporter(db, logger.Discard())
+	columns, rows, err := e.ReadEncryptedRows("test_export_20240101", []string{"Email", "Phone", "Postal"}, 42)
+	if err != nil {
+		t.Fatalf("ReadEncryptedRows: %v", err)
+	}
+	if want := []string{"Email", "FallbackContact", "Phone"}; !reflect.DeepEqual(columns, want) {
+		t.Errorf("columns = %v, want %v", columns, want)
+	}
+	want := []EncryptedRow{{CustomerID: 42, KeyID: "k2", Values: []string{"e", "", "p"}}}
+	if !reflect.DeepEqual(rows, want) {
+		t.Errorf("rows = %+v, want %+v", rows, want)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+
+	if _, _, err := e.ReadEncryptedRows("test_export; DROP TABLE x", nil, 0); err == nil {
+		t.Error("ReadEncryptedRows accepted an invalid table name")
+	}
+}
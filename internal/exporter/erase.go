@@ -0,0 +1,221 @@
+package exporter
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"quanticfy-test/pkg/logger"
+)
+
+// EraseCustomer deletes a customer from every daily output table (test_*
+// tables with a CustomerID column, templates excepted), including rows of
+// merged identities listing it in MergedCustomerIDs. It returns the number of
+// rows deleted per table.
+func (e *Exporter) EraseCustomer(customerID int64) (map[string]int64, error) {
+	e.log.Info("Erasing customer from output tables", "customer_id", customerID)
+
+	rows, err := e.db.Query(`
+		SELECT TABLE_NAME
+		FROM information_schema.COLUMNS
+		WHERE TABLE_SCHEMA = DATABASE() AND COLUMN_NAME = 'CustomerID'
+		  AND TABLE_NAME LIKE 'test\_%' AND TABLE_NAME NOT LIKE '%\_template'
+		ORDER BY TABLE_NAME
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("error listing output tables: %w", err)
+	}
+	var tables []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("error scanning table name: %w", err)
+		}
+		tables = append(tables, name)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating table names: %w", err)
+	}
+
+	deleted := make(map[string]int64, len(tables))
+	for _, table := range tables {
+		columns, err := e.tableColumns(table)
+		if err != nil {
+			return deleted, err
+		}
+
+		query := fmt.Sprintf("DELETE FROM %s WHERE CustomerID = ?", table)
+		args := []interface{}{customerID}
+		for _, col := range columns {
+			if col.Name == "MergedCustomerIDs" {
+				query += " OR FIND_IN_SET(?, MergedCustomerIDs) > 0"
+				args = append(args, strconv.FormatInt(customerID, 10))
+			}
+		}
+
+		result, err := e.db.Exec(query, args...)
+		if err != nil {
+			return deleted, fmt.Errorf("error erasing customer from %s: %w", table, err)
+		}
+		n, err := result.RowsAffected()
+		if err != nil {
+			return deleted, fmt.Errorf("error erasing customer from %s: %w", table, err)
+		}
+		if n > 0 {
+			e.log.Info("Deleted customer rows", "table", table, logger.Rows(int(n)))
+		}
+		deleted[table] = n
+	}
+
+	return deleted, nil
+}
+
+// EraseCustomerFromFiles removes a customer from file outputs: JSON reports
+// (objects with a matching "customer_id" and IDs in "*customer_ids" lists)
+// and CSV files (rows with a matching CustomerID column). Missing files are
+// skipped. It returns the number of entries removed per file.
+func (e *Exporter) EraseCustomerFromFiles(paths []string, customerID int64) (map[string]int, error) {
+	removed := make(map[string]int, len(paths))
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return removed, fmt.Errorf("error reading %s: %w", path, err)
+		}
+
+		var scrubbed []byte
+		var n int
+		switch strings.ToLower(filepath.Ext(path)) {
+		case ".json":
+			scrubbed, n, err = eraseFromJSON(data, customerID)
+		case ".csv":
+			scrubbed, n, err = eraseFromCSV(data, customerID)
+		default:
+			err = fmt.Errorf("unsupported file type")
+		}
+		if err != nil {
+			return removed, fmt.Errorf("error erasing customer from %s: %w", path, err)
+		}
+
+		if n > 0 {
+			if err := os.WriteFile(path, scrubbed, 0o644); err != nil {
+				return removed, fmt.Errorf("error writing %s: %w", path, err)
+			}
+			e.log.Info("Removed customer entries", "path", path, logger.Rows(n))
+		}
+		removed[path] = n
+	}
+	return removed, nil
+}
+
+func eraseFromJSON(data []byte, customerID int64) ([]byte, int, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var doc interface{}
+	if err := dec.Decode(&doc); err != nil {
+		return nil, 0, err
+	}
+
+	id := json.Number(strconv.FormatInt(customerID, 10))
+	removed := 0
+	var scrub func(v interface{}) interface{}
+	matches := func(v interface{}) bool {
+		obj, ok := v.(map[string]interface{})
+		return ok && obj["customer_id"] == id
+	}
+	scrub = func(v interface{}) interface{} {
+		switch v := v.(type) {
+		case map[string]interface{}:
+			for key, value := range v {
+				if list, ok := value.([]interface{}); ok && strings.HasSuffix(key, "customer_ids") {
+					kept := list[:0]
+					for _, item := range list {
+						if item == id {
+							removed++
+							continue
+						}
+						kept = append(kept, item)
+					}
+					v[key] = kept
+					continue
+				}
+				v[key] = scrub(value)
+			}
+			return v
+		case []interface{}:
+			kept := v[:0]
+			for _, item := range v {
+				if matches(item) {
+					removed++
+					continue
+				}
+				kept = append(kept, scrub(item))
+			}
+			return kept
+		default:
+			return v
+		}
+	}
+	doc = scrub(doc)
+
+	out, err := json.MarshalIndent(doc, "", "  ")
+	return out, removed, err
+}
+
+func eraseFromCSV(data []byte, customerID int64) ([]byte, int, error) {
+	records, err := csv.NewReader(bytes.NewReader(data)).ReadAll()
+	if err != nil || len(records) == 0 {
+		return nil, 0, err
+	}
+
+	column := -1
+	for i, name := range records[0] {
+		if name == "CustomerID" {
+			column = i
+		}
+	}
+	if column < 0 {
+		return data, 0, nil
+	}
+
+	id := strconv.FormatInt(customerID, 10)
+	kept := records[:1]
+	for _, record := range records[1:] {
+		if column < len(record) && record[column] == id {
+			continue
+		}
+		kept = append(kept, record)
+	}
+
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	if err := w.WriteAll(kept); err != nil {
+		return nil, 0, err
+	}
+	return buf.Bytes(), len(records) - len(kept), nil
+}
+
+// SuppressCustomer adds a customer_id rule to CustomerExclusion so that later
+// runs keep the erased customer out of the ranking
+func (e *Exporter) SuppressCustomer(customerID int64, reason string) error {
+	_, err := e.db.Exec(`
+		INSERT INTO CustomerExclusion (RuleType, Value, Reason)
+		VALUES ('customer_id', ?, ?)
+		ON DUPLICATE KEY UPDATE Reason = VALUES(Reason)
+	`, strconv.FormatInt(customerID, 10), reason)
+	if err != nil {
+		return fmt.Errorf("error adding exclusion rule: %w", err)
+	}
+	return nil
+}
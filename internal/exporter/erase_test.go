@@ -0,0 +1,151 @@
+package exporter
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"quanticfy-test/pkg/logger"
+)
+
+func TestEraseFromJSON(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       string
+		want        string
+		wantRemoved int
+	}{
+		{
+			name:        "objects with customer_id",
+			input:       `{"outliers": [{"customer_id": 42, "revenue": 1}, {"customer_id": 7, "revenue": 2}]}`,
+			want:        `{"outliers": [{"customer_id": 7, "revenue": 2}]}`,
+			wantRemoved: 1,
+		},
+		{
+			name:        "id lists",
+			input:       `{"missing_email_customer_ids": [1, 42, 3], "orphan_customer_ids": [42], "orphan_content_ids": [42]}`,
+			want:        `{"missing_email_customer_ids": [1, 3], "orphan_customer_ids": [], "orphan_content_ids": [42]}`,
+			wantRemoved: 2,
+		},
+		{
+			name:        "nested",
+			input:       `{"samples": {"invalid": [{"customer_id": 42, "value": "x"}]}, "total": 42}`,
+			want:        `{"samples": {"invalid": []}, "total": 42}`,
+			wantRemoved: 1,
+		},
+		{
+			name:        "large ids keep their precision",
+			input:       `[{"customer_id": 9007199254740993}, {"customer_id": 42}]`,
+			want:        `[{"customer_id": 9007199254740993}]`,
+			wantRemoved: 1,
+		},
+		{
+			name:        "absent",
+			input:       `{"outliers": [{"customer_id": 7}]}`,
+			want:        `{"outliers": [{"customer_id": 7}]}`,
+			wantRemoved: 0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, removed, err := eraseFromJSON([]byte(tt.input), 42)
+			if err != nil {
+				t.Fatalf("eraseFromJSON: %v", err)
+			}
+			if removed != tt.wantRemoved {
+				t.Errorf("removed %d entries, want %d", removed, tt.wantRemoved)
+			}
+			if !jsonEqual(t, out, []byte(tt.want)) {
+				t.Errorf("got %s, want %s", out, tt.want)
+			}
+		})
+	}
+
+	if _, _, err := eraseFromJSON([]byte(`{"truncated": `), 42); err == nil {
+		t.Error("eraseFromJSON accepted invalid JSON")
+	}
+}
+
+func jsonEqual(t *testing.T, a, b []byte) bool {
+	t.Helper()
+	var va, vb interface{}
+	if err := json.Unmarshal(a, &va); err != nil {
+		t.Fatalf("invalid JSON %s: %v", a, err)
+	}
+	if err := json.Unmarshal(b, &vb); err != nil {
+		t.Fatalf("invalid JSON %s: %v", b, err)
+	}
+	return reflect.DeepEqual(va, vb)
+}
+
+func TestEraseFromCSV(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       string
+		want        string
+		wantRemoved int
+	}{
+		{
+			name:        "matching rows",
+			input:       "Period,CustomerID,Revenue\n2024-01,42,10\n2024-01,7,5\n2024-02,42,3\n",
+			want:        "Period,CustomerID,Revenue\n2024-01,7,5\n",
+			wantRemoved: 2,
+		},
+		{
+			name:        "only exact ids",
+			input:       "CustomerID,Email\n420,a@example.com\n4,b@example.com\n",
+			want:        "CustomerID,Email\n420,a@example.com\n4,b@example.com\n",
+			wantRemoved: 0,
+		},
+		{
+			name:        "no CustomerID column",
+			input:       "Period,Revenue\n42,10\n",
+			want:        "Period,Revenue\n42,10\n",
+			wantRemoved: 0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, removed, err := eraseFromCSV([]byte(tt.input), 42)
+			if err != nil {
+				t.Fatalf("eraseFromCSV: %v", err)
+			}
+			if removed != tt.wantRemoved || string(out) != tt.want {
+				t.Errorf("got %d removed and\n%s\nwant %d removed and\n%s", removed, out, tt.wantRemoved, tt.want)
+			}
+		})
+	}
+}
+
+func TestEraseCustomerFromFiles(t *testing.T) {
+	dir := t.TempDir()
+	report := filepath.Join(dir, "outliers.json")
+	series := filepath.Join(dir, "series.csv")
+	if err := os.WriteFile(report, []byte(`{"outliers": [{"customer_id": 42}]}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(series, []byte("CustomerID\n7\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	e := NewExporter(nil, logger.Discard())
+	removed, err := e.EraseCustomerFromFiles([]string{report, series, filepath.Join(dir, "missing.json")}, 42)
+	if err != nil {
+		t.Fatalf("EraseCustomerFromFiles: %v", err)
+	}
+	if want := map[string]int{report: 1, series: 0}; !reflect.DeepEqual(removed, want) {
+		t.Errorf("removed %v, want %v", removed, want)
+	}
+	if data, _ := os.ReadFile(report); strings.Contains(string(data), "42") {
+		t.Errorf("report still mentions the customer: %s", data)
+	}
+
+	unsupported := filepath.Join(dir, "audit.log")
+	os.WriteFile(unsupported, []byte("42"), 0o644)
+	if _, err := e.EraseCustomerFromFiles([]string{unsupported}, 42); err == nil {
+		t.Error("EraseCustomerFromFiles accepted an unsupported file type")
+	}
+}
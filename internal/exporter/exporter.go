@@ -1,23 +1,63 @@
 package exporter
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
-	"log"
 	"strings"
 	"time"
 
 	"quanticfy-test/internal/models"
+	"quanticfy-test/internal/privacy"
+	"quanticfy-test/pkg/logger"
+	"quanticfy-test/pkg/progress"
+	"quanticfy-test/pkg/tracing"
 
-	"github.com/schollz/progressbar/v3"
+	"go.opentelemetry.io/otel/attribute"
 )
 
+// exportTemplateTable is created by the migrations and holds the export schema
+const exportTemplateTable = "test_export_template"
+
 type Exporter struct {
-	db *sql.DB
+	db  *sql.DB
+	log *logger.Logger
+	// extraColumns are optional columns added on top of the template schema
+	extraColumns []exportColumn
+	// pseudonymizer replaces the Email and contact columns with their keyed
+	// hash, when set; placeholder is the email left as is
+	pseudonymizer *privacy.Pseudonymizer
+	placeholder   string
+	// encryptor replaces the Email and contact columns with their AES-GCM
+	// encryption, when set; encrypted holds the values of the export in
+	// progress by CustomerID and column
+	encryptor *privacy.Encryptor
+	encrypted map[int64]map[string]string
+	progress  progress.Reporter
+	// written counts the rows inserted or updated per table
+	written map[string]int
+	// ctx carries the span the batch spans are children of
+	ctx context.Context
+}
+
+func NewExporter(db *sql.DB, log *logger.Logger) *Exporter {
+	return &Exporter{
+		db:       db,
+		log:      log,
+		progress: progress.Noop(),
+		written:  make(map[string]int),
+		ctx:      context.Background(),
+	}
+}
+
+// SetTraceContext makes the batch insert spans children of the span in ctx
+func (e *Exporter) SetTraceContext(ctx context.Context) {
+	e.ctx = ctx
 }
 
-func NewExporter(db *sql.DB) *Exporter {
-	return &Exporter{db: db}
+// SetProgress reports the progress of batch inserts through reporter
+func (e *Exporter) SetProgress(reporter progress.Reporter) {
+	e.progress = reporter
 }
 
 // ExportTopCustomers exports top customers to a date-specific table
@@ -26,25 +66,48 @@ func (e *Exporter) ExportTopCustomers(
 	topCustomers map[int64]*models.CustomerRevenue,
 ) error {
 
-	log.Println("[INFO] Exporting top customers to database...")
-	startTime := time.Now()
-
 	// Generate table name with current date: test_export_YYYYMMDD
 	tableName := fmt.Sprintf("test_export_%s", time.Now().Format("20060102"))
+	e.log.Info("Exporting top customers", "table", tableName)
+
+	return e.exportToTable(tableName, topCustomers)
+}
+
+// ExportUnreachableCustomers exports top customers with no usable contact
+// to test_unreachable_YYYYMMDD, which shares the export table schema
+func (e *Exporter) ExportUnreachableCustomers(
+	customers map[int64]*models.CustomerRevenue,
+) error {
+
+	tableName := fmt.Sprintf("test_unreachable_%s", time.Now().Format("20060102"))
+	e.log.Info("Exporting unreachable top customers", "table", tableName)
+
+	return e.exportToTable(tableName, customers)
+}
+
+func (e *Exporter) exportToTable(
+	tableName string,
+	customersByID map[int64]*models.CustomerRevenue,
+) error {
+
+	startTime := time.Now()
 
 	// Create or verify table exists
 	if err := e.createExportTable(tableName); err != nil {
 		return fmt.Errorf("error creating export table: %w", err)
 	}
+	if err := e.clearTable(tableName); err != nil {
+		return err
+	}
 
 	// Convert map to slice for processing
-	customers := make([]*models.CustomerRevenue, 0, len(topCustomers))
-	for _, rev := range topCustomers {
+	customers := make([]*models.CustomerRevenue, 0, len(customersByID))
+	for _, rev := range customersByID {
 		customers = append(customers, rev)
 	}
 
 	if len(customers) == 0 {
-		log.Println("[WARNING] No customers to export")
+		e.log.Warn("No customers to export", "table", tableName)
 		return nil
 	}
 
@@ -53,34 +116,123 @@ func (e *Exporter) ExportTopCustomers(
 		return fmt.Errorf("error inserting customers: %w", err)
 	}
 
-	log.Printf("[INFO] Successfully exported %d customers to table '%s' in %v",
-		len(customers), tableName, time.Since(startTime))
+	e.log.Info("Exported customers", "table", tableName, logger.Rows(len(customers)), logger.Duration(time.Since(startTime)))
 
 	return nil
 }
 
-// createExportTable creates the export table if it doesn't exist
+// createExportTable creates the export table if it doesn't exist.
+// The schema comes from test_export_template (see internal/migrations).
 func (e *Exporter) createExportTable(tableName string) error {
-	log.Printf("[INFO] Creating/verifying table '%s'...", tableName)
-
-	createTableSQL := fmt.Sprintf(`
-		CREATE TABLE IF NOT EXISTS %s (
-			CustomerID BIGINT UNSIGNED NOT NULL,
-			Email VARCHAR(600) NOT NULL,
-			CA DECIMAL(12,2) NOT NULL,
-			InsertDate TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-			UpdateDate TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
-			PRIMARY KEY (CustomerID),
-			INDEX idx_ca (CA DESC)
-		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4
-	`, tableName)
+	extra := make([]columnDef, 0, len(e.extraColumns))
+	for _, col := range e.extraColumns {
+		extra = append(extra, col.columnDef)
+	}
+	return e.createTableLike(tableName, exportTemplateTable, extra)
+}
+
+// createTableLike creates tableName from a migrated template table, then adds
+// the template columns an older table may lack and any extra columns
+func (e *Exporter) createTableLike(tableName, templateTable string, extra []columnDef) error {
+	e.log.Debug("Creating or verifying table", "table", tableName, "template", templateTable)
+
+	createTableSQL := fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s LIKE %s`, tableName, templateTable)
 
 	_, err := e.db.Exec(createTableSQL)
 	if err != nil {
-		return fmt.Errorf("error creating table: %w", err)
+		return fmt.Errorf("error creating table from %s (run the migrate command first): %w", templateTable, err)
+	}
+
+	// A table created earlier the same day may predate the latest migrations
+	templateColumns, err := e.tableColumns(templateTable)
+	if err != nil {
+		return err
+	}
+	if err := e.ensureColumns(tableName, append(templateColumns, extra...)); err != nil {
+		return err
+	}
+
+	e.log.Debug("Table ready", "table", tableName)
+	return nil
+}
+
+// clearTable deletes the rows left in a daily table by an earlier run of the
+// same day, so that the table only holds the rows of the latest run
+func (e *Exporter) clearTable(tableName string) error {
+	result, err := e.db.Exec(fmt.Sprintf("DELETE FROM %s", tableName))
+	if err != nil {
+		return fmt.Errorf("error clearing table %s: %w", tableName, err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n > 0 {
+		e.log.Info("Removed rows of an earlier run", "table", tableName, logger.Rows(int(n)))
+	}
+	return nil
+}
+
+// tableColumns reads the column definitions of a table from information_schema
+func (e *Exporter) tableColumns(tableName string) ([]columnDef, error) {
+	rows, err := e.db.Query(`
+		SELECT COLUMN_NAME, COLUMN_TYPE, IS_NULLABLE
+		FROM information_schema.COLUMNS
+		WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ?
+		ORDER BY ORDINAL_POSITION
+	`, tableName)
+	if err != nil {
+		return nil, fmt.Errorf("error reading columns of %s: %w", tableName, err)
+	}
+	defer rows.Close()
+
+	var columns []columnDef
+	for rows.Next() {
+		var name, columnType, nullable string
+		if err := rows.Scan(&name, &columnType, &nullable); err != nil {
+			return nil, fmt.Errorf("error scanning column row: %w", err)
+		}
+		definition := columnType + " NULL"
+		if nullable == "NO" {
+			definition = columnType + " NOT NULL"
+		}
+		columns = append(columns, columnDef{Name: name, Definition: definition})
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating column rows: %w", err)
+	}
+
+	return columns, nil
+}
+
+// ensureColumns adds the columns missing from tableName and aligns the
+// nullability of existing ones with the given definitions
+func (e *Exporter) ensureColumns(tableName string, columns []columnDef) error {
+	existing, err := e.tableColumns(tableName)
+	if err != nil {
+		return err
+	}
+
+	current := make(map[string]string, len(existing))
+	for _, col := range existing {
+		current[strings.ToLower(col.Name)] = col.Definition
+	}
+
+	for _, col := range columns {
+		definition, exists := current[strings.ToLower(col.Name)]
+		var alterSQL string
+		switch {
+		case !exists:
+			alterSQL = fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", tableName, col.Name, col.Definition)
+		case !strings.EqualFold(definition, col.Definition):
+			alterSQL = fmt.Sprintf("ALTER TABLE %s MODIFY COLUMN %s %s", tableName, col.Name, col.Definition)
+		default:
+			continue
+		}
+
+		e.log.Info("Updating table column", "table", tableName, "column", col.Name, "definition", col.Definition)
+		if _, err := e.db.Exec(alterSQL); err != nil {
+			return fmt.Errorf("error updating column %s of %s: %w", col.Name, tableName, err)
+		}
 	}
 
-	log.Printf("[INFO] Table '%s' ready", tableName)
 	return nil
 }
 
@@ -90,54 +242,111 @@ func (e *Exporter) massInsertCustomers(
 	customers []*models.CustomerRevenue,
 ) error {
 
+	if e.encryptor != nil {
+		if err := e.encryptValues(customers); err != nil {
+			return err
+		}
+	}
+
+	columns := e.insertColumns()
+	names := make([]string, 0, len(columns))
+	for _, col := range columns {
+		names = append(names, col.Name)
+	}
+
+	rows := make([][]interface{}, 0, len(customers))
+	for _, customer := range customers {
+		row := make([]interface{}, 0, len(columns))
+		for _, col := range columns {
+			row = append(row, col.value(customer))
+		}
+		rows = append(rows, row)
+	}
+
+	e.log.Info("Inserting customers", "table", tableName, logger.Rows(len(customers)))
+	return e.batchInsert(tableName, names, rows, "Exporting")
+}
+
+// batchInsert writes rows in batches of INSERT ... ON DUPLICATE KEY UPDATE.
+// The first column is the key and is not updated on duplicates.
+func (e *Exporter) batchInsert(
+	tableName string,
+	columns []string,
+	rows [][]interface{},
+	description string,
+) error {
+
 	batchSize := 1000
-	totalBatches := (len(customers) + batchSize - 1) / batchSize
+	totalBatches := (len(rows) + batchSize - 1) / batchSize
+
+	placeholders := "(" + strings.TrimSuffix(strings.Repeat("?, ", len(columns)), ", ") + ")"
+	updates := make([]string, 0, len(columns)-1)
+	for _, name := range columns[1:] {
+		updates = append(updates, fmt.Sprintf("%s = VALUES(%s)", name, name))
+	}
 
-	log.Printf("[INFO] Inserting %d customers in %d batches...", len(customers), totalBatches)
-	bar := progressbar.Default(int64(len(customers)), "Exporting")
+	e.log.Info("Writing rows", "table", tableName, logger.Rows(len(rows)), "batches", totalBatches)
+	task := e.progress.Start(description, len(rows))
+	defer task.Finish()
+	ctx, span := tracing.Start(e.ctx, "batchInsert", tracing.Table(tableName), tracing.Rows(len(rows)),
+		attribute.Int("batches", totalBatches))
+	defer span.End()
 
-	for i := 0; i < len(customers); i += batchSize {
+	for i := 0; i < len(rows); i += batchSize {
 		end := i + batchSize
-		if end > len(customers) {
-			end = len(customers)
+		if end > len(rows) {
+			end = len(rows)
 		}
 
-		batch := customers[i:end]
+		batch := rows[i:end]
 
 		// Build VALUES clause
 		valueStrings := make([]string, 0, len(batch))
-		valueArgs := make([]interface{}, 0, len(batch)*3)
+		valueArgs := make([]interface{}, 0, len(batch)*len(columns))
 
-		for _, customer := range batch {
-			valueStrings = append(valueStrings, "(?, ?, ?)")
-			valueArgs = append(valueArgs, customer.CustomerID, customer.Email, customer.Revenue)
+		for _, row := range batch {
+			valueStrings = append(valueStrings, placeholders)
+			valueArgs = append(valueArgs, row...)
 		}
 
 		// Build INSERT statement with ON DUPLICATE KEY UPDATE
 		query := fmt.Sprintf(`
-			INSERT INTO %s (CustomerID, Email, CA)
+			INSERT INTO %s (%s)
 			VALUES %s
 			ON DUPLICATE KEY UPDATE
-				CA = VALUES(CA),
-				Email = VALUES(Email)
-		`, tableName, strings.Join(valueStrings, ","))
+				%s
+		`, tableName, strings.Join(columns, ", "), strings.Join(valueStrings, ","), strings.Join(updates, ", "))
 
 		// Execute batch insert
+		_, batchSpan := tracing.Start(ctx, "INSERT "+tableName, tracing.Table(tableName),
+			tracing.Rows(len(batch)), attribute.Int("batch", i/batchSize+1))
 		_, err := e.db.Exec(query, valueArgs...)
 		if err != nil {
+			tracing.Fail(batchSpan, err)
+			batchSpan.End()
 			return fmt.Errorf("error executing batch insert: %w", err)
 		}
+		batchSpan.End()
 
-		bar.Add(len(batch))
+		e.written[tableName] += len(batch)
+		task.Add(len(batch))
 	}
 
-	fmt.Println()
+	task.Finish()
 	return nil
 }
 
+// RowsWritten returns the number of rows written so far to each table
+func (e *Exporter) RowsWritten() map[string]int {
+	written := make(map[string]int, len(e.written))
+	for table, n := range e.written {
+		written[table] = n
+	}
+	return written
+}
+
 // GetExportStats returns statistics about the exported data
 func (e *Exporter) GetExportStats(tableName string) error {
-	log.Printf("[INFO] Export statistics for table '%s':", tableName)
 
 	var count int
 	var totalRevenue float64
@@ -160,11 +369,8 @@ func (e *Exporter) GetExportStats(tableName string) error {
 		return fmt.Errorf("error getting export stats: %w", err)
 	}
 
-	log.Printf("  Total Customers: %d", count)
-	log.Printf("  Total Revenue: %.2f", totalRevenue)
-	log.Printf("  Average Revenue: %.2f", avgRevenue)
-	log.Printf("  Max Revenue: %.2f", maxRevenue)
-	log.Printf("  Min Revenue: %.2f", minRevenue)
+	e.log.Info("Export statistics", "table", tableName, "customers", count, "total_revenue", totalRevenue,
+		"avg_revenue", avgRevenue, "max_revenue", maxRevenue, "min_revenue", minRevenue)
 
 	return nil
-}
\ No newline at end of file
+}
@@ -0,0 +1,46 @@
+package exporter
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"quanticfy-test/internal/manifest"
+)
+
+// runHistoryTable has one row per run; it is created by the migrations
+const runHistoryTable = "run_history"
+
+// ExportRunHistory records the manifest of a finished run in run_history
+func (e *Exporter) ExportRunHistory(m *manifest.Manifest) error {
+	data, err := json.Marshal(m)
+	if err != nil {
+		return fmt.Errorf("error encoding run manifest: %w", err)
+	}
+
+	var failedPhase, runError, watermark, threshold interface{}
+	if m.FailedPhase != "" {
+		failedPhase = m.FailedPhase
+	}
+	if m.Error != "" {
+		runError = m.Error
+	}
+	if m.Watermark != nil {
+		watermark = *m.Watermark
+	}
+	if m.RevenueThreshold != nil {
+		threshold = *m.RevenueThreshold
+	}
+
+	_, err = e.db.Exec(`
+		INSERT INTO `+runHistoryTable+` (RunID, Version, Status, StartedAt, FinishedAt, FailedPhase,
+			Error, Watermark, RevenueThreshold, TopCustomers, Manifest)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, m.RunID, m.Version, m.Status, m.StartedAt, m.FinishedAt, failedPhase,
+		runError, watermark, threshold, m.TopCustomers, string(data))
+	if err != nil {
+		return fmt.Errorf("error inserting run history: %w", err)
+	}
+
+	e.log.Info("Recorded run history", "table", runHistoryTable, "status", m.Status)
+	return nil
+}
@@ -0,0 +1,46 @@
+package exporter
+
+import (
+	"fmt"
+	"time"
+
+	"quanticfy-test/internal/models"
+	"quanticfy-test/pkg/logger"
+)
+
+// ordersTemplateTable is created by the migrations and holds the orders schema
+const ordersTemplateTable = "test_orders_template"
+
+// ExportOrders writes one row per order to test_orders_YYYYMMDD
+func (e *Exporter) ExportOrders(orders []models.OrderRevenue) error {
+	startTime := time.Now()
+
+	tableName := fmt.Sprintf("test_orders_%s", time.Now().Format("20060102"))
+	e.log.Info("Exporting order-level revenue", "table", tableName)
+	if err := e.createTableLike(tableName, ordersTemplateTable, nil); err != nil {
+		return fmt.Errorf("error creating orders table: %w", err)
+	}
+	if err := e.clearTable(tableName); err != nil {
+		return err
+	}
+
+	if len(orders) == 0 {
+		e.log.Warn("No orders to export", "table", tableName)
+		return nil
+	}
+
+	rows := make([][]interface{}, 0, len(orders))
+	for _, order := range orders {
+		rows = append(rows, []interface{}{
+			order.EventID, order.CustomerID, order.OrderDate, order.Lines, order.Items, order.Revenue,
+		})
+	}
+
+	columns := []string{"EventID", "CustomerID", "OrderDate", "LineCount", "ItemCount", "Revenue"}
+	if err := e.batchInsert(tableName, columns, rows, "Exporting orders"); err != nil {
+		return fmt.Errorf("error inserting orders: %w", err)
+	}
+
+	e.log.Info("Exported orders", "table", tableName, logger.Rows(len(orders)), logger.Duration(time.Since(startTime)))
+	return nil
+}
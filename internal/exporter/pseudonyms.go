@@ -0,0 +1,47 @@
+package exporter
+
+import (
+	"fmt"
+	"time"
+
+	"quanticfy-test/internal/models"
+	"quanticfy-test/pkg/logger"
+)
+
+// emailPseudonymsTable maps email pseudonyms back to addresses; it is created
+// by the migrations and should only be readable by accounts allowed to see emails
+const emailPseudonymsTable = "test_email_pseudonyms"
+
+// ExportEmailPseudonyms records the pseudonym of every exported email in
+// test_email_pseudonyms, one row per customer since customers that were not
+// merged can share an email. It requires PseudonymizeEmails.
+func (e *Exporter) ExportEmailPseudonyms(customers map[int64]*models.CustomerRevenue) error {
+	if e.pseudonymizer == nil {
+		return fmt.Errorf("email pseudonymization is not enabled")
+	}
+
+	e.log.Info("Exporting email pseudonym mapping", "table", emailPseudonymsTable)
+	startTime := time.Now()
+
+	rows := make([][]interface{}, 0, len(customers))
+	for _, c := range customers {
+		if c.Email == "" || c.Email == e.placeholder {
+			continue
+		}
+		rows = append(rows, []interface{}{e.pseudonymizer.Pseudonymize(c.Email), c.Email, c.CustomerID})
+	}
+
+	if len(rows) == 0 {
+		e.log.Warn("No emails to map", "table", emailPseudonymsTable)
+		return nil
+	}
+
+	columns := []string{"EmailHash", "Email", "CustomerID"}
+	if err := e.batchInsert(emailPseudonymsTable, columns, rows, "Exporting pseudonyms"); err != nil {
+		return fmt.Errorf("error inserting email pseudonyms: %w", err)
+	}
+
+	e.log.Info("Exported email pseudonyms", "table", emailPseudonymsTable, logger.Rows(len(rows)),
+		logger.Duration(time.Since(startTime)))
+	return nil
+}
@@ -0,0 +1,47 @@
+package exporter
+
+import (
+	"fmt"
+	"time"
+
+	"quanticfy-test/internal/analytics"
+	"quanticfy-test/pkg/logger"
+)
+
+// revenueTimeSeriesTemplateTable is created by the migrations and holds the time series schema
+const revenueTimeSeriesTemplateTable = "test_revenue_timeseries_template"
+
+// ExportRevenueTimeSeries writes the revenue time series to test_revenue_timeseries_YYYYMMDD
+func (e *Exporter) ExportRevenueTimeSeries(report *analytics.TimeSeriesReport) error {
+	startTime := time.Now()
+
+	tableName := fmt.Sprintf("test_revenue_timeseries_%s", time.Now().Format("20060102"))
+	e.log.Info("Exporting revenue time series", "table", tableName)
+	if err := e.createTableLike(tableName, revenueTimeSeriesTemplateTable, nil); err != nil {
+		return fmt.Errorf("error creating revenue time series table: %w", err)
+	}
+	if err := e.clearTable(tableName); err != nil {
+		return err
+	}
+
+	if len(report.Buckets) == 0 {
+		e.log.Warn("No time series periods to export", "table", tableName)
+		return nil
+	}
+
+	rows := make([][]interface{}, 0, len(report.Buckets))
+	for _, b := range report.Buckets {
+		rows = append(rows, []interface{}{
+			report.Granularity, b.Segment, b.PeriodStart.Format("2006-01-02"),
+			b.Revenue, b.Orders, b.Units, b.ActiveCustomers,
+		})
+	}
+
+	columns := []string{"Granularity", "Segment", "PeriodStart", "Revenue", "Orders", "Units", "ActiveCustomers"}
+	if err := e.batchInsert(tableName, columns, rows, "Exporting periods"); err != nil {
+		return fmt.Errorf("error inserting revenue time series: %w", err)
+	}
+
+	e.log.Info("Exported revenue time series", "table", tableName, logger.Rows(len(report.Buckets)), logger.Duration(time.Since(startTime)))
+	return nil
+}
@@ -0,0 +1,81 @@
+package generator
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"quanticfy-test/pkg/logger"
+)
+
+// fixtureTimeFormat matches MySQL's DATETIME literal so fixtures can be
+// loaded with LOAD DATA INFILE
+const fixtureTimeFormat = "2006-01-02 15:04:05"
+
+// WriteFixtures writes one CSV file per source table into dir
+func WriteFixtures(dir string, ds *Dataset, log *logger.Logger) error {
+	log.Info("Writing fixture files", "dir", dir)
+	startTime := time.Now()
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("error creating fixture directory: %w", err)
+	}
+
+	for _, table := range datasetTables(ds) {
+		if err := writeCSV(filepath.Join(dir, table.name+".csv"), table); err != nil {
+			return fmt.Errorf("error writing %s fixture: %w", table.name, err)
+		}
+		log.Info("Wrote fixture file", "file", table.name+".csv", logger.Rows(len(table.rows)))
+	}
+
+	log.Info("Fixture files written", logger.Duration(time.Since(startTime)))
+	return nil
+}
+
+func writeCSV(path string, table tableRows) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	w := csv.NewWriter(f)
+	if err := w.Write(table.columns); err != nil {
+		return err
+	}
+
+	record := make([]string, len(table.columns))
+	for _, row := range table.rows {
+		for i, value := range row {
+			record[i] = formatValue(value)
+		}
+		if err := w.Write(record); err != nil {
+			return err
+		}
+	}
+
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+func formatValue(value interface{}) string {
+	switch v := value.(type) {
+	case time.Time:
+		return v.UTC().Format(fixtureTimeFormat)
+	case bool:
+		if v {
+			return "1"
+		}
+		return "0"
+	case float64:
+		return strconv.FormatFloat(v, 'f', 2, 64)
+	default:
+		return fmt.Sprint(v)
+	}
+}
@@ -0,0 +1,331 @@
+package generator
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"strings"
+	"time"
+
+	"quanticfy-test/internal/models"
+	"quanticfy-test/pkg/logger"
+)
+
+const (
+	channelTypeEmail  = 1
+	channelTypePhone  = 2
+	eventTypePurchase = 6
+)
+
+// Config controls the size and shape of the generated dataset
+type Config struct {
+	Seed             int64
+	Customers        int
+	Contents         int
+	Orders           int
+	MaxLinesPerOrder int
+	From             time.Time
+	To               time.Time
+	MissingEmailRate float64
+	MissingPriceRate float64
+	PhoneRate        float64
+	// MultiEmailRate is the share of customers with a second email row
+	MultiEmailRate float64
+	// MessyEmailRate is the share of email rows with stray spaces, an
+	// uppercase domain or a broken syntax
+	MessyEmailRate float64
+	// DuplicateCustomerRate is the share of customers that reuse the email
+	// of an earlier customer, as when one person has several accounts
+	DuplicateCustomerRate float64
+	// ParetoAlpha shapes customer spend; 1.16 gives roughly an 80/20 split
+	ParetoAlpha float64
+}
+
+// DefaultConfig returns a small dataset suitable for local development
+func DefaultConfig() Config {
+	return Config{
+		Seed:                  42,
+		Customers:             10000,
+		Contents:              500,
+		Orders:                50000,
+		MaxLinesPerOrder:      5,
+		From:                  time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC),
+		To:                    time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC),
+		MissingEmailRate:      0.02,
+		MissingPriceRate:      0.01,
+		PhoneRate:             0.6,
+		MultiEmailRate:        0.1,
+		MessyEmailRate:        0.02,
+		DuplicateCustomerRate: 0.03,
+		ParetoAlpha:           1.16,
+	}
+}
+
+// Dataset holds one row slice per source table
+type Dataset struct {
+	Customers         []models.Customer
+	CustomerData      []models.CustomerData
+	Contents          []models.Content
+	ContentPrices     []models.ContentPrice
+	CustomerEvents    []models.CustomerEvent
+	CustomerEventData []models.CustomerEventData
+}
+
+func (c Config) validate() error {
+	if c.Customers <= 0 || c.Contents <= 0 || c.Orders < 0 {
+		return fmt.Errorf("customers and contents must be positive and orders non-negative")
+	}
+	if c.MaxLinesPerOrder <= 0 {
+		return fmt.Errorf("max lines per order must be positive")
+	}
+	if !c.To.After(c.From) {
+		return fmt.Errorf("date range end %s must be after start %s",
+			c.To.Format("2006-01-02"), c.From.Format("2006-01-02"))
+	}
+	for name, rate := range map[string]float64{
+		"missing email rate":      c.MissingEmailRate,
+		"missing price rate":      c.MissingPriceRate,
+		"phone rate":              c.PhoneRate,
+		"multi email rate":        c.MultiEmailRate,
+		"messy email rate":        c.MessyEmailRate,
+		"duplicate customer rate": c.DuplicateCustomerRate,
+	} {
+		if rate < 0 || rate > 1 {
+			return fmt.Errorf("%s must be between 0 and 1, got %.3f", name, rate)
+		}
+	}
+	if c.ParetoAlpha <= 0 {
+		return fmt.Errorf("pareto alpha must be positive")
+	}
+	return nil
+}
+
+// Generate builds a synthetic dataset. The same Config always yields the same dataset.
+func Generate(cfg Config, log *logger.Logger) (*Dataset, error) {
+	if err := cfg.validate(); err != nil {
+		return nil, fmt.Errorf("invalid generator config: %w", err)
+	}
+
+	log.Info("Generating synthetic data", "seed", cfg.Seed, "customers", cfg.Customers,
+		"contents", cfg.Contents, "orders", cfg.Orders)
+	startTime := time.Now()
+
+	rng := rand.New(rand.NewSource(cfg.Seed))
+	ds := &Dataset{}
+
+	generateCustomers(rng, cfg, ds)
+	generateContents(rng, cfg, ds)
+	generateOrders(rng, cfg, ds)
+
+	log.Info("Generated synthetic data", "customers", len(ds.Customers), "channel_rows", len(ds.CustomerData),
+		"contents", len(ds.Contents), "prices", len(ds.ContentPrices), "orders", len(ds.CustomerEvents),
+		"order_lines", len(ds.CustomerEventData), logger.Duration(time.Since(startTime)))
+
+	return ds, nil
+}
+
+var (
+	firstNames = []string{"camille", "lea", "manon", "chloe", "emma", "ines", "jade", "louise",
+		"lucas", "hugo", "louis", "nathan", "gabriel", "jules", "arthur", "adam", "paul", "sarah"}
+	lastNames = []string{"martin", "bernard", "thomas", "petit", "robert", "richard", "durand",
+		"dubois", "moreau", "laurent", "simon", "michel", "lefebvre", "leroy", "roux", "david"}
+	emailDomains = []string{"gmail.com", "yahoo.fr", "hotmail.fr", "orange.fr", "free.fr",
+		"outlook.com", "laposte.net", "sfr.fr"}
+)
+
+func generateCustomers(rng *rand.Rand, cfg Config, ds *Dataset) {
+	ds.Customers = make([]models.Customer, 0, cfg.Customers)
+	ds.CustomerData = make([]models.CustomerData, 0, cfg.Customers*2)
+
+	var channelID int64
+	var firstEmails []string
+	for i := 1; i <= cfg.Customers; i++ {
+		customerID := int64(i)
+		insertDate := randomTime(rng, cfg.From.AddDate(-1, 0, 0), cfg.From)
+
+		ds.Customers = append(ds.Customers, models.Customer{
+			CustomerID:       customerID,
+			ClientCustomerID: 1000000 + customerID*7 + int64(rng.Intn(7)),
+			InsertDate:       insertDate,
+		})
+
+		if rng.Float64() >= cfg.MissingEmailRate {
+			first := firstNames[rng.Intn(len(firstNames))]
+			last := lastNames[rng.Intn(len(lastNames))]
+			emails := []string{fmt.Sprintf("%s.%s%d@%s", first, last, customerID,
+				emailDomains[rng.Intn(len(emailDomains))])}
+			if len(firstEmails) > 0 && rng.Float64() < cfg.DuplicateCustomerRate {
+				emails[0] = firstEmails[rng.Intn(len(firstEmails))]
+			} else {
+				firstEmails = append(firstEmails, emails[0])
+			}
+			if rng.Float64() < cfg.MultiEmailRate {
+				emails = append(emails, fmt.Sprintf("%s%d@%s", first, customerID,
+					emailDomains[rng.Intn(len(emailDomains))]))
+			}
+
+			primary := rng.Intn(len(emails))
+			for n, email := range emails {
+				if rng.Float64() < cfg.MessyEmailRate {
+					email = messyEmail(rng, email)
+				}
+				channelID++
+				ds.CustomerData = append(ds.CustomerData, models.CustomerData{
+					CustomerChannelID: channelID,
+					CustomerID:        customerID,
+					ChannelTypeID:     channelTypeEmail,
+					ChannelValue:      email,
+					IsPrimary:         n == primary,
+					InsertDate:        insertDate.Add(time.Duration(n*rng.Intn(90*24)) * time.Hour),
+				})
+			}
+		}
+
+		if rng.Float64() < cfg.PhoneRate {
+			channelID++
+			ds.CustomerData = append(ds.CustomerData, models.CustomerData{
+				CustomerChannelID: channelID,
+				CustomerID:        customerID,
+				ChannelTypeID:     channelTypePhone,
+				ChannelValue:      fmt.Sprintf("+336%08d", rng.Intn(100000000)),
+				InsertDate:        insertDate,
+			})
+		}
+	}
+}
+
+// messyEmail degrades an address the way hand-typed data does
+func messyEmail(rng *rand.Rand, email string) string {
+	local, domain, _ := strings.Cut(email, "@")
+	switch rng.Intn(3) {
+	case 0:
+		return "  " + email + " "
+	case 1:
+		return local + "@" + strings.ToUpper(domain)
+	default:
+		return local + "@@" + domain
+	}
+}
+
+func generateContents(rng *rand.Rand, cfg Config, ds *Dataset) {
+	ds.Contents = make([]models.Content, 0, cfg.Contents)
+	ds.ContentPrices = make([]models.ContentPrice, 0, cfg.Contents)
+
+	var priceID int32
+	for i := 1; i <= cfg.Contents; i++ {
+		contentID := int32(i)
+		insertDate := randomTime(rng, cfg.From.AddDate(-1, 0, 0), cfg.From)
+
+		ds.Contents = append(ds.Contents, models.Content{
+			ContentID:       contentID,
+			ClientContentID: 500000 + int64(contentID)*3,
+			InsertDate:      insertDate,
+		})
+
+		if rng.Float64() < cfg.MissingPriceRate {
+			continue
+		}
+
+		// Log-normal prices centred around 30 EUR, rounded to the cent
+		price := math.Exp(math.Log(30) + rng.NormFloat64()*0.8)
+		price = math.Max(0.99, math.Round(price*100)/100)
+
+		priceID++
+		ds.ContentPrices = append(ds.ContentPrices, models.ContentPrice{
+			ContentPriceID: priceID,
+			ContentID:      contentID,
+			Price:          price,
+			Currency:       "EUR",
+			InsertDate:     insertDate,
+		})
+	}
+}
+
+func generateOrders(rng *rand.Rand, cfg Config, ds *Dataset) {
+	customerPicker := newWeightedPicker(paretoWeights(rng, cfg.Customers, cfg.ParetoAlpha))
+	// Product popularity is skewed too, but less than customer spend
+	contentPicker := newWeightedPicker(paretoWeights(rng, cfg.Contents, 2.0))
+
+	ds.CustomerEvents = make([]models.CustomerEvent, 0, cfg.Orders)
+	ds.CustomerEventData = make([]models.CustomerEventData, 0, cfg.Orders*2)
+
+	var eventDataID int64
+	for i := 1; i <= cfg.Orders; i++ {
+		eventID := int64(i)
+		customerID := int64(customerPicker.pick(rng) + 1)
+		eventDate := randomTime(rng, cfg.From, cfg.To).Truncate(time.Second)
+		insertDate := eventDate.Add(time.Duration(rng.Intn(3600)) * time.Second)
+
+		ds.CustomerEvents = append(ds.CustomerEvents, models.CustomerEvent{
+			EventID:       eventID,
+			ClientEventID: 9000000 + eventID,
+			InsertDate:    insertDate,
+		})
+
+		lines := 1 + rng.Intn(cfg.MaxLinesPerOrder)
+		seen := make(map[int32]bool, lines)
+		for l := 0; l < lines; l++ {
+			contentID := int32(contentPicker.pick(rng) + 1)
+			if seen[contentID] {
+				continue
+			}
+			seen[contentID] = true
+
+			quantity := int16(1)
+			if rng.Float64() < 0.2 {
+				quantity += int16(rng.Intn(4))
+			}
+
+			eventDataID++
+			ds.CustomerEventData = append(ds.CustomerEventData, models.CustomerEventData{
+				EventDataID: eventDataID,
+				EventID:     eventID,
+				ContentID:   contentID,
+				CustomerID:  customerID,
+				EventTypeID: eventTypePurchase,
+				EventDate:   eventDate,
+				Quantity:    quantity,
+				InsertDate:  insertDate,
+			})
+		}
+	}
+}
+
+// paretoWeights draws n Pareto(xm=1, alpha) samples by inverse transform
+func paretoWeights(rng *rand.Rand, n int, alpha float64) []float64 {
+	weights := make([]float64, n)
+	for i := range weights {
+		u := 1 - rng.Float64() // (0, 1]
+		weights[i] = math.Pow(u, -1/alpha)
+	}
+	return weights
+}
+
+// weightedPicker samples indexes proportionally to their weight
+type weightedPicker struct {
+	cumulative []float64
+}
+
+func newWeightedPicker(weights []float64) *weightedPicker {
+	cumulative := make([]float64, len(weights))
+	total := 0.0
+	for i, w := range weights {
+		total += w
+		cumulative[i] = total
+	}
+	return &weightedPicker{cumulative: cumulative}
+}
+
+func (w *weightedPicker) pick(rng *rand.Rand) int {
+	target := rng.Float64() * w.cumulative[len(w.cumulative)-1]
+	return sort.SearchFloat64s(w.cumulative, target)
+}
+
+func randomTime(rng *rand.Rand, from, to time.Time) time.Time {
+	span := to.Sub(from)
+	if span <= 0 {
+		return from
+	}
+	return from.Add(time.Duration(rng.Int63n(int64(span)))).UTC()
+}
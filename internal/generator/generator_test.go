@@ -0,0 +1,114 @@
+package generator
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"quanticfy-test/pkg/logger"
+)
+
+// smallConfig is DefaultConfig shrunk so the tests run instantly
+func smallConfig(seed int64) Config {
+	cfg := DefaultConfig()
+	cfg.Seed = seed
+	cfg.Customers = 200
+	cfg.Contents = 50
+	cfg.Orders = 1000
+	return cfg
+}
+
+func TestGenerateIsDeterministic(t *testing.T) {
+	first, err := Generate(smallConfig(7), logger.Discard())
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	second, err := Generate(smallConfig(7), logger.Discard())
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	if !reflect.DeepEqual(first, second) {
+		t.Error("two datasets generated with seed 7 differ")
+	}
+
+	other, err := Generate(smallConfig(8), logger.Discard())
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	if reflect.DeepEqual(first.CustomerEventData, other.CustomerEventData) {
+		t.Error("datasets generated with seeds 7 and 8 are identical")
+	}
+}
+
+func TestWriteFixturesIsDeterministic(t *testing.T) {
+	var files [2][]byte
+	for i := range files {
+		ds, err := Generate(smallConfig(7), logger.Discard())
+		if err != nil {
+			t.Fatalf("Generate: %v", err)
+		}
+		dir := t.TempDir()
+		if err := WriteFixtures(dir, ds, logger.Discard()); err != nil {
+			t.Fatalf("WriteFixtures: %v", err)
+		}
+		if files[i], err = os.ReadFile(filepath.Join(dir, "CustomerEventData.csv")); err != nil {
+			t.Fatalf("reading fixture: %v", err)
+		}
+	}
+	if !bytes.Equal(files[0], files[1]) {
+		t.Error("CustomerEventData.csv differs between two runs with the same seed")
+	}
+}
+
+func TestGenerateReferences(t *testing.T) {
+	cfg := smallConfig(1)
+	ds, err := Generate(cfg, logger.Discard())
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+
+	if len(ds.Customers) != cfg.Customers || len(ds.Contents) != cfg.Contents || len(ds.CustomerEvents) != cfg.Orders {
+		t.Errorf("got %d customers, %d contents, %d orders", len(ds.Customers), len(ds.Contents), len(ds.CustomerEvents))
+	}
+	for _, line := range ds.CustomerEventData {
+		if line.CustomerID < 1 || line.CustomerID > int64(cfg.Customers) {
+			t.Fatalf("event line %d references unknown customer %d", line.EventDataID, line.CustomerID)
+		}
+		if line.ContentID < 1 || line.ContentID > int32(cfg.Contents) {
+			t.Fatalf("event line %d references unknown content %d", line.EventDataID, line.ContentID)
+		}
+		if line.EventDate.Before(cfg.From) || !line.EventDate.Before(cfg.To) {
+			t.Fatalf("event line %d dated %s, outside the configured range", line.EventDataID, line.EventDate)
+		}
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(*Config)
+		wantErr bool
+	}{
+		{"default", func(*Config) {}, false},
+		{"no customers", func(c *Config) { c.Customers = 0 }, true},
+		{"negative orders", func(c *Config) { c.Orders = -1 }, true},
+		{"no lines per order", func(c *Config) { c.MaxLinesPerOrder = 0 }, true},
+		{"empty date range", func(c *Config) { c.To = c.From }, true},
+		{"reversed date range", func(c *Config) { c.To = c.From.Add(-time.Hour) }, true},
+		{"rate above 1", func(c *Config) { c.MissingEmailRate = 1.5 }, true},
+		{"negative rate", func(c *Config) { c.PhoneRate = -0.1 }, true},
+		{"zero pareto alpha", func(c *Config) { c.ParetoAlpha = 0 }, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DefaultConfig()
+			tt.modify(&cfg)
+			if err := cfg.validate(); (err != nil) != tt.wantErr {
+				t.Errorf("validate() = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
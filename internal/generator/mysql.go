@@ -0,0 +1,78 @@
+package generator
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"quanticfy-test/pkg/logger"
+	"quanticfy-test/pkg/progress"
+)
+
+// WriteMySQL inserts the dataset into the source tables created by the
+// migrations. With truncate set, existing rows are deleted first. The progress
+// of each table is reported through reporter.
+func WriteMySQL(db *sql.DB, ds *Dataset, truncate bool, log *logger.Logger, reporter progress.Reporter) error {
+	log.Info("Writing synthetic data to database")
+	startTime := time.Now()
+
+	tables := datasetTables(ds)
+
+	if truncate {
+		for i := len(tables) - 1; i >= 0; i-- {
+			if _, err := db.Exec(fmt.Sprintf("DELETE FROM %s", tables[i].name)); err != nil {
+				return fmt.Errorf("error clearing table %s: %w", tables[i].name, err)
+			}
+		}
+	}
+
+	for _, table := range tables {
+		if err := insertTable(db, table, reporter); err != nil {
+			return fmt.Errorf("error inserting into %s: %w", table.name, err)
+		}
+	}
+
+	log.Info("Synthetic data written", logger.Duration(time.Since(startTime)))
+	return nil
+}
+
+// insertTable performs batch inserts, like the exporter does for results
+func insertTable(db *sql.DB, table tableRows, reporter progress.Reporter) error {
+	if len(table.rows) == 0 {
+		return nil
+	}
+
+	batchSize := 1000
+	placeholders := "(" + strings.TrimSuffix(strings.Repeat("?, ", len(table.columns)), ", ") + ")"
+	task := reporter.Start("Inserting "+table.name, len(table.rows))
+	defer task.Finish()
+
+	for i := 0; i < len(table.rows); i += batchSize {
+		end := i + batchSize
+		if end > len(table.rows) {
+			end = len(table.rows)
+		}
+
+		batch := table.rows[i:end]
+		valueStrings := make([]string, 0, len(batch))
+		valueArgs := make([]interface{}, 0, len(batch)*len(table.columns))
+
+		for _, row := range batch {
+			valueStrings = append(valueStrings, placeholders)
+			valueArgs = append(valueArgs, row...)
+		}
+
+		query := fmt.Sprintf("INSERT INTO %s (%s) VALUES %s",
+			table.name, strings.Join(table.columns, ", "), strings.Join(valueStrings, ","))
+
+		if _, err := db.Exec(query, valueArgs...); err != nil {
+			return fmt.Errorf("error executing batch insert: %w", err)
+		}
+
+		task.Add(len(batch))
+	}
+
+	task.Finish()
+	return nil
+}
@@ -0,0 +1,65 @@
+package generator
+
+// tableRows is a dataset table flattened into column names and row values,
+// shared by the MySQL and fixture writers
+type tableRows struct {
+	name    string
+	columns []string
+	rows    [][]interface{}
+}
+
+// datasetTables returns the dataset tables in insertion order
+func datasetTables(ds *Dataset) []tableRows {
+	customers := tableRows{
+		name:    "Customer",
+		columns: []string{"CustomerID", "ClientCustomerID", "InsertDate"},
+	}
+	for _, c := range ds.Customers {
+		customers.rows = append(customers.rows, []interface{}{c.CustomerID, c.ClientCustomerID, c.InsertDate})
+	}
+
+	customerData := tableRows{
+		name:    "CustomerData",
+		columns: []string{"CustomerChannelID", "CustomerID", "ChannelTypeID", "ChannelValue", "IsPrimary", "InsertDate"},
+	}
+	for _, d := range ds.CustomerData {
+		customerData.rows = append(customerData.rows,
+			[]interface{}{d.CustomerChannelID, d.CustomerID, d.ChannelTypeID, d.ChannelValue, d.IsPrimary, d.InsertDate})
+	}
+
+	contents := tableRows{
+		name:    "Content",
+		columns: []string{"ContentID", "ClientContentID", "InsertDate"},
+	}
+	for _, c := range ds.Contents {
+		contents.rows = append(contents.rows, []interface{}{c.ContentID, c.ClientContentID, c.InsertDate})
+	}
+
+	prices := tableRows{
+		name:    "ContentPrice",
+		columns: []string{"ContentPriceID", "ContentID", "Price", "Currency", "InsertDate"},
+	}
+	for _, p := range ds.ContentPrices {
+		prices.rows = append(prices.rows, []interface{}{p.ContentPriceID, p.ContentID, p.Price, p.Currency, p.InsertDate})
+	}
+
+	events := tableRows{
+		name:    "CustomerEvent",
+		columns: []string{"EventID", "ClientEventID", "InsertDate"},
+	}
+	for _, e := range ds.CustomerEvents {
+		events.rows = append(events.rows, []interface{}{e.EventID, e.ClientEventID, e.InsertDate})
+	}
+
+	eventData := tableRows{
+		name: "CustomerEventData",
+		columns: []string{"EventDataID", "EventID", "ContentID", "CustomerID", "EventTypeID",
+			"EventDate", "Quantity", "InsertDate"},
+	}
+	for _, e := range ds.CustomerEventData {
+		eventData.rows = append(eventData.rows, []interface{}{e.EventDataID, e.EventID, e.ContentID,
+			e.CustomerID, e.EventTypeID, e.EventDate, e.Quantity, e.InsertDate})
+	}
+
+	return []tableRows{customers, customerData, contents, prices, events, eventData}
+}
@@ -0,0 +1,97 @@
+package loader
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"quanticfy-test/pkg/logger"
+	"quanticfy-test/pkg/tracing"
+)
+
+// LoadConsents loads the CustomerConsent statuses of one channel (e.g. "email")
+func (l *Loader) LoadConsents(channel string) (map[int64]string, error) {
+	l.log.Info("Loading consents", "channel", channel)
+	startTime := time.Now()
+	_, span := tracing.Start(l.ctx, "LoadConsents", tracing.Table("CustomerConsent"))
+	defer span.End()
+
+	rows, err := l.db.Query(`SELECT CustomerID, Status FROM CustomerConsent WHERE LOWER(Channel) = ?`,
+		strings.ToLower(channel))
+	if err != nil {
+		return nil, fmt.Errorf("error querying consents: %w", err)
+	}
+	defer rows.Close()
+
+	consents := make(map[int64]string)
+	for rows.Next() {
+		var customerID int64
+		var status string
+		if err := rows.Scan(&customerID, &status); err != nil {
+			return nil, fmt.Errorf("error scanning consent row: %w", err)
+		}
+		consents[customerID] = normalizeConsentStatus(status)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating consent rows: %w", err)
+	}
+
+	span.SetAttributes(tracing.Rows(len(consents)))
+	l.recordRows("LoadConsents", "CustomerConsent", len(consents))
+	l.log.Info("Loaded consents", "table", "CustomerConsent", logger.Rows(len(consents)), logger.Duration(time.Since(startTime)))
+	return consents, nil
+}
+
+// ReadConsentFile reads the statuses of one channel from a CSV file with the
+// columns CustomerID, Channel and Status. Lines starting with "#" and a
+// "CustomerID" header row are skipped.
+func (l *Loader) ReadConsentFile(path, channel string) (map[int64]string, error) {
+	l.log.Info("Reading consents", "channel", channel, "path", path)
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("error opening consent file: %w", err)
+	}
+	defer f.Close()
+
+	r := csv.NewReader(f)
+	r.Comment = '#'
+	r.FieldsPerRecord = 3
+	r.TrimLeadingSpace = true
+
+	consents := make(map[int64]string)
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error reading consent file: %w", err)
+		}
+		if strings.EqualFold(record[0], "CustomerID") {
+			continue
+		}
+
+		customerID, err := strconv.ParseInt(strings.TrimSpace(record[0]), 10, 64)
+		if err != nil {
+			line, _ := r.FieldPos(0)
+			return nil, fmt.Errorf("error reading consent file: line %d: invalid CustomerID %q", line, record[0])
+		}
+		if strings.EqualFold(strings.TrimSpace(record[1]), channel) {
+			consents[customerID] = normalizeConsentStatus(record[2])
+		}
+	}
+
+	l.log.Info("Read consents", "path", path, logger.Rows(len(consents)))
+	return consents, nil
+}
+
+// normalizeConsentStatus lowercases a status and accepts "opt-in" for "opt_in"
+func normalizeConsentStatus(status string) string {
+	return strings.ReplaceAll(strings.ToLower(strings.TrimSpace(status)), "-", "_")
+}
@@ -0,0 +1,112 @@
+package loader
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"quanticfy-test/internal/models"
+	"quanticfy-test/pkg/logger"
+	"quanticfy-test/pkg/tracing"
+)
+
+// exclusionTable holds the exclusion rules stored in the database
+const exclusionTable = "CustomerExclusion"
+
+// LoadExclusions loads the exclusion rules of the CustomerExclusion table
+func (l *Loader) LoadExclusions() ([]models.ExclusionRule, error) {
+	l.log.Info("Loading exclusion rules")
+	return l.loadExclusions("LoadExclusions", "")
+}
+
+// LoadSuppressions loads only the customer_id rules of the CustomerExclusion
+// table, which the erase command adds for erased customers. They apply even
+// when the other rules of the table are disabled.
+func (l *Loader) LoadSuppressions() ([]models.ExclusionRule, error) {
+	l.log.Info("Loading customer suppressions")
+	return l.loadExclusions("LoadSuppressions", ` WHERE RuleType = 'customer_id'`)
+}
+
+// loadExclusions reads the rules of the CustomerExclusion table matching where
+func (l *Loader) loadExclusions(query, where string) ([]models.ExclusionRule, error) {
+	startTime := time.Now()
+	_, span := tracing.Start(l.ctx, query, tracing.Table(exclusionTable))
+	defer span.End()
+
+	rows, err := l.db.Query(`SELECT RuleType, Value, COALESCE(Reason, '') FROM ` + exclusionTable + where +
+		` ORDER BY ExclusionID`)
+	if err != nil {
+		return nil, fmt.Errorf("error querying exclusion rules: %w", err)
+	}
+	defer rows.Close()
+
+	var rules []models.ExclusionRule
+	for rows.Next() {
+		rule := models.ExclusionRule{Source: exclusionTable}
+		if err := rows.Scan(&rule.Type, &rule.Value, &rule.Reason); err != nil {
+			return nil, fmt.Errorf("error scanning exclusion row: %w", err)
+		}
+		rules = append(rules, rule)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating exclusion rows: %w", err)
+	}
+
+	span.SetAttributes(tracing.Rows(len(rules)))
+	l.recordRows(query, exclusionTable, len(rules))
+	l.log.Info("Loaded exclusion rules", "table", exclusionTable, logger.Rows(len(rules)), logger.Duration(time.Since(startTime)))
+	return rules, nil
+}
+
+// ReadExclusionFile reads exclusion rules from a CSV file with the columns
+// rule type, value and an optional reason. Blank lines, lines starting with
+// "#" and a "RuleType" header row are skipped.
+func (l *Loader) ReadExclusionFile(path string) ([]models.ExclusionRule, error) {
+	l.log.Info("Reading exclusion rules", "path", path)
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("error opening exclusion file: %w", err)
+	}
+	defer f.Close()
+
+	r := csv.NewReader(f)
+	r.Comment = '#'
+	r.FieldsPerRecord = -1
+	r.TrimLeadingSpace = true
+
+	var rules []models.ExclusionRule
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error reading exclusion file: %w", err)
+		}
+		if len(record) < 2 {
+			line, _ := r.FieldPos(0)
+			return nil, fmt.Errorf("error reading exclusion file: line %d: expected rule type and value", line)
+		}
+		if strings.EqualFold(record[0], "RuleType") {
+			continue
+		}
+
+		rule := models.ExclusionRule{
+			Type:   strings.ToLower(strings.TrimSpace(record[0])),
+			Value:  strings.TrimSpace(record[1]),
+			Source: path,
+		}
+		if len(record) > 2 {
+			rule.Reason = strings.TrimSpace(record[2])
+		}
+		rules = append(rules, rule)
+	}
+
+	l.log.Info("Read exclusion rules", "path", path, logger.Rows(len(rules)))
+	return rules, nil
+}
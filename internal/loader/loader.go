@@ -1,66 +1,281 @@
 package loader
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
-	"log"
 	"time"
 
 	"quanticfy-test/internal/models"
-
-	"github.com/schollz/progressbar/v3"
+	"quanticfy-test/pkg/logger"
+	"quanticfy-test/pkg/progress"
+	"quanticfy-test/pkg/tracing"
 )
 
 type Loader struct {
-	db *sql.DB
+	db       *sql.DB
+	log      *logger.Logger
+	progress progress.Reporter
+	// ctx carries the span the query spans are children of
+	ctx context.Context
+	// read lists the rows read by each query, in the order they ran
+	read []QueryRows
+}
+
+// QueryRows is the number of rows one loader query read from a source table
+type QueryRows struct {
+	Query string
+	Table string
+	Rows  int
+}
+
+func NewLoader(db *sql.DB, log *logger.Logger) *Loader {
+	return &Loader{db: db, log: log, progress: progress.Noop(), ctx: context.Background()}
+}
+
+// SetProgress reports the progress of long queries through reporter
+func (l *Loader) SetProgress(reporter progress.Reporter) {
+	l.progress = reporter
+}
+
+// SetTraceContext makes the query spans children of the span in ctx
+func (l *Loader) SetTraceContext(ctx context.Context) {
+	l.ctx = ctx
+}
+
+// RowsRead returns the rows read by each query so far
+func (l *Loader) RowsRead() []QueryRows {
+	return append([]QueryRows(nil), l.read...)
+}
+
+func (l *Loader) recordRows(query, table string, rows int) {
+	l.read = append(l.read, QueryRows{Query: query, Table: table, Rows: rows})
 }
 
-func NewLoader(db *sql.DB) *Loader {
-	return &Loader{db: db}
+// EmailChannelTypeID is the ChannelType used for email addresses
+const EmailChannelTypeID int16 = 1
+
+// LoadCustomerEmails loads every email row; a customer may have several
+func (l *Loader) LoadCustomerEmails() ([]models.CustomerData, error) {
+	l.log.Info("Loading customer emails")
+	_, span := tracing.Start(l.ctx, "LoadCustomerEmails", tracing.Table("CustomerData"))
+	defer span.End()
+	startTime := time.Now()
+
+	records, err := l.queryCustomerData(`
+		SELECT CustomerChannelID, CustomerID, ChannelTypeID, ChannelValue, IsPrimary, InsertDate
+		FROM CustomerData
+		WHERE ChannelTypeID = ?
+	`, EmailChannelTypeID)
+	if err != nil {
+		return nil, err
+	}
+
+	span.SetAttributes(tracing.Rows(len(records)))
+	l.recordRows("LoadCustomerEmails", "CustomerData", len(records))
+	l.log.Info("Loaded customer emails", "table", "CustomerData", logger.Rows(len(records)), logger.Duration(time.Since(startTime)))
+	return records, nil
 }
 
-// LoadCustomerEmails loads customer emails into a map
-func (l *Loader) LoadCustomerEmails() (map[int64]string, error) {
-	log.Println("[INFO] Loading customer emails...")
+// LoadCustomerChannel loads the CustomerData values of one channel type into a
+// map. A customer with several values gets the most recently inserted one,
+// the lowest value breaking ties.
+func (l *Loader) LoadCustomerChannel(channelTypeID int16) (map[int64]string, error) {
+	l.log.Info("Loading customer channel values", "channel_type_id", channelTypeID)
+	_, span := tracing.Start(l.ctx, "LoadCustomerChannel", tracing.Table("CustomerData"))
+	defer span.End()
 	startTime := time.Now()
 
-	// Query to get customer emails (ChannelTypeID = 1 for email based on the schema)
 	query := `
 		SELECT cd.CustomerID, cd.ChannelValue 
 		FROM CustomerData cd 
-		WHERE cd.ChannelTypeID = 1
+		WHERE cd.ChannelTypeID = ?
+		ORDER BY cd.CustomerID, cd.InsertDate DESC, cd.ChannelValue
 	`
 
-	rows, err := l.db.Query(query)
+	rows, err := l.db.Query(query, channelTypeID)
 	if err != nil {
-		return nil, fmt.Errorf("error querying customer emails: %w", err)
+		return nil, fmt.Errorf("error querying customer channel %d: %w", channelTypeID, err)
 	}
 	defer rows.Close()
 
-	customerEmails := make(map[int64]string)
+	values := make(map[int64]string)
 	count := 0
 
 	for rows.Next() {
 		var customerID int64
-		var email string
-		if err := rows.Scan(&customerID, &email); err != nil {
-			return nil, fmt.Errorf("error scanning email row: %w", err)
+		var value string
+		if err := rows.Scan(&customerID, &value); err != nil {
+			return nil, fmt.Errorf("error scanning channel row: %w", err)
+		}
+		if _, seen := values[customerID]; !seen {
+			values[customerID] = value
 		}
-		customerEmails[customerID] = email
 		count++
 	}
 
 	if err = rows.Err(); err != nil {
-		return nil, fmt.Errorf("error iterating email rows: %w", err)
+		return nil, fmt.Errorf("error iterating channel rows: %w", err)
 	}
 
-	log.Printf("[INFO] Loaded %d customer emails in %v", count, time.Since(startTime))
-	return customerEmails, nil
+	span.SetAttributes(tracing.Rows(count))
+	l.recordRows(fmt.Sprintf("LoadCustomerChannel(%d)", channelTypeID), "CustomerData", count)
+	l.log.Info("Loaded customer channel values", "table", "CustomerData", "channel_type_id", channelTypeID,
+		logger.Rows(count), logger.Duration(time.Since(startTime)))
+	return values, nil
+}
+
+// LoadChannelTypes loads the channel type names keyed by ChannelTypeID
+func (l *Loader) LoadChannelTypes() (map[int16]string, error) {
+	l.log.Info("Loading channel types")
+	_, span := tracing.Start(l.ctx, "LoadChannelTypes", tracing.Table("ChannelType"))
+	defer span.End()
+
+	rows, err := l.db.Query(`SELECT ChannelTypeID, Name FROM ChannelType`)
+	if err != nil {
+		return nil, fmt.Errorf("error querying channel types: %w", err)
+	}
+	defer rows.Close()
+
+	channelTypes := make(map[int16]string)
+	for rows.Next() {
+		var channelType models.ChannelType
+		if err := rows.Scan(&channelType.ChannelTypeID, &channelType.Name); err != nil {
+			return nil, fmt.Errorf("error scanning channel type row: %w", err)
+		}
+		channelTypes[channelType.ChannelTypeID] = channelType.Name
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating channel type rows: %w", err)
+	}
+
+	span.SetAttributes(tracing.Rows(len(channelTypes)))
+	l.recordRows("LoadChannelTypes", "ChannelType", len(channelTypes))
+	l.log.Info("Loaded channel types", "table", "ChannelType", logger.Rows(len(channelTypes)))
+	return channelTypes, nil
+}
+
+// LoadCustomerData loads every CustomerData row, all channel types included
+func (l *Loader) LoadCustomerData() ([]models.CustomerData, error) {
+	l.log.Info("Loading customer contact data (all channels)")
+	_, span := tracing.Start(l.ctx, "LoadCustomerData", tracing.Table("CustomerData"))
+	defer span.End()
+	startTime := time.Now()
+
+	records, err := l.queryCustomerData(`
+		SELECT CustomerChannelID, CustomerID, ChannelTypeID, ChannelValue, IsPrimary, InsertDate
+		FROM CustomerData
+	`)
+	if err != nil {
+		return nil, err
+	}
+
+	span.SetAttributes(tracing.Rows(len(records)))
+	l.recordRows("LoadCustomerData", "CustomerData", len(records))
+	l.log.Info("Loaded customer contact data", "table", "CustomerData", logger.Rows(len(records)), logger.Duration(time.Since(startTime)))
+	return records, nil
+}
+
+func (l *Loader) queryCustomerData(query string, args ...interface{}) ([]models.CustomerData, error) {
+	rows, err := l.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error querying customer data: %w", err)
+	}
+	defer rows.Close()
+
+	var records []models.CustomerData
+	for rows.Next() {
+		var record models.CustomerData
+		if err := rows.Scan(
+			&record.CustomerChannelID,
+			&record.CustomerID,
+			&record.ChannelTypeID,
+			&record.ChannelValue,
+			&record.IsPrimary,
+			&record.InsertDate,
+		); err != nil {
+			return nil, fmt.Errorf("error scanning customer data row: %w", err)
+		}
+		records = append(records, record)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating customer data rows: %w", err)
+	}
+
+	return records, nil
+}
+
+// LoadCustomers loads the Customer dimension: ClientCustomerID keyed by CustomerID
+func (l *Loader) LoadCustomers() (map[int64]int64, error) {
+	l.log.Info("Loading customers")
+	_, span := tracing.Start(l.ctx, "LoadCustomers", tracing.Table("Customer"))
+	defer span.End()
+	startTime := time.Now()
+
+	rows, err := l.db.Query(`SELECT CustomerID, ClientCustomerID FROM Customer`)
+	if err != nil {
+		return nil, fmt.Errorf("error querying customers: %w", err)
+	}
+	defer rows.Close()
+
+	clientIDs := make(map[int64]int64)
+	for rows.Next() {
+		var customer models.Customer
+		if err := rows.Scan(&customer.CustomerID, &customer.ClientCustomerID); err != nil {
+			return nil, fmt.Errorf("error scanning customer row: %w", err)
+		}
+		clientIDs[customer.CustomerID] = customer.ClientCustomerID
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating customer rows: %w", err)
+	}
+
+	span.SetAttributes(tracing.Rows(len(clientIDs)))
+	l.recordRows("LoadCustomers", "Customer", len(clientIDs))
+	l.log.Info("Loaded customers", "table", "Customer", logger.Rows(len(clientIDs)), logger.Duration(time.Since(startTime)))
+	return clientIDs, nil
+}
+
+// LoadContents loads the Content dimension: ClientContentID keyed by ContentID
+func (l *Loader) LoadContents() (map[int32]int64, error) {
+	l.log.Info("Loading contents")
+	_, span := tracing.Start(l.ctx, "LoadContents", tracing.Table("Content"))
+	defer span.End()
+	startTime := time.Now()
+
+	rows, err := l.db.Query(`SELECT ContentID, ClientContentID FROM Content`)
+	if err != nil {
+		return nil, fmt.Errorf("error querying contents: %w", err)
+	}
+	defer rows.Close()
+
+	clientIDs := make(map[int32]int64)
+	for rows.Next() {
+		var content models.Content
+		if err := rows.Scan(&content.ContentID, &content.ClientContentID); err != nil {
+			return nil, fmt.Errorf("error scanning content row: %w", err)
+		}
+		clientIDs[content.ContentID] = content.ClientContentID
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating content rows: %w", err)
+	}
+
+	span.SetAttributes(tracing.Rows(len(clientIDs)))
+	l.recordRows("LoadContents", "Content", len(clientIDs))
+	l.log.Info("Loaded contents", "table", "Content", logger.Rows(len(clientIDs)), logger.Duration(time.Since(startTime)))
+	return clientIDs, nil
 }
 
 // LoadContentPrices loads all content prices into a map
 func (l *Loader) LoadContentPrices() (map[int32]float64, error) {
-	log.Println("[INFO] Loading content prices...")
+	l.log.Info("Loading content prices")
+	_, span := tracing.Start(l.ctx, "LoadContentPrices", tracing.Table("ContentPrice"))
+	defer span.End()
 	startTime := time.Now()
 
 	query := `SELECT ContentID, Price FROM ContentPrice`
@@ -88,43 +303,71 @@ func (l *Loader) LoadContentPrices() (map[int32]float64, error) {
 		return nil, fmt.Errorf("error iterating price rows: %w", err)
 	}
 
-	log.Printf("[INFO] Loaded %d content prices in %v", count, time.Since(startTime))
+	span.SetAttributes(tracing.Rows(count))
+	l.recordRows("LoadContentPrices", "ContentPrice", count)
+	l.log.Info("Loaded content prices", "table", "ContentPrice", logger.Rows(count), logger.Duration(time.Since(startTime)))
 	return prices, nil
 }
 
 
-func (l *Loader) LoadPurchaseEvents(sinceDate time.Time) ([]models.CustomerEventData, error) {
-	log.Printf("[INFO] Loading purchase events since %s...", sinceDate.Format("2006-01-02"))
+// LoadPurchaseEvents loads the purchase lines dated in [from, to). A zero
+// bound leaves that side of the range open.
+func (l *Loader) LoadPurchaseEvents(from, to time.Time) ([]models.CustomerEventData, error) {
+	where := "EventTypeID = 6"
+	var args []interface{}
+	rangeText := "all dates"
+	if !from.IsZero() {
+		where += " AND EventDate >= ?"
+		args = append(args, from)
+		rangeText = "since " + from.Format("2006-01-02")
+	}
+	if !to.IsZero() {
+		where += " AND EventDate < ?"
+		args = append(args, to)
+		if from.IsZero() {
+			rangeText = "before " + to.Format("2006-01-02")
+		} else {
+			rangeText += " until " + to.Format("2006-01-02") + " (excluded)"
+		}
+	}
+
+	l.log.Info("Loading purchase events", "range", rangeText)
 	startTime := time.Now()
+	ctx, span := tracing.Start(l.ctx, "LoadPurchaseEvents", tracing.Table("CustomerEventData"))
+	defer span.End()
 
 	var totalCount int
 	countQuery := `
 		SELECT COUNT(*) 
 		FROM CustomerEventData 
-		WHERE EventTypeID = 6 AND EventDate >= ?
-	`
-	err := l.db.QueryRow(countQuery, sinceDate).Scan(&totalCount)
+		WHERE ` + where
+	_, countSpan := tracing.Start(ctx, "COUNT CustomerEventData", tracing.Table("CustomerEventData"))
+	err := l.db.QueryRow(countQuery, args...).Scan(&totalCount)
+	countSpan.SetAttributes(tracing.Rows(totalCount))
+	countSpan.End()
 	if err != nil {
 		return nil, fmt.Errorf("error counting purchase events: %w", err)
 	}
 
-	log.Printf("[INFO] Found %d purchase events to load", totalCount)
+	l.log.Info("Found purchase events to load", logger.Rows(totalCount))
 
 	query := `
 		SELECT EventDataID, EventID, ContentID, CustomerID, EventTypeID, 
 		       EventDate, Quantity, InsertDate
 		FROM CustomerEventData
-		WHERE EventTypeID = 6 AND EventDate >= ?
-	`
+		WHERE ` + where
 
-	rows, err := l.db.Query(query, sinceDate)
+	_, selectSpan := tracing.Start(ctx, "SELECT CustomerEventData", tracing.Table("CustomerEventData"))
+	defer selectSpan.End()
+	rows, err := l.db.Query(query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("error querying purchase events: %w", err)
 	}
 	defer rows.Close()
 
 	events := make([]models.CustomerEventData, 0, totalCount)
-	bar := progressbar.Default(int64(totalCount), "Loading purchases")
+	task := l.progress.Start("Loading purchases", totalCount)
+	defer task.Finish()
 
 	for rows.Next() {
 		var event models.CustomerEventData
@@ -141,14 +384,17 @@ func (l *Loader) LoadPurchaseEvents(sinceDate time.Time) ([]models.CustomerEvent
 			return nil, fmt.Errorf("error scanning event row: %w", err)
 		}
 		events = append(events, event)
-		bar.Add(1)
+		task.Add(1)
 	}
 
 	if err = rows.Err(); err != nil {
 		return nil, fmt.Errorf("error iterating event rows: %w", err)
 	}
 
-	fmt.Println()
-	log.Printf("[INFO] Loaded %d purchase events in %v", len(events), time.Since(startTime))
+	task.Finish()
+	selectSpan.SetAttributes(tracing.Rows(len(events)))
+	span.SetAttributes(tracing.Rows(len(events)))
+	l.recordRows("LoadPurchaseEvents", "CustomerEventData", len(events))
+	l.log.Info("Loaded purchase events", "table", "CustomerEventData", logger.Rows(len(events)), logger.Duration(time.Since(startTime)))
 	return events, nil
 }
\ No newline at end of file
@@ -0,0 +1,140 @@
+// Package manifest describes what a run read, computed and wrote, for lineage
+// and audit: the manifest is written as JSON and recorded in run_history.
+package manifest
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Run statuses
+const (
+	StatusSuccess = "success"
+	StatusFailed  = "failed"
+)
+
+// Output types
+const (
+	OutputTable = "table"
+	OutputFile  = "file"
+)
+
+// Output is a table or file written by the run
+type Output struct {
+	Type string `json:"type"`
+	Name string `json:"name"`
+	Rows int    `json:"rows,omitempty"`
+}
+
+// Manifest is the record of one run
+type Manifest struct {
+	RunID      string    `json:"run_id"`
+	Version    string    `json:"version"`
+	Status     string    `json:"status"`
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+	// FailedPhase and Error explain a failed run
+	FailedPhase string `json:"failed_phase,omitempty"`
+	Error       string `json:"error,omitempty"`
+
+	// Config is the configuration with its secrets redacted
+	Config interface{} `json:"config"`
+
+	// Inputs are the rows read per source table, all queries included
+	Inputs map[string]int `json:"inputs"`
+	// InputQueries are the rows read by each loader query
+	InputQueries []InputQuery `json:"input_queries"`
+	// Watermark is the latest InsertDate of the purchase events read: the
+	// export reflects source data inserted up to that time
+	Watermark *time.Time `json:"watermark,omitempty"`
+
+	Quantile     float64 `json:"quantile"`
+	Customers    int     `json:"customers"`
+	TopCustomers int     `json:"top_customers"`
+	// RevenueThreshold is the revenue of the last customer in the top
+	// quantile, unset when the run failed before the cutoff
+	RevenueThreshold *float64 `json:"revenue_threshold,omitempty"`
+
+	Outputs []Output `json:"outputs"`
+
+	// PhaseDurations are in seconds, keyed by phase
+	PhaseDurations  map[string]float64 `json:"phase_durations_seconds"`
+	DurationSeconds float64            `json:"duration_seconds"`
+}
+
+// InputQuery is the number of rows one loader query read from a source table
+type InputQuery struct {
+	Query string `json:"query"`
+	Table string `json:"table"`
+	Rows  int    `json:"rows"`
+}
+
+// AddInput records the rows read by a query and adds them to its table
+func (m *Manifest) AddInput(query, table string, rows int) {
+	m.InputQueries = append(m.InputQueries, InputQuery{Query: query, Table: table, Rows: rows})
+	m.Inputs[table] += rows
+}
+
+// New starts the manifest of a run; config should already be redacted
+func New(runID, version string, startedAt time.Time, config interface{}) *Manifest {
+	return &Manifest{
+		RunID:          runID,
+		Version:        version,
+		StartedAt:      startedAt,
+		Config:         config,
+		Inputs:         make(map[string]int),
+		InputQueries:   []InputQuery{},
+		Outputs:        []Output{},
+		PhaseDurations: make(map[string]float64),
+	}
+}
+
+// SetWatermark records the watermark, ignoring the zero time
+func (m *Manifest) SetWatermark(watermark time.Time) {
+	if !watermark.IsZero() {
+		m.Watermark = &watermark
+	}
+}
+
+// SetRevenueThreshold records the revenue needed to enter the top quantile
+func (m *Manifest) SetRevenueThreshold(threshold float64) {
+	m.RevenueThreshold = &threshold
+}
+
+// AddOutput records a table or file written by the run
+func (m *Manifest) AddOutput(outputType, name string, rows int) {
+	m.Outputs = append(m.Outputs, Output{Type: outputType, Name: name, Rows: rows})
+}
+
+// Finish sets the status and end time; a nil err means success
+func (m *Manifest) Finish(finishedAt time.Time, failedPhase string, err error) {
+	m.FinishedAt = finishedAt
+	m.DurationSeconds = finishedAt.Sub(m.StartedAt).Seconds()
+	m.Status = StatusSuccess
+	if err != nil {
+		m.Status = StatusFailed
+		m.FailedPhase = failedPhase
+		m.Error = err.Error()
+	}
+}
+
+// Path returns the manifest file of the run in dir
+func (m *Manifest) Path(dir string) string {
+	return filepath.Join(dir, "run_"+m.RunID+".json")
+}
+
+// WriteJSON writes the manifest as indented JSON
+func (m *Manifest) WriteJSON(path string) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error encoding run manifest: %w", err)
+	}
+
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("error writing run manifest: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,21 @@
+package manifest
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestAddInput(t *testing.T) {
+	m := New("run-1", "dev", time.Now(), nil)
+	m.AddInput("LoadCustomerEmails", "CustomerData", 10)
+	m.AddInput("LoadCustomerChannel(2)", "CustomerData", 4)
+	m.AddInput("LoadConsents", "CustomerConsent", 0)
+
+	if want := map[string]int{"CustomerData": 14, "CustomerConsent": 0}; !reflect.DeepEqual(m.Inputs, want) {
+		t.Errorf("inputs = %v, want %v", m.Inputs, want)
+	}
+	if len(m.InputQueries) != 3 || m.InputQueries[1] != (InputQuery{Query: "LoadCustomerChannel(2)", Table: "CustomerData", Rows: 4}) {
+		t.Errorf("input queries = %+v, want the three queries in order", m.InputQueries)
+	}
+}
@@ -0,0 +1,220 @@
+// Package metrics exposes the figures of a pipeline run in the Prometheus
+// format, over HTTP while the run is going and as a node_exporter textfile
+// once it ends.
+package metrics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+
+	"quanticfy-test/pkg/logger"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// namespace prefixes every metric name
+const namespace = "quanticfy"
+
+// Metrics holds the gauges and counters of one run in their own registry
+type Metrics struct {
+	registry *prometheus.Registry
+
+	RowsLoaded         *prometheus.GaugeVec
+	PhaseDuration      *prometheus.GaugeVec
+	CustomersProcessed prometheus.Gauge
+	TopCustomers       prometheus.Gauge
+	RevenueThreshold   prometheus.Gauge
+	ExportRows         *prometheus.GaugeVec
+	Errors             *prometheus.CounterVec
+	ProgressRows       *prometheus.GaugeVec
+
+	runInfo       *prometheus.GaugeVec
+	runDuration   prometheus.Gauge
+	lastRunTime   prometheus.Gauge
+	lastRunStatus prometheus.Gauge
+}
+
+func New(runID string) *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		RowsLoaded: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "rows_loaded",
+			Help:      "Rows read from each source table, by loader query.",
+		}, []string{"table", "query"}),
+		PhaseDuration: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "phase_duration_seconds",
+			Help:      "Duration of each pipeline phase.",
+		}, []string{"phase"}),
+		CustomersProcessed: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "customers_processed",
+			Help:      "Customers with revenue in the analysis window.",
+		}),
+		TopCustomers: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "top_customers",
+			Help:      "Customers in the top quantile.",
+		}),
+		RevenueThreshold: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "revenue_threshold",
+			Help:      "Revenue of the last customer in the top quantile.",
+		}),
+		ExportRows: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "export_rows",
+			Help:      "Rows written to each export table.",
+		}, []string{"table"}),
+		Errors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "errors_total",
+			Help:      "Error log lines by pipeline phase.",
+		}, []string{"phase"}),
+		ProgressRows: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "progress_rows",
+			Help:      "Units done by each long-running operation.",
+		}, []string{"operation"}),
+		runInfo: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "run_info",
+			Help:      "Always 1, labelled with the run ID.",
+		}, []string{"run_id"}),
+		runDuration: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "run_duration_seconds",
+			Help:      "Duration of the whole run.",
+		}),
+		lastRunTime: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "last_run_timestamp_seconds",
+			Help:      "Unix time the run ended.",
+		}),
+		lastRunStatus: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "last_run_success",
+			Help:      "1 if the run succeeded, 0 if it failed.",
+		}),
+	}
+
+	m.registry.MustRegister(
+		m.RowsLoaded, m.PhaseDuration, m.CustomersProcessed, m.TopCustomers,
+		m.RevenueThreshold, m.ExportRows, m.Errors, m.ProgressRows,
+		m.runInfo, m.runDuration, m.lastRunTime, m.lastRunStatus,
+	)
+	m.runInfo.WithLabelValues(runID).Set(1)
+	// Expose zero error counts so alerts can use increase() from the first run
+	for _, phase := range []string{"setup", "load", "compute", "export"} {
+		m.Errors.WithLabelValues(phase)
+	}
+	return m
+}
+
+// Finish records the end of the run
+func (m *Metrics) Finish(duration time.Duration, success bool) {
+	m.runDuration.Set(duration.Seconds())
+	m.lastRunTime.Set(float64(time.Now().Unix()))
+	if success {
+		m.lastRunStatus.Set(1)
+	} else {
+		m.lastRunStatus.Set(0)
+	}
+}
+
+// WriteTextfile writes every metric to path for the node_exporter textfile
+// collector. The file is written under a temporary name and renamed, so the
+// collector never reads a partial file; path should end in .prom.
+func (m *Metrics) WriteTextfile(path string) error {
+	if err := prometheus.WriteToTextfile(path, m.registry); err != nil {
+		return fmt.Errorf("error writing metrics textfile: %w", err)
+	}
+	return nil
+}
+
+// Serve exposes the metrics on http://addr/metrics until the returned
+// server is shut down
+func (m *Metrics) Serve(addr string, log *logger.Logger) (*http.Server, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("error listening on %s: %w", addr, err)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{}))
+	server := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+
+	go func() {
+		if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Warn("Metrics endpoint stopped", logger.Err(err))
+		}
+	}()
+	log.Info("Serving metrics", "address", "http://"+listener.Addr().String()+"/metrics")
+	return server, nil
+}
+
+// Shutdown stops a server started by Serve, waiting up to timeout for
+// in-flight scrapes
+func Shutdown(server *http.Server, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return server.Shutdown(ctx)
+}
+
+// CountErrors wraps a log handler so every line at error level increments
+// errors_total for the phase of the logger that wrote it ("setup" outside
+// the load, compute and export phases)
+func (m *Metrics) CountErrors(next slog.Handler) slog.Handler {
+	return &errorCounter{next: next, errors: m.Errors, phase: "setup"}
+}
+
+type errorCounter struct {
+	next   slog.Handler
+	errors *prometheus.CounterVec
+	phase  string
+}
+
+func (h *errorCounter) Enabled(ctx context.Context, level slog.Level) bool {
+	return level >= slog.LevelError || h.next.Enabled(ctx, level)
+}
+
+func (h *errorCounter) Handle(ctx context.Context, record slog.Record) error {
+	if record.Level < slog.LevelError {
+		return h.next.Handle(ctx, record)
+	}
+	h.errors.WithLabelValues(h.phase).Inc()
+	if !h.next.Enabled(ctx, record.Level) {
+		return nil
+	}
+	return h.next.Handle(ctx, record)
+}
+
+func (h *errorCounter) WithAttrs(attrs []slog.Attr) slog.Handler {
+	counter := &errorCounter{next: h.next.WithAttrs(attrs), errors: h.errors, phase: h.phase}
+	for _, attr := range attrs {
+		if attr.Key == logger.PhaseKey {
+			counter.phase = attr.Value.String()
+		}
+	}
+	return counter
+}
+
+func (h *errorCounter) WithGroup(name string) slog.Handler {
+	return &errorCounter{next: h.next.WithGroup(name), errors: h.errors, phase: h.phase}
+}
+
+// Progress and Finished make Metrics a progress.Sink
+func (m *Metrics) Progress(operation string, done, total int) {
+	m.ProgressRows.WithLabelValues(operation).Set(float64(done))
+}
+
+func (m *Metrics) Finished(operation string, done int, elapsed time.Duration) {
+	m.ProgressRows.WithLabelValues(operation).Set(float64(done))
+}
@@ -0,0 +1,318 @@
+package migrations
+
+import (
+	"database/sql"
+	"embed"
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"quanticfy-test/pkg/logger"
+)
+
+//go:embed sql/*.sql
+var files embed.FS
+
+// Migration is one versioned schema change with its up and down scripts
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+// MigrationStatus describes whether a migration has been applied
+type MigrationStatus struct {
+	Migration
+	Applied   bool
+	AppliedAt time.Time
+}
+
+type Migrator struct {
+	db         *sql.DB
+	log        *logger.Logger
+	migrations []Migration
+}
+
+func NewMigrator(db *sql.DB, log *logger.Logger) (*Migrator, error) {
+	migrations, err := loadMigrations()
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{db: db, log: log, migrations: migrations}, nil
+}
+
+// loadMigrations reads the embedded NNNN_name.up.sql / NNNN_name.down.sql pairs
+func loadMigrations() ([]Migration, error) {
+	entries, err := files.ReadDir("sql")
+	if err != nil {
+		return nil, fmt.Errorf("error reading embedded migrations: %w", err)
+	}
+
+	byVersion := make(map[int64]*Migration)
+	for _, entry := range entries {
+		fileName := entry.Name()
+
+		var direction string
+		switch {
+		case strings.HasSuffix(fileName, ".up.sql"):
+			direction = "up"
+		case strings.HasSuffix(fileName, ".down.sql"):
+			direction = "down"
+		default:
+			continue
+		}
+
+		base := strings.TrimSuffix(fileName, "."+direction+".sql")
+		versionPart, name, found := strings.Cut(base, "_")
+		if !found {
+			return nil, fmt.Errorf("invalid migration file name %q", fileName)
+		}
+		version, err := strconv.ParseInt(versionPart, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid migration version in %q: %w", fileName, err)
+		}
+
+		content, err := files.ReadFile(path.Join("sql", fileName))
+		if err != nil {
+			return nil, fmt.Errorf("error reading migration %q: %w", fileName, err)
+		}
+
+		m, exists := byVersion[version]
+		if !exists {
+			m = &Migration{Version: version, Name: name}
+			byVersion[version] = m
+		} else if m.Name != name {
+			return nil, fmt.Errorf("migration %d has conflicting names %q and %q", version, m.Name, name)
+		}
+
+		if direction == "up" {
+			m.Up = string(content)
+		} else {
+			m.Down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, fmt.Errorf("migration %d_%s has no up script", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	return migrations, nil
+}
+
+// ensureVersionTable creates the schema_migrations bookkeeping table
+func (m *Migrator) ensureVersionTable() error {
+	_, err := m.db.Exec(`
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			Version BIGINT UNSIGNED NOT NULL,
+			Name VARCHAR(255) NOT NULL,
+			AppliedAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (Version)
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4
+	`)
+	if err != nil {
+		return fmt.Errorf("error creating schema_migrations table: %w", err)
+	}
+	return nil
+}
+
+// appliedVersions returns the applied migration versions and when they were applied
+func (m *Migrator) appliedVersions() (map[int64]time.Time, error) {
+	if err := m.ensureVersionTable(); err != nil {
+		return nil, err
+	}
+
+	rows, err := m.db.Query(`SELECT Version, AppliedAt FROM schema_migrations`)
+	if err != nil {
+		return nil, fmt.Errorf("error querying schema_migrations: %w", err)
+	}
+	defer rows.Close()
+
+	applied := make(map[int64]time.Time)
+	for rows.Next() {
+		var version int64
+		var appliedAt time.Time
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, fmt.Errorf("error scanning schema_migrations row: %w", err)
+		}
+		applied[version] = appliedAt
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating schema_migrations rows: %w", err)
+	}
+
+	return applied, nil
+}
+
+// Up applies pending migrations in version order. steps <= 0 applies all of them.
+func (m *Migrator) Up(steps int) (int, error) {
+	applied, err := m.appliedVersions()
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for _, migration := range m.migrations {
+		if steps > 0 && count >= steps {
+			break
+		}
+		if _, done := applied[migration.Version]; done {
+			continue
+		}
+
+		m.log.Info("Applying migration", "version", migration.Version, "name", migration.Name)
+		if err := m.execScript(migration.Up); err != nil {
+			return count, fmt.Errorf("error applying migration %04d_%s: %w", migration.Version, migration.Name, err)
+		}
+
+		_, err := m.db.Exec(`INSERT INTO schema_migrations (Version, Name) VALUES (?, ?)`,
+			migration.Version, migration.Name)
+		if err != nil {
+			return count, fmt.Errorf("error recording migration %04d_%s: %w", migration.Version, migration.Name, err)
+		}
+		count++
+	}
+
+	return count, nil
+}
+
+// Down rolls back applied migrations, newest first. steps <= 0 rolls back one migration.
+func (m *Migrator) Down(steps int) (int, error) {
+	if steps <= 0 {
+		steps = 1
+	}
+
+	applied, err := m.appliedVersions()
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for i := len(m.migrations) - 1; i >= 0 && count < steps; i-- {
+		migration := m.migrations[i]
+		if _, done := applied[migration.Version]; !done {
+			continue
+		}
+		if migration.Down == "" {
+			return count, fmt.Errorf("migration %04d_%s has no down script", migration.Version, migration.Name)
+		}
+
+		m.log.Info("Rolling back migration", "version", migration.Version, "name", migration.Name)
+		if err := m.execScript(migration.Down); err != nil {
+			return count, fmt.Errorf("error rolling back migration %04d_%s: %w", migration.Version, migration.Name, err)
+		}
+
+		_, err := m.db.Exec(`DELETE FROM schema_migrations WHERE Version = ?`, migration.Version)
+		if err != nil {
+			return count, fmt.Errorf("error unrecording migration %04d_%s: %w", migration.Version, migration.Name, err)
+		}
+		count++
+	}
+
+	return count, nil
+}
+
+// Status lists every known migration and whether it has been applied
+func (m *Migrator) Status() ([]MigrationStatus, error) {
+	applied, err := m.appliedVersions()
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]MigrationStatus, 0, len(m.migrations))
+	for _, migration := range m.migrations {
+		appliedAt, done := applied[migration.Version]
+		statuses = append(statuses, MigrationStatus{
+			Migration: migration,
+			Applied:   done,
+			AppliedAt: appliedAt,
+		})
+	}
+
+	return statuses, nil
+}
+
+// execScript runs each statement of a migration script in order.
+// MySQL commits DDL implicitly, so statements are not wrapped in a transaction.
+func (m *Migrator) execScript(script string) error {
+	for _, statement := range splitStatements(script) {
+		if _, err := m.db.Exec(statement); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// splitStatements splits a script on semicolons that end a line, skipping
+// "--" comment lines. Migration scripts must not put ";" at the end of a
+// line inside a string literal.
+func splitStatements(script string) []string {
+	var statements []string
+	var current strings.Builder
+
+	for _, line := range strings.Split(script, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "--") {
+			continue
+		}
+
+		current.WriteString(line)
+		current.WriteString("\n")
+
+		if strings.HasSuffix(trimmed, ";") {
+			statement := strings.TrimSuffix(strings.TrimSpace(current.String()), ";")
+			statements = append(statements, statement)
+			current.Reset()
+		}
+	}
+
+	if rest := strings.TrimSpace(current.String()); rest != "" {
+		statements = append(statements, rest)
+	}
+
+	return statements
+}
+
+// CheckUpToDate fails unless every known migration has been applied, so that
+// a run stops at startup instead of on a missing table or column. Unlike the
+// other methods it does not create schema_migrations.
+func (m *Migrator) CheckUpToDate() error {
+	var tables int
+	err := m.db.QueryRow(`
+		SELECT COUNT(*) FROM information_schema.TABLES
+		WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = 'schema_migrations'
+	`).Scan(&tables)
+	if err != nil {
+		return fmt.Errorf("error looking for schema_migrations: %w", err)
+	}
+	if tables == 0 {
+		return fmt.Errorf("the database schema is not migrated: run `migrate up` first")
+	}
+
+	statuses, err := m.Status()
+	if err != nil {
+		return err
+	}
+	var pending []string
+	for _, status := range statuses {
+		if !status.Applied {
+			pending = append(pending, fmt.Sprintf("%04d_%s", status.Version, status.Name))
+		}
+	}
+	if len(pending) > 0 {
+		return fmt.Errorf("%d migration(s) pending (%s): run `migrate up` first", len(pending), strings.Join(pending, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,8 @@
+DROP TABLE IF EXISTS CustomerEventData;
+DROP TABLE IF EXISTS CustomerEvent;
+DROP TABLE IF EXISTS ContentPrice;
+DROP TABLE IF EXISTS Content;
+DROP TABLE IF EXISTS CustomerData;
+DROP TABLE IF EXISTS Customer;
+DROP TABLE IF EXISTS EventType;
+DROP TABLE IF EXISTS ChannelType;
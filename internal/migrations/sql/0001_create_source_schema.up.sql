@@ -0,0 +1,73 @@
+-- Source tables read by the loader (LOAD phase).
+
+CREATE TABLE IF NOT EXISTS ChannelType (
+	ChannelTypeID SMALLINT UNSIGNED NOT NULL,
+	Name VARCHAR(100) NOT NULL,
+	PRIMARY KEY (ChannelTypeID)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS EventType (
+	EventTypeID SMALLINT UNSIGNED NOT NULL,
+	Name VARCHAR(100) NOT NULL,
+	PRIMARY KEY (EventTypeID)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS Customer (
+	CustomerID BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+	ClientCustomerID BIGINT NOT NULL,
+	InsertDate TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (CustomerID),
+	INDEX idx_client_customer (ClientCustomerID)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS CustomerData (
+	CustomerChannelID BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+	CustomerID BIGINT UNSIGNED NOT NULL,
+	ChannelTypeID SMALLINT UNSIGNED NOT NULL,
+	ChannelValue VARCHAR(600) NOT NULL,
+	InsertDate TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (CustomerChannelID),
+	INDEX idx_customer_channel (CustomerID, ChannelTypeID),
+	INDEX idx_channel_type (ChannelTypeID)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS Content (
+	ContentID INT UNSIGNED NOT NULL AUTO_INCREMENT,
+	ClientContentID BIGINT NOT NULL,
+	InsertDate TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (ContentID),
+	INDEX idx_client_content (ClientContentID)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS ContentPrice (
+	ContentPriceID INT UNSIGNED NOT NULL AUTO_INCREMENT,
+	ContentID INT UNSIGNED NOT NULL,
+	Price DECIMAL(10,2) NOT NULL,
+	Currency CHAR(3) NOT NULL DEFAULT 'EUR',
+	InsertDate TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (ContentPriceID),
+	INDEX idx_content (ContentID)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS CustomerEvent (
+	EventID BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+	ClientEventID BIGINT NOT NULL,
+	InsertDate TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (EventID),
+	INDEX idx_client_event (ClientEventID)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS CustomerEventData (
+	EventDataID BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+	EventID BIGINT UNSIGNED NOT NULL,
+	ContentID INT UNSIGNED NOT NULL,
+	CustomerID BIGINT UNSIGNED NOT NULL,
+	EventTypeID SMALLINT UNSIGNED NOT NULL,
+	EventDate DATETIME NOT NULL,
+	Quantity SMALLINT NOT NULL,
+	InsertDate TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (EventDataID),
+	INDEX idx_type_date (EventTypeID, EventDate),
+	INDEX idx_event (EventID),
+	INDEX idx_customer (CustomerID)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
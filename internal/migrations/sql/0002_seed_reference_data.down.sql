@@ -0,0 +1,2 @@
+DELETE FROM EventType WHERE EventTypeID BETWEEN 1 AND 6;
+DELETE FROM ChannelType WHERE ChannelTypeID BETWEEN 1 AND 4;
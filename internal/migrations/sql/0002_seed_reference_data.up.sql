@@ -0,0 +1,16 @@
+-- Reference values the loader relies on (ChannelTypeID = 1 is email,
+-- EventTypeID = 6 is a purchase).
+
+INSERT IGNORE INTO ChannelType (ChannelTypeID, Name) VALUES
+	(1, 'Email'),
+	(2, 'Phone'),
+	(3, 'Postal'),
+	(4, 'PushToken');
+
+INSERT IGNORE INTO EventType (EventTypeID, Name) VALUES
+	(1, 'PageView'),
+	(2, 'ProductView'),
+	(3, 'AddToCart'),
+	(4, 'RemoveFromCart'),
+	(5, 'Checkout'),
+	(6, 'Purchase');
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS test_export_template;
@@ -0,0 +1,12 @@
+-- Template for the daily test_export_YYYYMMDD tables. The exporter creates
+-- each daily table with CREATE TABLE ... LIKE test_export_template.
+
+CREATE TABLE IF NOT EXISTS test_export_template (
+	CustomerID BIGINT UNSIGNED NOT NULL,
+	Email VARCHAR(600) NOT NULL,
+	CA DECIMAL(12,2) NOT NULL,
+	InsertDate TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	UpdateDate TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+	PRIMARY KEY (CustomerID),
+	INDEX idx_ca (CA DESC)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
@@ -0,0 +1,7 @@
+ALTER TABLE test_export_template DROP COLUMN FallbackContact;
+
+ALTER TABLE test_export_template DROP COLUMN FallbackChannelTypeID;
+
+UPDATE test_export_template SET Email = '' WHERE Email IS NULL;
+
+ALTER TABLE test_export_template MODIFY Email VARCHAR(600) NOT NULL;
@@ -0,0 +1,8 @@
+-- Customers without an email can be exported with a NULL Email, or with a
+-- contact from another CustomerData channel (see EMAIL_POLICY).
+
+ALTER TABLE test_export_template MODIFY Email VARCHAR(600) NULL;
+
+ALTER TABLE test_export_template ADD COLUMN FallbackChannelTypeID SMALLINT UNSIGNED NULL AFTER CA;
+
+ALTER TABLE test_export_template ADD COLUMN FallbackContact VARCHAR(600) NULL AFTER FallbackChannelTypeID;
@@ -0,0 +1 @@
+ALTER TABLE CustomerData DROP COLUMN IsPrimary;
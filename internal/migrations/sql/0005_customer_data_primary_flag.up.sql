@@ -0,0 +1,3 @@
+-- Marks the preferred value when a customer has several rows for a channel.
+
+ALTER TABLE CustomerData ADD COLUMN IsPrimary TINYINT(1) NOT NULL DEFAULT 0 AFTER ChannelValue;
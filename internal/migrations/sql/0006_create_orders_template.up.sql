@@ -0,0 +1,15 @@
+-- Template for the daily test_orders_YYYYMMDD tables (one row per order,
+-- i.e. per CustomerEventData.EventID).
+
+CREATE TABLE IF NOT EXISTS test_orders_template (
+	EventID BIGINT UNSIGNED NOT NULL,
+	CustomerID BIGINT UNSIGNED NOT NULL,
+	OrderDate DATETIME NOT NULL,
+	LineCount INT NOT NULL,
+	ItemCount INT NOT NULL,
+	Revenue DECIMAL(12,2) NOT NULL,
+	InsertDate TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (EventID),
+	INDEX idx_customer (CustomerID),
+	INDEX idx_order_date (OrderDate)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS test_content_revenue_template;
@@ -0,0 +1,21 @@
+-- Template for the daily test_content_revenue_YYYYMMDD tables (one row per
+-- ContentID sold in the analysis window).
+
+CREATE TABLE IF NOT EXISTS test_content_revenue_template (
+	ContentID INT UNSIGNED NOT NULL,
+	ClientContentID BIGINT NULL,
+	RevenueRank INT NOT NULL,
+	Revenue DECIMAL(14,2) NOT NULL,
+	Units INT NOT NULL,
+	Customers INT NOT NULL,
+	RevenueShare DECIMAL(9,6) NOT NULL,
+	CumulativeShare DECIMAL(9,6) NOT NULL,
+	TopSegmentRevenue DECIMAL(14,2) NOT NULL,
+	TopSegmentUnits INT NOT NULL,
+	TopSegmentShare DECIMAL(9,6) NOT NULL,
+	OtherShare DECIMAL(9,6) NOT NULL,
+	Lift DECIMAL(12,4) NULL,
+	InsertDate TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (ContentID),
+	INDEX idx_rank (RevenueRank)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
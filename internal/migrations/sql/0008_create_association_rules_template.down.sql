@@ -0,0 +1 @@
+DROP TABLE IF EXISTS test_association_rules_template;
@@ -0,0 +1,19 @@
+-- Template for the daily test_association_rules_YYYYMMDD tables (one row per
+-- rule "orders containing Antecedent also contain Consequent"). Itemsets are
+-- comma-separated ContentIDs in ascending order.
+
+CREATE TABLE IF NOT EXISTS test_association_rules_template (
+	Segment VARCHAR(16) NOT NULL,
+	Antecedent VARCHAR(255) NOT NULL,
+	Consequent VARCHAR(255) NOT NULL,
+	ClientAntecedent VARCHAR(512) NULL,
+	ClientConsequent VARCHAR(512) NULL,
+	RuleRank INT NOT NULL,
+	Orders INT NOT NULL,
+	Support DECIMAL(9,6) NOT NULL,
+	Confidence DECIMAL(9,6) NOT NULL,
+	Lift DECIMAL(12,4) NOT NULL,
+	InsertDate TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (Segment, Antecedent, Consequent),
+	INDEX idx_rank (RuleRank)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS test_revenue_timeseries_template;
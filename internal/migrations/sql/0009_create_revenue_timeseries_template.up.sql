@@ -0,0 +1,14 @@
+-- Template for the daily test_revenue_timeseries_YYYYMMDD tables (one row per
+-- segment and period: day, ISO week starting Monday, or month).
+
+CREATE TABLE IF NOT EXISTS test_revenue_timeseries_template (
+	Granularity VARCHAR(8) NOT NULL,
+	Segment VARCHAR(16) NOT NULL,
+	PeriodStart DATE NOT NULL,
+	Revenue DECIMAL(14,2) NOT NULL,
+	Orders INT NOT NULL,
+	Units INT NOT NULL,
+	ActiveCustomers INT NOT NULL,
+	InsertDate TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (Granularity, Segment, PeriodStart)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
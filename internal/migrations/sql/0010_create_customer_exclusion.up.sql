@@ -0,0 +1,13 @@
+-- Customers kept out of the ranking: employees, test accounts, GDPR erasures.
+-- RuleType is customer_id (Value = CustomerID), email (exact address, case
+-- insensitive) or pattern (email glob such as *@quanticfy.fr).
+
+CREATE TABLE IF NOT EXISTS CustomerExclusion (
+	ExclusionID INT UNSIGNED NOT NULL AUTO_INCREMENT,
+	RuleType VARCHAR(16) NOT NULL,
+	Value VARCHAR(255) NOT NULL,
+	Reason VARCHAR(255) NULL,
+	InsertDate TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (ExclusionID),
+	UNIQUE KEY uq_rule (RuleType, Value)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
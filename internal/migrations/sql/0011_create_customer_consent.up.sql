@@ -0,0 +1,10 @@
+-- Marketing consent per customer and channel. Status is opt_in or opt_out;
+-- customers without a row have not consented.
+
+CREATE TABLE IF NOT EXISTS CustomerConsent (
+	CustomerID BIGINT UNSIGNED NOT NULL,
+	Channel VARCHAR(32) NOT NULL,
+	Status VARCHAR(16) NOT NULL,
+	UpdateDate TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+	PRIMARY KEY (CustomerID, Channel)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
@@ -0,0 +1,13 @@
+-- Mapping from email pseudonyms (HMAC-SHA256) back to addresses, written in
+-- privacy mode. Keep it apart from the analytics outputs: only grant SELECT
+-- on it to accounts allowed to see customer emails.
+
+CREATE TABLE IF NOT EXISTS test_email_pseudonyms (
+	EmailHash CHAR(64) NOT NULL,
+	Email VARCHAR(600) NOT NULL,
+	CustomerID BIGINT UNSIGNED NOT NULL,
+	InsertDate TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	UpdateDate TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+	PRIMARY KEY (EmailHash),
+	INDEX idx_customer (CustomerID)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
@@ -0,0 +1 @@
+ALTER TABLE test_export_template MODIFY Email VARCHAR(600) NULL;
@@ -0,0 +1,4 @@
+-- Encrypted emails (base64 of nonce, ciphertext and tag) are longer than the
+-- addresses they hold.
+
+ALTER TABLE test_export_template MODIFY Email VARCHAR(1024) NULL;
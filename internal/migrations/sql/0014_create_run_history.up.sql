@@ -0,0 +1,20 @@
+-- One row per pipeline run, written at the end of the run whether it
+-- succeeded or failed. Manifest holds the full JSON run manifest (redacted
+-- configuration, inputs, outputs, phase durations).
+
+CREATE TABLE IF NOT EXISTS run_history (
+	RunID VARCHAR(64) NOT NULL,
+	Version VARCHAR(128) NOT NULL,
+	Status VARCHAR(16) NOT NULL,
+	StartedAt DATETIME NOT NULL,
+	FinishedAt DATETIME NOT NULL,
+	FailedPhase VARCHAR(16) NULL,
+	Error TEXT NULL,
+	Watermark DATETIME NULL,
+	RevenueThreshold DECIMAL(12,2) NULL,
+	TopCustomers INT NOT NULL DEFAULT 0,
+	Manifest LONGTEXT NOT NULL,
+	InsertDate TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (RunID),
+	INDEX idx_started (StartedAt)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
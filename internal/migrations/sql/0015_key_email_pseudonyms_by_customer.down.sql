@@ -0,0 +1,6 @@
+-- Keep the lowest CustomerID of each pseudonym before restoring the single key
+
+DELETE p FROM test_email_pseudonyms p
+JOIN test_email_pseudonyms q ON p.EmailHash = q.EmailHash AND p.CustomerID > q.CustomerID;
+
+ALTER TABLE test_email_pseudonyms DROP PRIMARY KEY, ADD PRIMARY KEY (EmailHash);
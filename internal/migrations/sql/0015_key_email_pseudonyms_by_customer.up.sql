@@ -0,0 +1,4 @@
+-- Without identity resolution several customers can share an email, and so a
+-- pseudonym: key the mapping on both so none overwrites another.
+
+ALTER TABLE test_email_pseudonyms DROP PRIMARY KEY, ADD PRIMARY KEY (EmailHash, CustomerID);
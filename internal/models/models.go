@@ -15,6 +15,7 @@ type CustomerData struct {
 	CustomerID        int64
 	ChannelTypeID     int16
 	ChannelValue      string
+	IsPrimary         bool
 	InsertDate        time.Time
 }
 
@@ -27,14 +28,14 @@ type CustomerEvent struct {
 
 // CustomerEventData represents the details of a customer event
 type CustomerEventData struct {
-	EventDataID  int64
-	EventID      int64
-	ContentID    int32
-	CustomerID   int64
-	EventTypeID  int16
-	EventDate    time.Time
-	Quantity     int16
-	InsertDate   time.Time
+	EventDataID int64
+	EventID     int64
+	ContentID   int32
+	CustomerID  int64
+	EventTypeID int16
+	EventDate   time.Time
+	Quantity    int16
+	InsertDate  time.Time
 }
 
 // Content represents a product/content
@@ -70,6 +71,121 @@ type CustomerRevenue struct {
 	CustomerID int64
 	Email      string
 	Revenue    float64
+
+	// ClientCustomerID is the client-side identifier from the Customer table (0 if unknown)
+	ClientCustomerID int64
+
+	// Contact from another channel, set by the "fallback" email policy
+	FallbackChannelTypeID int16
+	FallbackContact       string
+
+	// Contacts holds every channel value known for the customer, when loaded
+	Contacts *ContactProfile
+
+	// MemberIDs lists the CustomerIDs merged into this one by identity resolution
+	MemberIDs []int64
+
+	// Orders holds order-level metrics, when computed
+	Orders *CustomerOrderMetrics
+
+	// WindowRevenue is the revenue per named analysis window (e.g. "90d"), when computed
+	WindowRevenue map[string]float64
+
+	// Trend compares the current and previous periods, when computed
+	Trend *CustomerTrend
+}
+
+// OrderRevenue aggregates the purchase lines sharing one EventID
+type OrderRevenue struct {
+	EventID    int64
+	CustomerID int64
+	OrderDate  time.Time
+	Lines      int
+	Items      int
+	Revenue    float64
+}
+
+// CustomerOrderMetrics summarizes a customer's orders
+type CustomerOrderMetrics struct {
+	OrderCount     int
+	TotalRevenue   float64
+	TotalItems     int
+	FirstOrderDate time.Time
+	LastOrderDate  time.Time
+}
+
+// AvgOrderValue is the mean revenue per order
+func (m *CustomerOrderMetrics) AvgOrderValue() float64 {
+	if m == nil || m.OrderCount == 0 {
+		return 0
+	}
+	return m.TotalRevenue / float64(m.OrderCount)
+}
+
+// ItemsPerOrder is the mean quantity per order
+func (m *CustomerOrderMetrics) ItemsPerOrder() float64 {
+	if m == nil || m.OrderCount == 0 {
+		return 0
+	}
+	return float64(m.TotalItems) / float64(m.OrderCount)
+}
+
+// CustomerTrend compares a customer's revenue over two consecutive periods
+type CustomerTrend struct {
+	CurrentRevenue  float64
+	PreviousRevenue float64
+	// GrowthRate is (current - previous) / previous; HasGrowthRate is false when previous is 0
+	GrowthRate    float64
+	HasGrowthRate bool
+	// LastPurchaseDate is zero when no purchase was loaded before the reference date
+	LastPurchaseDate      time.Time
+	DaysSinceLastPurchase int
+	// Status is growing, stable, declining or at_risk
+	Status string
+}
+
+// OutlierCustomer is a customer flagged for review by outlier detection
+type OutlierCustomer struct {
+	CustomerID int64   `json:"customer_id"`
+	Email      string  `json:"email,omitempty"`
+	Revenue    float64 `json:"revenue"`
+	Score      float64 `json:"score,omitempty"`
+	// MaxEventQuantity is the largest quantity on a single purchase line, when it broke the rule
+	MaxEventQuantity int      `json:"max_event_quantity,omitempty"`
+	Reasons          []string `json:"reasons"`
+}
+
+// ExclusionRule keeps matching customers out of the ranking
+type ExclusionRule struct {
+	// Type is customer_id, email or pattern
+	Type   string
+	Value  string
+	Reason string
+	// Source is where the rule was loaded from (table name or file path)
+	Source string
+}
+
+// ConsentStats counts customers by marketing consent
+type ConsentStats struct {
+	OptedIn  int
+	OptedOut int
+	// Unknown customers have no status for the channel
+	Unknown int
+}
+
+// ContactProfile groups a customer's CustomerData values by channel name
+// (from the ChannelType table), most recent first
+type ContactProfile struct {
+	CustomerID int64
+	Channels   map[string][]string
+}
+
+// Value returns the most recent value for a channel, or "" if there is none
+func (c *ContactProfile) Value(channel string) string {
+	if c == nil || len(c.Channels[channel]) == 0 {
+		return ""
+	}
+	return c.Channels[channel][0]
 }
 
 // QuantileStats holds statistics for a revenue quantile
@@ -78,4 +194,28 @@ type QuantileStats struct {
 	CustomerCount int
 	MaxRevenue    float64
 	MinRevenue    float64
-}
\ No newline at end of file
+
+	// Order metrics, filled when customers have Orders attached
+	HasOrderMetrics bool
+	OrderCount      int
+	AvgOrderValue   float64
+	ItemsPerOrder   float64
+}
+
+// EmailPolicyStats counts how top customers were handled by the email policy
+type EmailPolicyStats struct {
+	Policy      string
+	WithEmail   int
+	Placeholder int
+	Null        int
+	Fallback    int
+	Excluded    int
+	Unreachable int
+}
+
+// InvalidEmail is an email value rejected by normalization
+type InvalidEmail struct {
+	CustomerID int64  `json:"customer_id"`
+	Value      string `json:"value"`
+	Reason     string `json:"reason"`
+}
@@ -0,0 +1,211 @@
+// Package notify tells someone how a run ended: on success with its summary,
+// on failure with the phase and error, and when data quality thresholds are
+// exceeded. Events go to every configured channel (webhook, Slack, email).
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"quanticfy-test/internal/manifest"
+	"quanticfy-test/internal/quality"
+)
+
+// Event kinds, as accepted by NOTIFY_EVENTS
+const (
+	EventSuccess  = "success"
+	EventFailure  = "failure"
+	EventDQBreach = "dq_breach"
+)
+
+// Kinds lists every event kind
+var Kinds = []string{EventSuccess, EventFailure, EventDQBreach}
+
+// Event is the payload of a notification; webhooks receive it as JSON
+type Event struct {
+	Kind    string    `json:"event"`
+	RunID   string    `json:"run_id"`
+	Version string    `json:"version"`
+	Time    time.Time `json:"time"`
+	// Phase and Error explain a failure; Phase is also set on a breach
+	Phase    string           `json:"phase,omitempty"`
+	Error    string           `json:"error,omitempty"`
+	Breaches []quality.Breach `json:"breaches,omitempty"`
+	Summary  Summary          `json:"summary"`
+}
+
+// Summary is what the run read, computed and wrote before the event
+type Summary struct {
+	Inputs           map[string]int     `json:"inputs"`
+	Watermark        *time.Time         `json:"watermark,omitempty"`
+	Customers        int                `json:"customers"`
+	TopCustomers     int                `json:"top_customers"`
+	RevenueThreshold *float64           `json:"revenue_threshold,omitempty"`
+	Outputs          []manifest.Output  `json:"outputs"`
+	PhaseDurations   map[string]float64 `json:"phase_durations_seconds"`
+	DurationSeconds  float64            `json:"duration_seconds"`
+}
+
+// RunEvent is the success or failure event of a finished run
+func RunEvent(m *manifest.Manifest) Event {
+	event := newEvent(m, EventSuccess)
+	if m.Status == manifest.StatusFailed {
+		event.Kind = EventFailure
+		event.Phase = m.FailedPhase
+		event.Error = m.Error
+	}
+	event.Summary.DurationSeconds = m.DurationSeconds
+	return event
+}
+
+// BreachEvent reports the data quality thresholds exceeded in phase
+func BreachEvent(m *manifest.Manifest, phase string, breaches []quality.Breach) Event {
+	event := newEvent(m, EventDQBreach)
+	event.Phase = phase
+	event.Breaches = breaches
+	event.Summary.DurationSeconds = event.Time.Sub(m.StartedAt).Seconds()
+	return event
+}
+
+func newEvent(m *manifest.Manifest, kind string) Event {
+	return Event{
+		Kind:    kind,
+		RunID:   m.RunID,
+		Version: m.Version,
+		Time:    time.Now(),
+		Summary: Summary{
+			Inputs:           m.Inputs,
+			Watermark:        m.Watermark,
+			Customers:        m.Customers,
+			TopCustomers:     m.TopCustomers,
+			RevenueThreshold: m.RevenueThreshold,
+			Outputs:          m.Outputs,
+			PhaseDurations:   m.PhaseDurations,
+		},
+	}
+}
+
+// Title is a one-line description of the event, used as the email subject
+func (e Event) Title() string {
+	switch e.Kind {
+	case EventSuccess:
+		return fmt.Sprintf("quanticfy run %s succeeded", e.RunID)
+	case EventFailure:
+		return fmt.Sprintf("quanticfy run %s failed in phase %s", e.RunID, e.Phase)
+	case EventDQBreach:
+		return fmt.Sprintf("quanticfy run %s: data quality thresholds exceeded", e.RunID)
+	}
+	return fmt.Sprintf("quanticfy run %s: %s", e.RunID, e.Kind)
+}
+
+// Text is the plain text body of the event for people (email, Slack)
+func (e Event) Text() string {
+	var b strings.Builder
+	if e.Error != "" {
+		fmt.Fprintf(&b, "Error: %s\n", e.Error)
+	}
+	for _, breach := range e.Breaches {
+		fmt.Fprintf(&b, "Breach: %s\n", breach)
+	}
+
+	s := e.Summary
+	if len(s.Inputs) > 0 {
+		tables := make([]string, 0, len(s.Inputs))
+		for table := range s.Inputs {
+			tables = append(tables, table)
+		}
+		sort.Strings(tables)
+		for i, table := range tables {
+			tables[i] = fmt.Sprintf("%s=%d", table, s.Inputs[table])
+		}
+		fmt.Fprintf(&b, "Rows loaded: %s\n", strings.Join(tables, ", "))
+	}
+	if s.Watermark != nil {
+		fmt.Fprintf(&b, "Watermark: %s\n", s.Watermark.Format(time.RFC3339))
+	}
+	if s.Customers > 0 {
+		fmt.Fprintf(&b, "Customers: %d, top customers: %d", s.Customers, s.TopCustomers)
+		if s.RevenueThreshold != nil {
+			fmt.Fprintf(&b, ", revenue threshold: %.2f", *s.RevenueThreshold)
+		}
+		b.WriteString("\n")
+	}
+	for _, output := range s.Outputs {
+		fmt.Fprintf(&b, "Output: %s %s", output.Type, output.Name)
+		if output.Rows > 0 {
+			fmt.Fprintf(&b, " (%d rows)", output.Rows)
+		}
+		b.WriteString("\n")
+	}
+	fmt.Fprintf(&b, "Duration: %.1fs\nVersion: %s\n", s.DurationSeconds, e.Version)
+	return b.String()
+}
+
+// Notifier delivers events to one channel
+type Notifier interface {
+	Name() string
+	Notify(ctx context.Context, event Event) error
+}
+
+// Dispatcher sends the enabled kinds of events to every notifier
+type Dispatcher struct {
+	notifiers []Notifier
+	kinds     map[string]bool
+	timeout   time.Duration
+	// breached holds the runs whose dq_breach event was sent
+	breached map[string]bool
+}
+
+// NewDispatcher sends the given kinds of events, giving each notifier
+// timeout to deliver one
+func NewDispatcher(kinds []string, timeout time.Duration) *Dispatcher {
+	d := &Dispatcher{kinds: make(map[string]bool), timeout: timeout, breached: make(map[string]bool)}
+	for _, kind := range kinds {
+		d.kinds[kind] = true
+	}
+	return d
+}
+
+// Add registers a channel
+func (d *Dispatcher) Add(n Notifier) {
+	d.notifiers = append(d.notifiers, n)
+}
+
+// Channels returns the names of the registered channels
+func (d *Dispatcher) Channels() []string {
+	names := make([]string, len(d.notifiers))
+	for i, n := range d.notifiers {
+		names[i] = n.Name()
+	}
+	return names
+}
+
+// Send delivers event to every channel unless its kind is disabled. A failing
+// channel does not prevent delivery to the others; all errors are returned.
+// The failure of a run aborted by a data quality breach is not sent when the
+// dq_breach event already was, so each failure is notified once.
+func (d *Dispatcher) Send(event Event) error {
+	if !d.kinds[event.Kind] {
+		return nil
+	}
+	if event.Kind == EventFailure && d.breached[event.RunID] {
+		return nil
+	}
+	if event.Kind == EventDQBreach {
+		d.breached[event.RunID] = true
+	}
+
+	var errs []error
+	for _, n := range d.notifiers {
+		ctx, cancel := context.WithTimeout(context.Background(), d.timeout)
+		if err := n.Notify(ctx, event); err != nil {
+			errs = append(errs, fmt.Errorf("error notifying %s: %w", n.Name(), err))
+		}
+		cancel()
+	}
+	return errors.Join(errs...)
+}
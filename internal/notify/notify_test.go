@@ -0,0 +1,109 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"quanticfy-test/internal/quality"
+)
+
+// recorder is a channel that remembers the events it was given
+type recorder struct {
+	name   string
+	err    error
+	events []string
+}
+
+func (r *recorder) Name() string { return r.name }
+
+func (r *recorder) Notify(_ context.Context, event Event) error {
+	r.events = append(r.events, event.Kind)
+	return r.err
+}
+
+func TestDispatcherFiltersKinds(t *testing.T) {
+	m := testManifest()
+	m.Finish(m.StartedAt.Add(time.Minute), "", nil)
+	success := RunEvent(m)
+	breach := BreachEvent(m, "compute", nil)
+
+	tests := []struct {
+		name  string
+		kinds []string
+		want  []string
+	}{
+		{"all", Kinds, []string{EventSuccess, EventDQBreach}},
+		{"failures only", []string{EventFailure}, nil},
+		{"breaches only", []string{EventDQBreach}, []string{EventDQBreach}},
+		{"none", nil, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &recorder{name: "recorder"}
+			d := NewDispatcher(tt.kinds, time.Second)
+			d.Add(r)
+			for _, event := range []Event{success, breach} {
+				if err := d.Send(event); err != nil {
+					t.Fatalf("Send(%s): %v", event.Kind, err)
+				}
+			}
+			if strings.Join(r.events, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("sent %v, want %v", r.events, tt.want)
+			}
+		})
+	}
+}
+
+func TestDispatcherFailingChannelDoesNotBlockOthers(t *testing.T) {
+	server, received := newServer(t, http.StatusNoContent)
+	broken := &recorder{name: "broken", err: errors.New("connection refused")}
+	after := &recorder{name: "after"}
+
+	d := NewDispatcher(Kinds, time.Second)
+	d.Add(broken)
+	d.Add(NewWebhook(server.URL, server.Client()))
+	d.Add(after)
+
+	err := d.Send(RunEvent(testManifest()))
+	if err == nil || !strings.Contains(err.Error(), "error notifying broken: connection refused") {
+		t.Errorf("got error %v, want the broken channel's error", err)
+	}
+	if len(received) != 1 {
+		t.Errorf("webhook received %d requests, want 1", len(received))
+	}
+	if len(after.events) != 1 {
+		t.Errorf("channel after the broken one got %d events, want 1", len(after.events))
+	}
+}
+
+func TestDispatcherNotifiesBreachFailureOnce(t *testing.T) {
+	m := testManifest()
+	breach := BreachEvent(m, "compute", []quality.Breach{{Metric: "missing_price_rate", Rate: 0.05, Threshold: 0.01}})
+	m.Finish(m.StartedAt.Add(time.Minute), "compute", errors.New("data quality thresholds exceeded"))
+	failure := RunEvent(m)
+
+	tests := []struct {
+		name  string
+		kinds []string
+		want  []string
+	}{
+		{"breach enabled", Kinds, []string{EventDQBreach}},
+		{"breach disabled", []string{EventSuccess, EventFailure}, []string{EventFailure}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &recorder{name: "recorder"}
+			d := NewDispatcher(tt.kinds, time.Second)
+			d.Add(r)
+			d.Send(breach)
+			d.Send(failure)
+			if strings.Join(r.events, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("sent %v, want %v", r.events, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,84 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// Slack attachment colors per event kind
+var slackColors = map[string]string{
+	EventSuccess:  "good",
+	EventFailure:  "danger",
+	EventDQBreach: "warning",
+}
+
+// Webhook POSTs the event as JSON to a URL
+type Webhook struct {
+	url    string
+	client *http.Client
+}
+
+func NewWebhook(url string, client *http.Client) *Webhook {
+	return &Webhook{url: url, client: client}
+}
+
+func (w *Webhook) Name() string { return "webhook" }
+
+func (w *Webhook) Notify(ctx context.Context, event Event) error {
+	return postJSON(ctx, w.client, w.url, event)
+}
+
+// Slack POSTs a Slack incoming webhook payload (also accepted by Mattermost
+// and Rocket.Chat): the title as text and the details in a colored attachment
+type Slack struct {
+	url    string
+	client *http.Client
+}
+
+func NewSlack(url string, client *http.Client) *Slack {
+	return &Slack{url: url, client: client}
+}
+
+func (s *Slack) Name() string { return "slack" }
+
+func (s *Slack) Notify(ctx context.Context, event Event) error {
+	payload := map[string]interface{}{
+		"text": event.Title(),
+		"attachments": []map[string]string{{
+			"color":    slackColors[event.Kind],
+			"text":     event.Text(),
+			"fallback": event.Title(),
+		}},
+	}
+	return postJSON(ctx, s.client, s.url, payload)
+}
+
+// postJSON POSTs payload and fails on a non-2xx response
+func postJSON(ctx context.Context, client *http.Client, url string, payload interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("error encoding payload: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("error creating request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("error posting payload: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("unexpected status %s: %s", resp.Status, bytes.TrimSpace(detail))
+	}
+	return nil
+}
@@ -0,0 +1,124 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"quanticfy-test/internal/manifest"
+	"quanticfy-test/internal/quality"
+)
+
+// request is what the stand-in server received
+type request struct {
+	method      string
+	contentType string
+	body        []byte
+}
+
+// newServer answers every request with status and records it
+func newServer(t *testing.T, status int) (*httptest.Server, chan request) {
+	t.Helper()
+	received := make(chan request, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received <- request{method: r.Method, contentType: r.Header.Get("Content-Type"), body: body}
+		w.WriteHeader(status)
+		w.Write([]byte("stand-in says no"))
+	}))
+	t.Cleanup(server.Close)
+	return server, received
+}
+
+func testManifest() *manifest.Manifest {
+	started := time.Date(2024, 3, 1, 8, 0, 0, 0, time.UTC)
+	m := manifest.New("run-1", "1.2.3", started, nil)
+	m.Inputs["CustomerEventData"] = 1000
+	m.Customers = 400
+	m.TopCustomers = 10
+	m.SetRevenueThreshold(1234.5)
+	m.AddOutput(manifest.OutputTable, "test_export_20240301", 10)
+	return m
+}
+
+func TestWebhookPayload(t *testing.T) {
+	server, received := newServer(t, http.StatusOK)
+	m := testManifest()
+	m.Finish(m.StartedAt.Add(90*time.Second), "compute", io.ErrUnexpectedEOF)
+
+	if err := NewWebhook(server.URL, server.Client()).Notify(context.Background(), RunEvent(m)); err != nil {
+		t.Fatalf("Notify: %v", err)
+	}
+
+	req := <-received
+	if req.method != http.MethodPost || req.contentType != "application/json" {
+		t.Errorf("got %s with Content-Type %q, want a JSON POST", req.method, req.contentType)
+	}
+	var got Event
+	if err := json.Unmarshal(req.body, &got); err != nil {
+		t.Fatalf("payload is not an event: %v\n%s", err, req.body)
+	}
+	if got.Kind != EventFailure || got.RunID != "run-1" || got.Phase != "compute" || got.Error != io.ErrUnexpectedEOF.Error() {
+		t.Errorf("got event %+v", got)
+	}
+	if got.Summary.TopCustomers != 10 || got.Summary.Inputs["CustomerEventData"] != 1000 || got.Summary.DurationSeconds != 90 {
+		t.Errorf("got summary %+v", got.Summary)
+	}
+	if got.Summary.RevenueThreshold == nil || *got.Summary.RevenueThreshold != 1234.5 {
+		t.Errorf("got revenue threshold %v, want 1234.5", got.Summary.RevenueThreshold)
+	}
+}
+
+func TestSlackPayload(t *testing.T) {
+	server, received := newServer(t, http.StatusOK)
+	event := BreachEvent(testManifest(), "compute", []quality.Breach{{Metric: "missing_price_rate", Rate: 0.05, Threshold: 0.01}})
+
+	if err := NewSlack(server.URL, server.Client()).Notify(context.Background(), event); err != nil {
+		t.Fatalf("Notify: %v", err)
+	}
+
+	var payload struct {
+		Text        string `json:"text"`
+		Attachments []struct {
+			Color string `json:"color"`
+			Text  string `json:"text"`
+		} `json:"attachments"`
+	}
+	if err := json.Unmarshal((<-received).body, &payload); err != nil {
+		t.Fatalf("payload is not JSON: %v", err)
+	}
+	if payload.Text != "quanticfy run run-1: data quality thresholds exceeded" {
+		t.Errorf("got text %q", payload.Text)
+	}
+	if len(payload.Attachments) != 1 {
+		t.Fatalf("got %d attachments, want 1", len(payload.Attachments))
+	}
+	attachment := payload.Attachments[0]
+	if attachment.Color != "warning" {
+		t.Errorf("got color %q, want warning", attachment.Color)
+	}
+	for _, want := range []string{"Breach: missing_price_rate 5.00% exceeds threshold 1.00%", "top customers: 10"} {
+		if !strings.Contains(attachment.Text, want) {
+			t.Errorf("attachment text %q does not contain %q", attachment.Text, want)
+		}
+	}
+}
+
+func TestPostJSONRejectsNon2xx(t *testing.T) {
+	for _, status := range []int{http.StatusMovedPermanently, http.StatusBadRequest, http.StatusInternalServerError} {
+		server, _ := newServer(t, status)
+		err := NewWebhook(server.URL, server.Client()).Notify(context.Background(), RunEvent(testManifest()))
+		if err == nil {
+			t.Errorf("status %d: got no error", status)
+			continue
+		}
+		if !strings.Contains(err.Error(), "stand-in says no") {
+			t.Errorf("status %d: error %q does not include the response body", status, err)
+		}
+	}
+}
@@ -0,0 +1,199 @@
+package privacy
+
+import (
+	"bufio"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// encryptionKeyLength is the AES-256 key size in bytes
+const encryptionKeyLength = 32
+
+var validKeyID = regexp.MustCompile(`^[A-Za-z0-9_.-]{1,32}$`)
+
+// Keyring holds the AES-256 keys used to encrypt emails, by key ID. New
+// values are encrypted with the active key; older keys stay available to
+// decrypt values written before a rotation.
+type Keyring struct {
+	keys   map[string][]byte
+	active string
+}
+
+// ParseKeyring reads "id:base64key" entries separated by commas or newlines.
+// Blank lines and lines starting with # are ignored. activeID selects the
+// encryption key; when empty, the last key listed is active.
+func ParseKeyring(spec, activeID string) (*Keyring, error) {
+	keyring := &Keyring{keys: make(map[string][]byte)}
+	var last string
+
+	scanner := bufio.NewScanner(strings.NewReader(strings.ReplaceAll(spec, ",", "\n")))
+	for scanner.Scan() {
+		entry := strings.TrimSpace(scanner.Text())
+		if entry == "" || strings.HasPrefix(entry, "#") {
+			continue
+		}
+		id, encoded, found := strings.Cut(entry, ":")
+		id = strings.TrimSpace(id)
+		if !found || !validKeyID.MatchString(id) {
+			return nil, fmt.Errorf("invalid key entry %q, expected id:base64key with an id of up to 32 letters, digits, '.', '_' or '-'", id)
+		}
+		key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+		if err != nil {
+			return nil, fmt.Errorf("key %q is not valid base64: %w", id, err)
+		}
+		if len(key) != encryptionKeyLength {
+			return nil, fmt.Errorf("key %q must be %d bytes, got %d", id, encryptionKeyLength, len(key))
+		}
+		if _, exists := keyring.keys[id]; exists {
+			return nil, fmt.Errorf("key %q is defined twice", id)
+		}
+		keyring.keys[id] = key
+		last = id
+	}
+
+	if len(keyring.keys) == 0 {
+		return nil, fmt.Errorf("no encryption key found")
+	}
+
+	keyring.active = last
+	if activeID != "" {
+		if _, exists := keyring.keys[activeID]; !exists {
+			return nil, fmt.Errorf("active key %q is not in the keyring", activeID)
+		}
+		keyring.active = activeID
+	}
+	return keyring, nil
+}
+
+// ReadKeyringFile reads a keyring from a file with one "id:base64key" per line
+func ReadKeyringFile(path, activeID string) (*Keyring, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading key file: %w", err)
+	}
+	keyring, err := ParseKeyring(string(data), activeID)
+	if err != nil {
+		return nil, fmt.Errorf("error in key file %s: %w", path, err)
+	}
+	return keyring, nil
+}
+
+// ActiveKeyID is the ID of the key new values are encrypted with
+func (k *Keyring) ActiveKeyID() string {
+	return k.active
+}
+
+// Encryptor encrypts emails and contacts with AES-256-GCM under the active
+// key of a keyring
+type Encryptor struct {
+	keyring *Keyring
+	// deterministic derives the nonce from the value so equal values of a
+	// customer give equal ciphertexts under the same key
+	deterministic bool
+}
+
+func NewEncryptor(keyring *Keyring, deterministic bool) *Encryptor {
+	return &Encryptor{keyring: keyring, deterministic: deterministic}
+}
+
+// ActiveKeyID is the ID of the key Encrypt uses
+func (e *Encryptor) ActiveKeyID() string {
+	return e.keyring.active
+}
+
+// Encrypt returns base64(nonce || ciphertext || tag) of value under the
+// active key, or "" for no value. The customer, the column and the key ID
+// are bound as additional data, so a value copied to another row or column
+// no longer decrypts. In deterministic mode the nonce is an HMAC of the
+// column and value and the customer is left out of the additional data: the
+// same value in the same column gives the same ciphertext whatever the
+// customer, so exports can be joined on it, and a value copied to another
+// customer still decrypts.
+func (e *Encryptor) Encrypt(value string, customerID int64, column string) (string, error) {
+	if value == "" {
+		return "", nil
+	}
+	key := e.keyring.keys[e.keyring.active]
+	aead, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if e.deterministic {
+		// A subkey keeps the nonce derivation independent of the AES key use.
+		// A nonce is only reused for the same column and value, hence for the
+		// same additional data and plaintext.
+		subkey := hmac.New(sha256.New, key)
+		subkey.Write([]byte("email-nonce"))
+		mac := hmac.New(sha256.New, subkey.Sum(nil))
+		mac.Write([]byte(column))
+		mac.Write([]byte{0})
+		mac.Write([]byte(value))
+		copy(nonce, mac.Sum(nil))
+	} else if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("error generating nonce: %w", err)
+	}
+
+	sealed := aead.Seal(nonce, nonce, []byte(value), e.additionalData(e.keyring.active, customerID, column))
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt returns the value encrypted by Encrypt under keyID for the customer
+// and column. The encryptor must use the mode the value was encrypted with.
+func (e *Encryptor) Decrypt(value, keyID string, customerID int64, column string) (string, error) {
+	if value == "" {
+		return "", nil
+	}
+	key, exists := e.keyring.keys[keyID]
+	if !exists {
+		return "", fmt.Errorf("unknown key %q", keyID)
+	}
+	aead, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(value)
+	if err != nil {
+		return "", fmt.Errorf("value is not valid base64: %w", err)
+	}
+	if len(sealed) < aead.NonceSize()+aead.Overhead() {
+		return "", fmt.Errorf("value is too short to be an encrypted value")
+	}
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, ciphertext, e.additionalData(keyID, customerID, column))
+	if err != nil {
+		return "", fmt.Errorf("error decrypting %s of customer %d with key %q: %w", column, customerID, keyID, err)
+	}
+	return string(plaintext), nil
+}
+
+// additionalData is the GCM additional data binding a value to its key,
+// column and, outside deterministic mode, customer
+func (e *Encryptor) additionalData(keyID string, customerID int64, column string) []byte {
+	if e.deterministic {
+		return []byte(fmt.Sprintf("%s\x00%s", keyID, column))
+	}
+	return []byte(fmt.Sprintf("%s\x00%d\x00%s", keyID, customerID, column))
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("error creating cipher: %w", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("error creating GCM: %w", err)
+	}
+	return aead, nil
+}
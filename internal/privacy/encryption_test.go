@@ -0,0 +1,152 @@
+package privacy
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+)
+
+// testKeyring has keys k1 and k2, each 32 times the same byte
+func testKeyring(t *testing.T, activeID string) *Keyring {
+	t.Helper()
+	spec := "k1:" + base64.StdEncoding.EncodeToString([]byte(strings.Repeat("1", 32))) +
+		",k2:" + base64.StdEncoding.EncodeToString([]byte(strings.Repeat("2", 32)))
+	keyring, err := ParseKeyring(spec, activeID)
+	if err != nil {
+		t.Fatalf("ParseKeyring: %v", err)
+	}
+	return keyring
+}
+
+func TestParseKeyring(t *testing.T) {
+	key := base64.StdEncoding.EncodeToString([]byte(strings.Repeat("k", 32)))
+	tests := []struct {
+		name       string
+		spec       string
+		activeID   string
+		wantActive string
+		wantErr    bool
+	}{
+		{"last key is active", "# keys\na:" + key + "\n\nb:" + key, "", "b", false},
+		{"active key chosen", "a:" + key + ",b:" + key, "a", "a", false},
+		{"unknown active key", "a:" + key, "z", "", true},
+		{"duplicate id", "a:" + key + ",a:" + key, "", "", true},
+		{"short key", "a:" + base64.StdEncoding.EncodeToString([]byte("short")), "", "", true},
+		{"not base64", "a:???", "", "", true},
+		{"invalid id", "a b:" + key, "", "", true},
+		{"empty", "# nothing", "", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keyring, err := ParseKeyring(tt.spec, tt.activeID)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseKeyring error = %v, want error %v", err, tt.wantErr)
+			}
+			if err == nil && keyring.ActiveKeyID() != tt.wantActive {
+				t.Errorf("active key = %q, want %q", keyring.ActiveKeyID(), tt.wantActive)
+			}
+		})
+	}
+}
+
+func TestEncryptRoundTrip(t *testing.T) {
+	for _, deterministic := range []bool{false, true} {
+		e := NewEncryptor(testKeyring(t, ""), deterministic)
+		encrypted, err := e.Encrypt("jane@example.com", 42, "Email")
+		if err != nil {
+			t.Fatalf("Encrypt: %v", err)
+		}
+		if strings.Contains(encrypted, "jane") {
+			t.Errorf("ciphertext %q leaks the email", encrypted)
+		}
+		got, err := e.Decrypt(encrypted, e.ActiveKeyID(), 42, "Email")
+		if err != nil || got != "jane@example.com" {
+			t.Errorf("deterministic %v: Decrypt = %q, %v; want the email", deterministic, got, err)
+		}
+	}
+
+	e := NewEncryptor(testKeyring(t, ""), false)
+	if encrypted, err := e.Encrypt("", 42, "Email"); encrypted != "" || err != nil {
+		t.Errorf("Encrypt of no email = %q, %v; want empty", encrypted, err)
+	}
+}
+
+func TestEncryptDeterministic(t *testing.T) {
+	random := NewEncryptor(testKeyring(t, ""), false)
+	a, _ := random.Encrypt("jane@example.com", 42, "Email")
+	b, _ := random.Encrypt("jane@example.com", 42, "Email")
+	if a == b {
+		t.Error("random nonces gave equal ciphertexts")
+	}
+
+	deterministic := NewEncryptor(testKeyring(t, ""), true)
+	a, _ = deterministic.Encrypt("jane@example.com", 42, "Email")
+	b, _ = deterministic.Encrypt("jane@example.com", 42, "Email")
+	if a != b {
+		t.Error("deterministic mode gave different ciphertexts for the same email")
+	}
+
+	// Equal values of two customers can be joined on, but not across columns
+	other, _ := deterministic.Encrypt("jane@example.com", 43, "Email")
+	if other != a {
+		t.Error("deterministic mode gave different ciphertexts for two customers sharing an email")
+	}
+	if got, err := deterministic.Decrypt(other, "k2", 43, "Email"); err != nil || got != "jane@example.com" {
+		t.Errorf("Decrypt of the other customer = %q, %v; want the email", got, err)
+	}
+	contact, _ := deterministic.Encrypt("jane@example.com", 42, "FallbackContact")
+	if contact[:16] == a[:16] {
+		t.Error("deterministic mode reused a nonce for another column")
+	}
+	if _, err := deterministic.Decrypt(a, "k2", 42, "FallbackContact"); err == nil {
+		t.Error("deterministic value copied to another column decrypted")
+	}
+}
+
+func TestDecryptAfterRotation(t *testing.T) {
+	old := NewEncryptor(testKeyring(t, "k1"), false)
+	encrypted, err := old.Encrypt("jane@example.com", 42, "Email")
+	if err != nil {
+		t.Fatalf("Encrypt: %v", err)
+	}
+
+	rotated := NewEncryptor(testKeyring(t, ""), false)
+	if rotated.ActiveKeyID() != "k2" {
+		t.Fatalf("active key = %q, want k2", rotated.ActiveKeyID())
+	}
+	if got, err := rotated.Decrypt(encrypted, "k1", 42, "Email"); err != nil || got != "jane@example.com" {
+		t.Errorf("Decrypt with the retired key = %q, %v; want the email", got, err)
+	}
+	if _, err := rotated.Decrypt(encrypted, "k2", 42, "Email"); err == nil {
+		t.Error("Decrypt succeeded with the wrong key")
+	}
+	if _, err := rotated.Decrypt(encrypted, "k3", 42, "Email"); err == nil {
+		t.Error("Decrypt succeeded with an unknown key")
+	}
+}
+
+func TestDecryptRejectsMovedValues(t *testing.T) {
+	e := NewEncryptor(testKeyring(t, ""), false)
+	encrypted, err := e.Encrypt("jane@example.com", 42, "Email")
+	if err != nil {
+		t.Fatalf("Encrypt: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		value      string
+		customerID int64
+		column     string
+	}{
+		{"copied to another customer", encrypted, 43, "Email"},
+		{"copied to another column", encrypted, 42, "FallbackContact"},
+		{"tampered", encrypted[:len(encrypted)-4] + "AAAA", 42, "Email"},
+		{"not base64", "???", 42, "Email"},
+		{"too short", base64.StdEncoding.EncodeToString([]byte("short")), 42, "Email"},
+	}
+	for _, tt := range tests {
+		if got, err := e.Decrypt(tt.value, "k2", tt.customerID, tt.column); err == nil {
+			t.Errorf("%s: Decrypt = %q, want an error", tt.name, got)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package privacy
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// minKeyLength is the minimum HMAC key size in bytes
+const minKeyLength = 16
+
+// MaskEmail keeps the first character of the local part and the domain,
+// e.g. jean.dupont@gmail.com becomes j***@gmail.com
+func MaskEmail(email string) string {
+	email = strings.TrimSpace(email)
+	if email == "" {
+		return ""
+	}
+	at := strings.LastIndex(email, "@")
+	if at <= 0 {
+		return "***"
+	}
+	return email[:1] + "***" + email[at:]
+}
+
+// Pseudonymizer replaces emails with a keyed hash. Without the key the hash
+// cannot be reversed or recomputed from a guessed address, while the same
+// email always gives the same pseudonym so outputs can still be joined.
+type Pseudonymizer struct {
+	key []byte
+}
+
+func NewPseudonymizer(key string) (*Pseudonymizer, error) {
+	if len(key) < minKeyLength {
+		return nil, fmt.Errorf("HMAC key must be at least %d bytes, got %d", minKeyLength, len(key))
+	}
+	return &Pseudonymizer{key: []byte(key)}, nil
+}
+
+// Pseudonymize returns the hex HMAC-SHA256 of the lowercased email, or "" for no email
+func (p *Pseudonymizer) Pseudonymize(email string) string {
+	email = strings.ToLower(strings.TrimSpace(email))
+	if email == "" {
+		return ""
+	}
+	mac := hmac.New(sha256.New, p.key)
+	mac.Write([]byte(email))
+	return hex.EncodeToString(mac.Sum(nil))
+}
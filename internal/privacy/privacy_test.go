@@ -0,0 +1,50 @@
+package privacy
+
+import "testing"
+
+func TestMaskEmail(t *testing.T) {
+	tests := []struct {
+		email string
+		want  string
+	}{
+		{"jean.dupont@gmail.com", "j***@gmail.com"},
+		{"  a@b.fr ", "a***@b.fr"},
+		{"first@second@example.com", "f***@example.com"},
+		{"@example.com", "***"},
+		{"not-an-email", "***"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := MaskEmail(tt.email); got != tt.want {
+			t.Errorf("MaskEmail(%q) = %q, want %q", tt.email, got, tt.want)
+		}
+	}
+}
+
+func TestPseudonymize(t *testing.T) {
+	if _, err := NewPseudonymizer("too short"); err == nil {
+		t.Error("NewPseudonymizer accepted a key under 16 bytes")
+	}
+	p, err := NewPseudonymizer("0123456789abcdef")
+	if err != nil {
+		t.Fatalf("NewPseudonymizer: %v", err)
+	}
+	other, _ := NewPseudonymizer("fedcba9876543210")
+
+	pseudonym := p.Pseudonymize("Jane@Example.com")
+	if len(pseudonym) != 64 {
+		t.Errorf("pseudonym %q is not a hex SHA-256", pseudonym)
+	}
+	if got := p.Pseudonymize(" jane@example.com "); got != pseudonym {
+		t.Error("case and surrounding spaces change the pseudonym")
+	}
+	if p.Pseudonymize("john@example.com") == pseudonym {
+		t.Error("two emails share a pseudonym")
+	}
+	if other.Pseudonymize("jane@example.com") == pseudonym {
+		t.Error("two keys give the same pseudonym")
+	}
+	if got := p.Pseudonymize("  "); got != "" {
+		t.Errorf("Pseudonymize of no email = %q, want empty", got)
+	}
+}
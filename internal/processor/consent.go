@@ -0,0 +1,55 @@
+package processor
+
+import (
+	"quanticfy-test/internal/models"
+	"quanticfy-test/pkg/logger"
+)
+
+// Consent statuses
+const (
+	ConsentOptIn  = "opt_in"
+	ConsentOptOut = "opt_out"
+)
+
+// ApplyConsent keeps the customers who opted in. A merged identity is kept
+// only if one of its members opted in and none opted out. Customers without
+// a status have not consented and are dropped.
+func (p *Processor) ApplyConsent(
+	customers map[int64]*models.CustomerRevenue,
+	consents map[int64]string,
+) (map[int64]*models.CustomerRevenue, models.ConsentStats) {
+
+	p.log.Info("Filtering customers on marketing consent", logger.Rows(len(customers)))
+
+	kept := make(map[int64]*models.CustomerRevenue, len(customers))
+	var stats models.ConsentStats
+	for id, customer := range customers {
+		ids := customer.MemberIDs
+		if len(ids) == 0 {
+			ids = []int64{customer.CustomerID}
+		}
+
+		optIn, optOut := false, false
+		for _, memberID := range ids {
+			switch consents[memberID] {
+			case ConsentOptIn:
+				optIn = true
+			case ConsentOptOut:
+				optOut = true
+			}
+		}
+
+		switch {
+		case optOut:
+			stats.OptedOut++
+		case optIn:
+			stats.OptedIn++
+			kept[id] = customer
+		default:
+			stats.Unknown++
+		}
+	}
+
+	p.log.Info("Applied marketing consent", "opted_in", stats.OptedIn, "opted_out", stats.OptedOut, "unknown", stats.Unknown)
+	return kept, stats
+}
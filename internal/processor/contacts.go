@@ -0,0 +1,120 @@
+package processor
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"quanticfy-test/internal/models"
+	"quanticfy-test/pkg/logger"
+)
+
+// BuildContactProfiles groups CustomerData rows into one profile per customer.
+// Values are keyed by channel name and ordered most recent first, then by
+// value; duplicates are dropped.
+func (p *Processor) BuildContactProfiles(
+	records []models.CustomerData,
+	channelTypes map[int16]string,
+) map[int64]*models.ContactProfile {
+
+	p.log.Info("Building customer contact profiles")
+	startTime := time.Now()
+
+	sorted := make([]models.CustomerData, len(records))
+	copy(sorted, records)
+	// Rows inserted together are ordered by value, as in LoadCustomerChannel,
+	// then by CustomerChannelID, so the order never depends on the query
+	sort.Slice(sorted, func(i, j int) bool {
+		a, b := sorted[i], sorted[j]
+		if !a.InsertDate.Equal(b.InsertDate) {
+			return a.InsertDate.After(b.InsertDate)
+		}
+		if a.ChannelValue != b.ChannelValue {
+			return a.ChannelValue < b.ChannelValue
+		}
+		return a.CustomerChannelID < b.CustomerChannelID
+	})
+
+	profiles := make(map[int64]*models.ContactProfile)
+	for _, record := range sorted {
+		value := strings.TrimSpace(record.ChannelValue)
+		if value == "" {
+			continue
+		}
+
+		channel, known := channelTypes[record.ChannelTypeID]
+		if !known {
+			channel = fmt.Sprintf("Channel%d", record.ChannelTypeID)
+		}
+
+		profile, exists := profiles[record.CustomerID]
+		if !exists {
+			profile = &models.ContactProfile{
+				CustomerID: record.CustomerID,
+				Channels:   make(map[string][]string),
+			}
+			profiles[record.CustomerID] = profile
+		}
+
+		duplicate := false
+		for _, existing := range profile.Channels[channel] {
+			if existing == value {
+				duplicate = true
+				break
+			}
+		}
+		if !duplicate {
+			profile.Channels[channel] = append(profile.Channels[channel], value)
+		}
+	}
+
+	p.log.Info("Built contact profiles", logger.Rows(len(profiles)), logger.Duration(time.Since(startTime)))
+	return profiles
+}
+
+// AttachContactProfiles links each customer to its contact profile and logs
+// channel coverage for the given channels
+func (p *Processor) AttachContactProfiles(
+	customers map[int64]*models.CustomerRevenue,
+	profiles map[int64]*models.ContactProfile,
+	channels []string,
+) {
+	coverage := make(map[string]int, len(channels))
+	for id, customer := range customers {
+		customer.Contacts = profiles[id]
+		for _, channel := range channels {
+			if customer.Contacts.Value(channel) != "" {
+				coverage[channel]++
+			}
+		}
+	}
+
+	if len(channels) == 0 || len(customers) == 0 {
+		return
+	}
+
+	for _, channel := range channels {
+		p.log.Info("Contact coverage", "channel", channel, "customers", coverage[channel],
+			"rate", float64(coverage[channel])/float64(len(customers)))
+	}
+}
+
+// ResolveChannelNames maps configured channel names to the names in the
+// ChannelType table, case-insensitively
+func ResolveChannelNames(requested []string, channelTypes map[int16]string) ([]string, error) {
+	byLower := make(map[string]string, len(channelTypes))
+	for _, name := range channelTypes {
+		byLower[strings.ToLower(name)] = name
+	}
+
+	resolved := make([]string, 0, len(requested))
+	for _, name := range requested {
+		canonical, ok := byLower[strings.ToLower(strings.TrimSpace(name))]
+		if !ok {
+			return nil, fmt.Errorf("unknown channel type %q", name)
+		}
+		resolved = append(resolved, canonical)
+	}
+	return resolved, nil
+}
@@ -0,0 +1,61 @@
+package processor
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"quanticfy-test/internal/models"
+	"quanticfy-test/pkg/logger"
+)
+
+func TestBuildContactProfiles(t *testing.T) {
+	day := func(n int) time.Time { return time.Date(2024, 1, n, 0, 0, 0, 0, time.UTC) }
+	row := func(id, customerID int64, channelTypeID int16, value string, inserted time.Time) models.CustomerData {
+		return models.CustomerData{CustomerChannelID: id, CustomerID: customerID, ChannelTypeID: channelTypeID, ChannelValue: value, InsertDate: inserted}
+	}
+	records := []models.CustomerData{
+		// 1: two phones inserted together, the value breaks the tie
+		row(10, 1, 2, "+33600000002", day(3)),
+		row(11, 1, 2, "+33600000001", day(3)),
+		row(12, 1, 2, "+33600000003", day(1)),
+		// 2: a newer phone, a duplicate, a blank value and an unknown channel
+		row(20, 2, 2, "+33611111111", day(1)),
+		row(21, 2, 2, "+33622222222", day(4)),
+		row(22, 2, 2, " +33611111111 ", day(2)),
+		row(23, 2, 2, "  ", day(5)),
+		row(24, 2, 9, "token", day(1)),
+	}
+	want := map[int64]map[string][]string{
+		1: {"Phone": {"+33600000001", "+33600000002", "+33600000003"}},
+		2: {"Phone": {"+33622222222", "+33611111111"}, "Channel9": {"token"}},
+	}
+
+	tests := []struct {
+		name    string
+		records []models.CustomerData
+	}{
+		{"as loaded", records},
+		{"reversed", reversed(records)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			profiles := NewProcessor(0.025, logger.Discard()).BuildContactProfiles(tt.records, map[int16]string{2: "Phone"})
+			got := make(map[int64]map[string][]string, len(profiles))
+			for id, profile := range profiles {
+				got[id] = profile.Channels
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("profiles = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func reversed(records []models.CustomerData) []models.CustomerData {
+	out := make([]models.CustomerData, len(records))
+	for i, record := range records {
+		out[len(records)-1-i] = record
+	}
+	return out
+}
@@ -0,0 +1,51 @@
+package processor
+
+import (
+	"quanticfy-test/internal/models"
+)
+
+// FindOrphans returns the CustomerIDs and ContentIDs referenced by events
+// but absent from the Customer and Content dimension tables
+func (p *Processor) FindOrphans(
+	events []models.CustomerEventData,
+	clientCustomerIDs map[int64]int64,
+	clientContentIDs map[int32]int64,
+) ([]int64, []int32) {
+
+	orphanCustomers := make(map[int64]bool)
+	orphanContents := make(map[int32]bool)
+	for _, event := range events {
+		if _, ok := clientCustomerIDs[event.CustomerID]; !ok {
+			orphanCustomers[event.CustomerID] = true
+		}
+		if _, ok := clientContentIDs[event.ContentID]; !ok {
+			orphanContents[event.ContentID] = true
+		}
+	}
+
+	customerIDs := make([]int64, 0, len(orphanCustomers))
+	for id := range orphanCustomers {
+		customerIDs = append(customerIDs, id)
+	}
+	contentIDs := make([]int32, 0, len(orphanContents))
+	for id := range orphanContents {
+		contentIDs = append(contentIDs, id)
+	}
+
+	if len(customerIDs) > 0 || len(contentIDs) > 0 {
+		p.log.Warn("Found orphan IDs in purchase events",
+			"orphan_customers", len(customerIDs), "orphan_contents", len(contentIDs))
+	}
+
+	return customerIDs, contentIDs
+}
+
+// AttachClientCustomerIDs sets ClientCustomerID on each customer from the Customer dimension
+func (p *Processor) AttachClientCustomerIDs(
+	customers map[int64]*models.CustomerRevenue,
+	clientCustomerIDs map[int64]int64,
+) {
+	for id, customer := range customers {
+		customer.ClientCustomerID = clientCustomerIDs[id]
+	}
+}
@@ -0,0 +1,107 @@
+package processor
+
+import (
+	"fmt"
+
+	"quanticfy-test/internal/models"
+	"quanticfy-test/pkg/logger"
+)
+
+// Policies for top customers that have no email address
+const (
+	// EmailPolicyPlaceholder exports them with a shared placeholder address
+	EmailPolicyPlaceholder = "placeholder"
+	// EmailPolicyExclude leaves them out of the export
+	EmailPolicyExclude = "exclude"
+	// EmailPolicyNull exports them with a NULL Email
+	EmailPolicyNull = "null"
+	// EmailPolicyFallback exports them with a contact from another channel, or NULL if none
+	EmailPolicyFallback = "fallback"
+	// EmailPolicyUnreachable routes them to a separate "unreachable VIPs" output
+	EmailPolicyUnreachable = "unreachable"
+)
+
+// EmailPolicy decides what happens to top customers without an email
+type EmailPolicy struct {
+	Name        string
+	Placeholder string
+	// FallbackChannelTypeIDs are tried in order by the fallback policy
+	FallbackChannelTypeIDs []int16
+}
+
+func (p EmailPolicy) Validate() error {
+	switch p.Name {
+	case EmailPolicyPlaceholder, EmailPolicyExclude, EmailPolicyNull, EmailPolicyUnreachable:
+		return nil
+	case EmailPolicyFallback:
+		if len(p.FallbackChannelTypeIDs) == 0 {
+			return fmt.Errorf("email policy %q needs at least one fallback channel type", p.Name)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown email policy %q", p.Name)
+	}
+}
+
+// ApplyEmailPolicy splits the top customers into those to export and those
+// routed to the unreachable output. fallbacks holds, per channel type, the
+// CustomerData values loaded for the fallback policy.
+func (p *Processor) ApplyEmailPolicy(
+	topCustomers map[int64]*models.CustomerRevenue,
+	policy EmailPolicy,
+	fallbacks map[int16]map[int64]string,
+) (map[int64]*models.CustomerRevenue, map[int64]*models.CustomerRevenue, models.EmailPolicyStats) {
+
+	p.log.Info("Applying email policy", "policy", policy.Name, logger.Rows(len(topCustomers)))
+
+	exported := make(map[int64]*models.CustomerRevenue, len(topCustomers))
+	unreachable := make(map[int64]*models.CustomerRevenue)
+	stats := models.EmailPolicyStats{Policy: policy.Name}
+
+	for id, customer := range topCustomers {
+		if customer.Email != "" {
+			exported[id] = customer
+			stats.WithEmail++
+			continue
+		}
+
+		switch policy.Name {
+		case EmailPolicyExclude:
+			stats.Excluded++
+
+		case EmailPolicyNull:
+			exported[id] = customer
+			stats.Null++
+
+		case EmailPolicyFallback:
+			for _, channelTypeID := range policy.FallbackChannelTypeIDs {
+				if contact := fallbacks[channelTypeID][id]; contact != "" {
+					customer.FallbackChannelTypeID = channelTypeID
+					customer.FallbackContact = contact
+					break
+				}
+			}
+			if customer.FallbackContact != "" {
+				stats.Fallback++
+			} else {
+				stats.Null++
+			}
+			exported[id] = customer
+
+		case EmailPolicyUnreachable:
+			unreachable[id] = customer
+			stats.Unreachable++
+
+		default:
+			customer.Email = policy.Placeholder
+			exported[id] = customer
+			stats.Placeholder++
+		}
+	}
+
+	p.log.Info("Applied email policy", "policy", stats.Policy, "with_email", stats.WithEmail,
+		"placeholder", stats.Placeholder, "null", stats.Null, "fallback", stats.Fallback,
+		"excluded", stats.Excluded, "unreachable", stats.Unreachable)
+
+	return exported, unreachable, stats
+}
@@ -0,0 +1,119 @@
+package processor
+
+import (
+	"testing"
+
+	"quanticfy-test/internal/models"
+	"quanticfy-test/pkg/logger"
+)
+
+func TestEmailPolicyValidate(t *testing.T) {
+	tests := []struct {
+		policy  EmailPolicy
+		wantErr bool
+	}{
+		{EmailPolicy{Name: EmailPolicyPlaceholder}, false},
+		{EmailPolicy{Name: EmailPolicyExclude}, false},
+		{EmailPolicy{Name: EmailPolicyNull}, false},
+		{EmailPolicy{Name: EmailPolicyUnreachable}, false},
+		{EmailPolicy{Name: EmailPolicyFallback, FallbackChannelTypeIDs: []int16{2}}, false},
+		{EmailPolicy{Name: EmailPolicyFallback}, true},
+		{EmailPolicy{Name: "drop"}, true},
+	}
+	for _, tt := range tests {
+		if err := tt.policy.Validate(); (err != nil) != tt.wantErr {
+			t.Errorf("%+v.Validate() = %v, want error %v", tt.policy, err, tt.wantErr)
+		}
+	}
+}
+
+func TestApplyEmailPolicy(t *testing.T) {
+	// 1 has an email; 2 has a phone (type 2); 3 has a postal contact (type 3); 4 has nothing
+	topCustomers := func() map[int64]*models.CustomerRevenue {
+		return map[int64]*models.CustomerRevenue{
+			1: {CustomerID: 1, Email: "jane@example.com"},
+			2: {CustomerID: 2},
+			3: {CustomerID: 3},
+			4: {CustomerID: 4},
+		}
+	}
+	fallbacks := map[int16]map[int64]string{
+		2: {1: "+33600000001", 2: "+33600000002"},
+		3: {2: "1 rue de la Paix", 3: "2 rue de Rivoli"},
+	}
+
+	tests := []struct {
+		name        string
+		policy      EmailPolicy
+		exported    map[int64]string // CustomerID -> Email or FallbackContact
+		unreachable []int64
+		stats       models.EmailPolicyStats
+	}{
+		{
+			name:     "placeholder",
+			policy:   EmailPolicy{Name: EmailPolicyPlaceholder, Placeholder: "no-email@unknown.com"},
+			exported: map[int64]string{1: "jane@example.com", 2: "no-email@unknown.com", 3: "no-email@unknown.com", 4: "no-email@unknown.com"},
+			stats:    models.EmailPolicyStats{Policy: EmailPolicyPlaceholder, WithEmail: 1, Placeholder: 3},
+		},
+		{
+			name:     "exclude",
+			policy:   EmailPolicy{Name: EmailPolicyExclude},
+			exported: map[int64]string{1: "jane@example.com"},
+			stats:    models.EmailPolicyStats{Policy: EmailPolicyExclude, WithEmail: 1, Excluded: 3},
+		},
+		{
+			name:     "null",
+			policy:   EmailPolicy{Name: EmailPolicyNull},
+			exported: map[int64]string{1: "jane@example.com", 2: "", 3: "", 4: ""},
+			stats:    models.EmailPolicyStats{Policy: EmailPolicyNull, WithEmail: 1, Null: 3},
+		},
+		{
+			name:     "fallback in channel order",
+			policy:   EmailPolicy{Name: EmailPolicyFallback, FallbackChannelTypeIDs: []int16{2, 3}},
+			exported: map[int64]string{1: "jane@example.com", 2: "+33600000002", 3: "2 rue de Rivoli", 4: ""},
+			stats:    models.EmailPolicyStats{Policy: EmailPolicyFallback, WithEmail: 1, Fallback: 2, Null: 1},
+		},
+		{
+			name:     "fallback preferring postal",
+			policy:   EmailPolicy{Name: EmailPolicyFallback, FallbackChannelTypeIDs: []int16{3, 2}},
+			exported: map[int64]string{1: "jane@example.com", 2: "1 rue de la Paix", 3: "2 rue de Rivoli", 4: ""},
+			stats:    models.EmailPolicyStats{Policy: EmailPolicyFallback, WithEmail: 1, Fallback: 2, Null: 1},
+		},
+		{
+			name:        "unreachable",
+			policy:      EmailPolicy{Name: EmailPolicyUnreachable},
+			exported:    map[int64]string{1: "jane@example.com"},
+			unreachable: []int64{2, 3, 4},
+			stats:       models.EmailPolicyStats{Policy: EmailPolicyUnreachable, WithEmail: 1, Unreachable: 3},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exported, unreachable, stats := NewProcessor(0.025, logger.Discard()).ApplyEmailPolicy(topCustomers(), tt.policy, fallbacks)
+			if stats != tt.stats {
+				t.Errorf("stats = %+v, want %+v", stats, tt.stats)
+			}
+			if len(exported) != len(tt.exported) {
+				t.Errorf("exported %d customers, want %d", len(exported), len(tt.exported))
+			}
+			for id, want := range tt.exported {
+				customer, ok := exported[id]
+				if !ok {
+					t.Errorf("customer %d not exported", id)
+					continue
+				}
+				if got := customer.Email + customer.FallbackContact; got != want {
+					t.Errorf("customer %d contact = %q, want %q", id, got, want)
+				}
+			}
+			if len(unreachable) != len(tt.unreachable) {
+				t.Errorf("unreachable = %d customers, want %v", len(unreachable), tt.unreachable)
+			}
+			for _, id := range tt.unreachable {
+				if _, ok := unreachable[id]; !ok {
+					t.Errorf("customer %d not routed to unreachable", id)
+				}
+			}
+		})
+	}
+}
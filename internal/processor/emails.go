@@ -0,0 +1,155 @@
+package processor
+
+import (
+	"fmt"
+	"net/mail"
+	"strings"
+	"time"
+
+	"quanticfy-test/internal/models"
+	"quanticfy-test/pkg/logger"
+)
+
+// Policies for choosing one email when a customer has several
+const (
+	// EmailSelectionRecent keeps the email with the most recent InsertDate
+	EmailSelectionRecent = "recent"
+	// EmailSelectionPrimary keeps the email flagged IsPrimary, else the most recent
+	EmailSelectionPrimary = "primary"
+	// EmailSelectionFrequent keeps the email found on the most rows, else the most recent
+	EmailSelectionFrequent = "frequent"
+)
+
+func ValidateEmailSelection(policy string) error {
+	switch policy {
+	case EmailSelectionRecent, EmailSelectionPrimary, EmailSelectionFrequent:
+		return nil
+	default:
+		return fmt.Errorf("unknown email selection policy %q", policy)
+	}
+}
+
+// NormalizeEmail trims the address, lowercases its domain and checks its syntax.
+// The local part is kept as-is since it may be case-sensitive.
+func NormalizeEmail(raw string) (string, error) {
+	email := strings.TrimSpace(raw)
+	if email == "" {
+		return "", fmt.Errorf("empty address")
+	}
+
+	at := strings.LastIndex(email, "@")
+	if at <= 0 || at == len(email)-1 {
+		return "", fmt.Errorf("missing local part or domain")
+	}
+
+	local, domain := email[:at], strings.ToLower(email[at+1:])
+	if strings.Contains(local, "@") {
+		return "", fmt.Errorf("more than one @")
+	}
+	if !strings.Contains(domain, ".") || strings.HasPrefix(domain, ".") || strings.HasSuffix(domain, ".") ||
+		strings.Contains(domain, "..") {
+		return "", fmt.Errorf("invalid domain %q", domain)
+	}
+
+	email = local + "@" + domain
+	parsed, err := mail.ParseAddress(email)
+	if err != nil || parsed.Address != email || parsed.Name != "" {
+		return "", fmt.Errorf("invalid syntax")
+	}
+
+	return email, nil
+}
+
+// emailCandidate is one normalized email of a customer with its selection criteria
+type emailCandidate struct {
+	email      string
+	rows       int
+	isPrimary  bool
+	lastInsert time.Time
+}
+
+// SelectEmails picks one normalized email per customer according to the
+// selection policy. Rows whose value fails normalization are returned as
+// invalid and never selected.
+func (p *Processor) SelectEmails(
+	records []models.CustomerData,
+	policy string,
+) (map[int64]string, []models.InvalidEmail) {
+
+	p.log.Info("Selecting customer emails", "policy", policy)
+	startTime := time.Now()
+
+	candidates := make(map[int64]map[string]*emailCandidate)
+	var invalid []models.InvalidEmail
+
+	for _, record := range records {
+		email, err := NormalizeEmail(record.ChannelValue)
+		if err != nil {
+			invalid = append(invalid, models.InvalidEmail{
+				CustomerID: record.CustomerID,
+				Value:      record.ChannelValue,
+				Reason:     err.Error(),
+			})
+			continue
+		}
+
+		byEmail, exists := candidates[record.CustomerID]
+		if !exists {
+			byEmail = make(map[string]*emailCandidate)
+			candidates[record.CustomerID] = byEmail
+		}
+
+		candidate, exists := byEmail[email]
+		if !exists {
+			candidate = &emailCandidate{email: email}
+			byEmail[email] = candidate
+		}
+		candidate.rows++
+		candidate.isPrimary = candidate.isPrimary || record.IsPrimary
+		if record.InsertDate.After(candidate.lastInsert) {
+			candidate.lastInsert = record.InsertDate
+		}
+	}
+
+	emails := make(map[int64]string, len(candidates))
+	multiple := 0
+	for customerID, byEmail := range candidates {
+		if len(byEmail) > 1 {
+			multiple++
+		}
+
+		var best *emailCandidate
+		for _, candidate := range byEmail {
+			if best == nil || betterEmail(candidate, best, policy) {
+				best = candidate
+			}
+		}
+		emails[customerID] = best.email
+	}
+
+	p.log.Info("Selected customer emails", logger.Rows(len(emails)), "several_addresses", multiple,
+		"invalid_rows", len(invalid), logger.Duration(time.Since(startTime)))
+
+	return emails, invalid
+}
+
+// betterEmail reports whether a should be preferred over b. Ties fall back to
+// the most recent InsertDate, then to the address itself so the result never
+// depends on row order.
+func betterEmail(a, b *emailCandidate, policy string) bool {
+	switch policy {
+	case EmailSelectionPrimary:
+		if a.isPrimary != b.isPrimary {
+			return a.isPrimary
+		}
+	case EmailSelectionFrequent:
+		if a.rows != b.rows {
+			return a.rows > b.rows
+		}
+	}
+
+	if !a.lastInsert.Equal(b.lastInsert) {
+		return a.lastInsert.After(b.lastInsert)
+	}
+	return a.email < b.email
+}
@@ -0,0 +1,98 @@
+package processor
+
+import (
+	"testing"
+	"time"
+
+	"quanticfy-test/internal/models"
+	"quanticfy-test/pkg/logger"
+)
+
+func TestNormalizeEmail(t *testing.T) {
+	tests := []struct {
+		raw     string
+		want    string
+		wantErr bool
+	}{
+		{raw: "jane.doe@example.com", want: "jane.doe@example.com"},
+		{raw: "  jane.doe@example.com ", want: "jane.doe@example.com"},
+		{raw: "Jane.Doe@EXAMPLE.COM", want: "Jane.Doe@example.com"},
+		{raw: "jane+promo@mail.example.fr", want: "jane+promo@mail.example.fr"},
+		{raw: "", wantErr: true},
+		{raw: "   ", wantErr: true},
+		{raw: "jane.doe", wantErr: true},
+		{raw: "@example.com", wantErr: true},
+		{raw: "jane@", wantErr: true},
+		{raw: "jane@@example.com", wantErr: true},
+		{raw: "jane@localhost", wantErr: true},
+		{raw: "jane@.example.com", wantErr: true},
+		{raw: "jane@example.com.", wantErr: true},
+		{raw: "jane@example..com", wantErr: true},
+		{raw: "jane doe@example.com", wantErr: true},
+		{raw: "Jane <jane@example.com>", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.raw, func(t *testing.T) {
+			got, err := NormalizeEmail(tt.raw)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NormalizeEmail(%q) error = %v, want error %v", tt.raw, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("NormalizeEmail(%q) = %q, want %q", tt.raw, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSelectEmails(t *testing.T) {
+	day := func(n int) time.Time { return time.Date(2024, 1, n, 0, 0, 0, 0, time.UTC) }
+	row := func(customerID int64, email string, primary bool, inserted time.Time) models.CustomerData {
+		return models.CustomerData{CustomerID: customerID, ChannelValue: email, IsPrimary: primary, InsertDate: inserted}
+	}
+	records := []models.CustomerData{
+		// 1: old primary, newer secondary seen twice
+		row(1, "old@example.com", true, day(1)),
+		row(1, "new@example.com", false, day(5)),
+		row(1, " new@EXAMPLE.com", false, day(3)),
+		// 2: two addresses inserted together, the address breaks the tie
+		row(2, "b@example.com", false, day(2)),
+		row(2, "a@example.com", false, day(2)),
+		// 3: only invalid rows
+		row(3, "not-an-email", false, day(1)),
+		// 4: the most recent row is invalid and ignored
+		row(4, "kept@example.com", false, day(1)),
+		row(4, "broken@@example.com", false, day(9)),
+	}
+
+	tests := []struct {
+		policy string
+		want   map[int64]string
+	}{
+		{EmailSelectionRecent, map[int64]string{1: "new@example.com", 2: "a@example.com", 4: "kept@example.com"}},
+		{EmailSelectionPrimary, map[int64]string{1: "old@example.com", 2: "a@example.com", 4: "kept@example.com"}},
+		{EmailSelectionFrequent, map[int64]string{1: "new@example.com", 2: "a@example.com", 4: "kept@example.com"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.policy, func(t *testing.T) {
+			// Reversing the rows must not change the selection
+			reversed := make([]models.CustomerData, len(records))
+			for i, record := range records {
+				reversed[len(records)-1-i] = record
+			}
+			for _, input := range [][]models.CustomerData{records, reversed} {
+				emails, invalid := NewProcessor(0.025, logger.Discard()).SelectEmails(input, tt.policy)
+				if len(emails) != len(tt.want) {
+					t.Errorf("selected %v, want %v", emails, tt.want)
+				}
+				for id, want := range tt.want {
+					if emails[id] != want {
+						t.Errorf("customer %d: selected %q, want %q", id, emails[id], want)
+					}
+				}
+				if len(invalid) != 2 {
+					t.Errorf("got %d invalid rows, want 2", len(invalid))
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,132 @@
+package processor
+
+import (
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"quanticfy-test/internal/models"
+	"quanticfy-test/pkg/logger"
+)
+
+// Exclusion rule types
+const (
+	// ExclusionCustomerID matches one CustomerID
+	ExclusionCustomerID = "customer_id"
+	// ExclusionEmail matches one email address, case-insensitively
+	ExclusionEmail = "email"
+	// ExclusionPattern matches email addresses against a glob such as *@quanticfy.fr
+	ExclusionPattern = "pattern"
+)
+
+// ExclusionCount is the number of customers removed by one rule
+type ExclusionCount struct {
+	Rule      models.ExclusionRule
+	Customers int
+}
+
+// ValidateExclusionRules checks the type and value of every rule
+func ValidateExclusionRules(rules []models.ExclusionRule) error {
+	for _, rule := range rules {
+		switch rule.Type {
+		case ExclusionCustomerID:
+			if _, err := strconv.ParseInt(rule.Value, 10, 64); err != nil {
+				return fmt.Errorf("exclusion rule from %s: invalid CustomerID %q", rule.Source, rule.Value)
+			}
+		case ExclusionEmail:
+			if rule.Value == "" {
+				return fmt.Errorf("exclusion rule from %s: empty email", rule.Source)
+			}
+		case ExclusionPattern:
+			if _, err := path.Match(strings.ToLower(rule.Value), ""); err != nil {
+				return fmt.Errorf("exclusion rule from %s: invalid pattern %q", rule.Source, rule.Value)
+			}
+		default:
+			return fmt.Errorf("exclusion rule from %s: unknown type %q", rule.Source, rule.Type)
+		}
+	}
+	return nil
+}
+
+// ApplyExclusions removes the customers matching any rule from the ranking.
+// A merged identity is excluded when any of its members matches, by
+// CustomerID or by the email selected for that member. Each excluded customer
+// is counted under the first rule it matches.
+func (p *Processor) ApplyExclusions(
+	revenueMap map[int64]*models.CustomerRevenue,
+	rules []models.ExclusionRule,
+	emails map[int64]string,
+) (map[int64]*models.CustomerRevenue, []ExclusionCount) {
+
+	p.log.Info("Applying exclusion rules", "rules", len(rules))
+	startTime := time.Now()
+
+	counts := make([]ExclusionCount, len(rules))
+	for i, rule := range rules {
+		counts[i].Rule = rule
+	}
+
+	kept := make(map[int64]*models.CustomerRevenue, len(revenueMap))
+	for id, customer := range revenueMap {
+		if i := matchExclusion(customer, rules, emails); i >= 0 {
+			counts[i].Customers++
+			continue
+		}
+		kept[id] = customer
+	}
+
+	p.log.Info("Excluded customers", logger.Rows(len(revenueMap)-len(kept)), logger.Duration(time.Since(startTime)))
+	for _, c := range counts {
+		value := c.Rule.Value
+		if c.Rule.Type == ExclusionEmail {
+			value = p.logEmail(value)
+		}
+		p.log.Info("Exclusion rule matched", "type", c.Rule.Type, "value", value, "source", c.Rule.Source, "customers", c.Customers)
+	}
+
+	return kept, counts
+}
+
+// matchExclusion returns the index of the first rule matching the customer, or -1
+func matchExclusion(customer *models.CustomerRevenue, rules []models.ExclusionRule, emails map[int64]string) int {
+	ids := customer.MemberIDs
+	if len(ids) == 0 {
+		ids = []int64{customer.CustomerID}
+	}
+
+	var addresses []string
+	if customer.Email != "" {
+		addresses = append(addresses, strings.ToLower(customer.Email))
+	}
+	for _, id := range ids {
+		if email := emails[id]; email != "" {
+			addresses = append(addresses, strings.ToLower(email))
+		}
+	}
+
+	for i, rule := range rules {
+		switch rule.Type {
+		case ExclusionCustomerID:
+			for _, id := range ids {
+				if strconv.FormatInt(id, 10) == rule.Value {
+					return i
+				}
+			}
+		case ExclusionEmail:
+			for _, address := range addresses {
+				if address == strings.ToLower(rule.Value) {
+					return i
+				}
+			}
+		case ExclusionPattern:
+			for _, address := range addresses {
+				if matched, _ := path.Match(strings.ToLower(rule.Value), address); matched {
+					return i
+				}
+			}
+		}
+	}
+	return -1
+}
@@ -0,0 +1,139 @@
+package processor
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"quanticfy-test/internal/models"
+	"quanticfy-test/pkg/logger"
+)
+
+// unionFind is a disjoint-set forest over CustomerIDs with path compression
+// and union by size
+type unionFind struct {
+	parent map[int64]int64
+	size   map[int64]int
+}
+
+func newUnionFind() *unionFind {
+	return &unionFind{parent: make(map[int64]int64), size: make(map[int64]int)}
+}
+
+func (u *unionFind) add(id int64) {
+	if _, exists := u.parent[id]; !exists {
+		u.parent[id] = id
+		u.size[id] = 1
+	}
+}
+
+func (u *unionFind) find(id int64) int64 {
+	root := id
+	for u.parent[root] != root {
+		root = u.parent[root]
+	}
+	for u.parent[id] != root {
+		next := u.parent[id]
+		u.parent[id] = root
+		id = next
+	}
+	return root
+}
+
+func (u *unionFind) union(a, b int64) {
+	rootA, rootB := u.find(a), u.find(b)
+	if rootA == rootB {
+		return
+	}
+	if u.size[rootA] < u.size[rootB] {
+		rootA, rootB = rootB, rootA
+	}
+	u.parent[rootB] = rootA
+	u.size[rootA] += u.size[rootB]
+}
+
+// NormalizePhone keeps the digits of a phone number and a leading "+"
+func NormalizePhone(raw string) string {
+	var b strings.Builder
+	for i, r := range strings.TrimSpace(raw) {
+		if r >= '0' && r <= '9' || (r == '+' && i == 0) {
+			b.WriteRune(r)
+		}
+	}
+	if b.Len() < 6 {
+		return ""
+	}
+	return b.String()
+}
+
+// ResolveIdentities merges customers that share a normalized email, or a
+// phone number when phones is not nil. Each group is summed under its
+// smallest CustomerID, and MemberIDs lists every merged CustomerID.
+func (p *Processor) ResolveIdentities(
+	revenueMap map[int64]*models.CustomerRevenue,
+	phones map[int64]string,
+) map[int64]*models.CustomerRevenue {
+
+	p.log.Info("Resolving customer identities")
+	startTime := time.Now()
+
+	uf := newUnionFind()
+	firstByKey := make(map[string]int64)
+	link := func(id int64, key string) {
+		if first, seen := firstByKey[key]; seen {
+			uf.union(first, id)
+		} else {
+			firstByKey[key] = id
+		}
+	}
+
+	// Iterate in ID order so the union-find shape does not depend on map order
+	ids := make([]int64, 0, len(revenueMap))
+	for id := range revenueMap {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	for _, id := range ids {
+		uf.add(id)
+		if email := strings.ToLower(revenueMap[id].Email); email != "" {
+			link(id, "email:"+email)
+		}
+		if phones != nil {
+			if phone := NormalizePhone(phones[id]); phone != "" {
+				link(id, "phone:"+phone)
+			}
+		}
+	}
+
+	groups := make(map[int64][]int64)
+	for _, id := range ids {
+		root := uf.find(id)
+		groups[root] = append(groups[root], id)
+	}
+
+	resolved := make(map[int64]*models.CustomerRevenue, len(groups))
+	mergedGroups, mergedCustomers := 0, 0
+	for _, members := range groups {
+		// members are sorted, so the first one is the canonical ID
+		canonical := *revenueMap[members[0]]
+		canonical.MemberIDs = members
+		for _, id := range members[1:] {
+			member := revenueMap[id]
+			canonical.Revenue += member.Revenue
+			if canonical.Email == "" {
+				canonical.Email = member.Email
+			}
+		}
+		if len(members) > 1 {
+			mergedGroups++
+			mergedCustomers += len(members)
+		}
+		resolved[canonical.CustomerID] = &canonical
+	}
+
+	p.log.Info("Resolved customer identities", "merged_customers", mergedCustomers, "identities", mergedGroups,
+		"customers_before", len(revenueMap), "customers_after", len(resolved), logger.Duration(time.Since(startTime)))
+
+	return resolved
+}
@@ -0,0 +1,100 @@
+package processor
+
+import (
+	"reflect"
+	"testing"
+
+	"quanticfy-test/internal/models"
+	"quanticfy-test/pkg/logger"
+)
+
+func TestNormalizePhone(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want string
+	}{
+		{"+33 6 12 34 56 78", "+33612345678"},
+		{"06.12.34.56.78", "0612345678"},
+		{" (01) 23-45 ", "012345"},
+		{"33+612345678", "33612345678"},
+		{"12345", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := NormalizePhone(tt.raw); got != tt.want {
+			t.Errorf("NormalizePhone(%q) = %q, want %q", tt.raw, got, tt.want)
+		}
+	}
+}
+
+func TestResolveIdentities(t *testing.T) {
+	customers := func() map[int64]*models.CustomerRevenue {
+		return map[int64]*models.CustomerRevenue{
+			// 3 and 7 share an email (case-insensitively); 7 and 9 share a phone
+			3: {CustomerID: 3, Email: "jane@example.com", Revenue: 10},
+			7: {CustomerID: 7, Email: "JANE@example.com", Revenue: 20},
+			9: {CustomerID: 9, Revenue: 5},
+			// 4 has no email and no phone
+			4: {CustomerID: 4, Revenue: 1},
+			// 5 and 6 only share a phone written differently
+			5: {CustomerID: 5, Email: "a@example.com", Revenue: 2},
+			6: {CustomerID: 6, Email: "b@example.com", Revenue: 3},
+		}
+	}
+	phones := map[int64]string{7: "06 12 34 56 78", 9: "0612345678", 5: "+33 1 23", 6: "+331-23"}
+
+	tests := []struct {
+		name   string
+		phones map[int64]string
+		want   map[int64][]int64
+		totals map[int64]float64
+	}{
+		{
+			name:   "email only",
+			want:   map[int64][]int64{3: {3, 7}, 9: {9}, 4: {4}, 5: {5}, 6: {6}},
+			totals: map[int64]float64{3: 30, 9: 5, 4: 1, 5: 2, 6: 3},
+		},
+		{
+			name:   "email and phone",
+			phones: phones,
+			want:   map[int64][]int64{3: {3, 7, 9}, 4: {4}, 5: {5, 6}},
+			totals: map[int64]float64{3: 35, 4: 1, 5: 5},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := customers()
+			resolved := NewProcessor(0.025, logger.Discard()).ResolveIdentities(input, tt.phones)
+			if len(resolved) != len(tt.want) {
+				t.Fatalf("got %d identities, want %d", len(resolved), len(tt.want))
+			}
+			for id, members := range tt.want {
+				customer, ok := resolved[id]
+				if !ok {
+					t.Fatalf("identity %d missing", id)
+				}
+				if !reflect.DeepEqual(customer.MemberIDs, members) {
+					t.Errorf("identity %d members = %v, want %v", id, customer.MemberIDs, members)
+				}
+				if customer.Revenue != tt.totals[id] {
+					t.Errorf("identity %d revenue = %v, want %v", id, customer.Revenue, tt.totals[id])
+				}
+			}
+			if input[3].Revenue != 10 {
+				t.Errorf("input customer modified: revenue %v, want 10", input[3].Revenue)
+			}
+		})
+	}
+}
+
+func TestResolveIdentitiesKeepsFirstEmail(t *testing.T) {
+	// The canonical customer has no email: it takes the first member's
+	customers := map[int64]*models.CustomerRevenue{
+		1: {CustomerID: 1, Revenue: 1},
+		2: {CustomerID: 2, Email: "x@example.com", Revenue: 1},
+	}
+	resolved := NewProcessor(0.025, logger.Discard()).ResolveIdentities(customers, map[int64]string{1: "0102030405", 2: "01 02 03 04 05"})
+	if got := resolved[1]; got == nil || got.Email != "x@example.com" || got.Revenue != 2 {
+		t.Errorf("got %+v, want customer 1 with x@example.com and revenue 2", got)
+	}
+}
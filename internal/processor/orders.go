@@ -0,0 +1,124 @@
+package processor
+
+import (
+	"sort"
+	"time"
+
+	"quanticfy-test/internal/models"
+	"quanticfy-test/pkg/logger"
+)
+
+// CalculateOrders groups purchase lines by EventID into orders, sorted by EventID.
+// Prices follow CalculateCustomerRevenue: unknown ContentIDs count as 0.
+func (p *Processor) CalculateOrders(
+	events []models.CustomerEventData,
+	prices map[int32]float64,
+) []models.OrderRevenue {
+
+	p.log.Info("Calculating order-level revenue")
+	startTime := time.Now()
+
+	orderMap := make(map[int64]*models.OrderRevenue)
+	task := p.progress.Start("Grouping orders", len(events))
+
+	for _, event := range events {
+		lineRevenue := float64(event.Quantity) * prices[event.ContentID]
+
+		order, exists := orderMap[event.EventID]
+		if !exists {
+			order = &models.OrderRevenue{
+				EventID:    event.EventID,
+				CustomerID: event.CustomerID,
+				OrderDate:  event.EventDate,
+			}
+			orderMap[event.EventID] = order
+		}
+
+		order.Lines++
+		order.Items += int(event.Quantity)
+		order.Revenue += lineRevenue
+		if event.EventDate.Before(order.OrderDate) {
+			order.OrderDate = event.EventDate
+		}
+		task.Add(1)
+	}
+
+	orders := make([]models.OrderRevenue, 0, len(orderMap))
+	for _, order := range orderMap {
+		orders = append(orders, *order)
+	}
+	sort.Slice(orders, func(i, j int) bool {
+		return orders[i].EventID < orders[j].EventID
+	})
+
+	task.Finish()
+	p.log.Info("Grouped purchase lines into orders", "lines", len(events), logger.Rows(len(orders)),
+		logger.Duration(time.Since(startTime)))
+
+	return orders
+}
+
+// CalculateOrderMetrics summarizes orders per CustomerID
+func (p *Processor) CalculateOrderMetrics(
+	orders []models.OrderRevenue,
+) map[int64]*models.CustomerOrderMetrics {
+
+	metrics := make(map[int64]*models.CustomerOrderMetrics)
+	for _, order := range orders {
+		single := models.CustomerOrderMetrics{
+			OrderCount:     1,
+			TotalRevenue:   order.Revenue,
+			TotalItems:     order.Items,
+			FirstOrderDate: order.OrderDate,
+			LastOrderDate:  order.OrderDate,
+		}
+		if m, exists := metrics[order.CustomerID]; exists {
+			mergeOrderMetrics(m, &single)
+		} else {
+			metrics[order.CustomerID] = &single
+		}
+	}
+	return metrics
+}
+
+// mergeOrderMetrics adds src into dst
+func mergeOrderMetrics(dst, src *models.CustomerOrderMetrics) {
+	dst.OrderCount += src.OrderCount
+	dst.TotalRevenue += src.TotalRevenue
+	dst.TotalItems += src.TotalItems
+	if src.FirstOrderDate.Before(dst.FirstOrderDate) {
+		dst.FirstOrderDate = src.FirstOrderDate
+	}
+	if src.LastOrderDate.After(dst.LastOrderDate) {
+		dst.LastOrderDate = src.LastOrderDate
+	}
+}
+
+// AttachOrderMetrics sets Orders on each customer. Customers merged by
+// identity resolution get the combined metrics of all their members.
+func (p *Processor) AttachOrderMetrics(
+	customers map[int64]*models.CustomerRevenue,
+	metrics map[int64]*models.CustomerOrderMetrics,
+) {
+	for id, customer := range customers {
+		members := customer.MemberIDs
+		if len(members) == 0 {
+			members = []int64{id}
+		}
+
+		var combined *models.CustomerOrderMetrics
+		for _, memberID := range members {
+			m, ok := metrics[memberID]
+			if !ok {
+				continue
+			}
+			if combined == nil {
+				copied := *m
+				combined = &copied
+			} else {
+				mergeOrderMetrics(combined, m)
+			}
+		}
+		customer.Orders = combined
+	}
+}
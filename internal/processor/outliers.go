@@ -0,0 +1,284 @@
+package processor
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"time"
+
+	"quanticfy-test/internal/models"
+	"quanticfy-test/internal/privacy"
+	"quanticfy-test/pkg/logger"
+)
+
+// Outlier detection methods, applied to log(revenue)
+const (
+	OutlierMethodNone = "none"
+	// OutlierMethodIQR flags values above Q3 + threshold * IQR
+	OutlierMethodIQR = "iqr"
+	// OutlierMethodMAD flags values whose modified z-score (median and median
+	// absolute deviation) exceeds the threshold
+	OutlierMethodMAD = "mad"
+	// OutlierMethodZScore flags values more than threshold standard deviations above the mean
+	OutlierMethodZScore = "zscore"
+)
+
+// defaultOutlierThresholds are used when OutlierPolicy.Threshold is 0
+var defaultOutlierThresholds = map[string]float64{
+	OutlierMethodIQR:    3,
+	OutlierMethodMAD:    3.5,
+	OutlierMethodZScore: 3,
+}
+
+// OutlierPolicy configures revenue outlier detection
+type OutlierPolicy struct {
+	Method    string
+	Threshold float64
+	// MaxEventQuantity flags customers with a single purchase line above this quantity (0 disables)
+	MaxEventQuantity int
+	// Exclude removes flagged customers from the ranking
+	Exclude bool
+}
+
+func (p OutlierPolicy) Validate() error {
+	switch p.Method {
+	case OutlierMethodNone, OutlierMethodIQR, OutlierMethodMAD, OutlierMethodZScore:
+	default:
+		return fmt.Errorf("unknown outlier method %q", p.Method)
+	}
+	if p.Threshold < 0 {
+		return fmt.Errorf("outlier threshold must not be negative, got %v", p.Threshold)
+	}
+	if p.MaxEventQuantity < 0 {
+		return fmt.Errorf("outlier max event quantity must not be negative, got %d", p.MaxEventQuantity)
+	}
+	return nil
+}
+
+// Enabled reports whether any outlier rule is configured
+func (p OutlierPolicy) Enabled() bool {
+	return p.Method != OutlierMethodNone || p.MaxEventQuantity > 0
+}
+
+// OutlierReport lists the customers flagged for review
+type OutlierReport struct {
+	GeneratedAt time.Time `json:"generated_at"`
+	Method      string    `json:"method"`
+	Threshold   float64   `json:"threshold"`
+	// UpperBound is the revenue above which the statistical rule flags a customer
+	UpperBound       float64                  `json:"upper_bound,omitempty"`
+	MaxEventQuantity int                      `json:"max_event_quantity,omitempty"`
+	Customers        int                      `json:"customers"`
+	Excluded         bool                     `json:"excluded"`
+	Outliers         []models.OutlierCustomer `json:"outliers"`
+}
+
+// DetectOutliers flags unusually high revenues and bulk purchase lines.
+// Only the upper tail is flagged: low spenders never reach the top quantile.
+func (p *Processor) DetectOutliers(
+	revenueMap map[int64]*models.CustomerRevenue,
+	events []models.CustomerEventData,
+	policy OutlierPolicy,
+) *OutlierReport {
+
+	threshold := policy.Threshold
+	if threshold == 0 {
+		threshold = defaultOutlierThresholds[policy.Method]
+	}
+
+	p.log.Info("Detecting revenue outliers", "method", policy.Method, "threshold", threshold,
+		"max_event_quantity", policy.MaxEventQuantity)
+	startTime := time.Now()
+
+	report := &OutlierReport{
+		GeneratedAt:      startTime,
+		Method:           policy.Method,
+		Threshold:        threshold,
+		MaxEventQuantity: policy.MaxEventQuantity,
+		Customers:        len(revenueMap),
+		Excluded:         policy.Exclude,
+	}
+	flagged := make(map[int64]*models.OutlierCustomer)
+	flag := func(customer *models.CustomerRevenue, reason string) *models.OutlierCustomer {
+		outlier, exists := flagged[customer.CustomerID]
+		if !exists {
+			outlier = &models.OutlierCustomer{
+				CustomerID: customer.CustomerID,
+				Email:      customer.Email,
+				Revenue:    customer.Revenue,
+			}
+			flagged[customer.CustomerID] = outlier
+		}
+		outlier.Reasons = append(outlier.Reasons, reason)
+		return outlier
+	}
+
+	if policy.Method != OutlierMethodNone {
+		values := make([]float64, 0, len(revenueMap))
+		for _, customer := range revenueMap {
+			if customer.Revenue > 0 {
+				values = append(values, math.Log(customer.Revenue))
+			}
+		}
+		sort.Float64s(values)
+
+		if score, bound, ok := outlierScorer(values, policy.Method, threshold); ok {
+			report.UpperBound = math.Exp(bound)
+			for _, customer := range revenueMap {
+				if customer.Revenue <= 0 {
+					continue
+				}
+				if s := score(math.Log(customer.Revenue)); s > threshold {
+					outlier := flag(customer, fmt.Sprintf("%s score %.2f above %.2f", policy.Method, s, threshold))
+					outlier.Score = s
+				}
+			}
+		} else {
+			p.log.Warn("Revenue spread too small for statistical outlier detection")
+		}
+	}
+
+	if policy.MaxEventQuantity > 0 {
+		owner := make(map[int64]*models.CustomerRevenue, len(revenueMap))
+		for _, customer := range revenueMap {
+			owner[customer.CustomerID] = customer
+			for _, id := range customer.MemberIDs {
+				owner[id] = customer
+			}
+		}
+
+		maxQuantity := make(map[int64]int16)
+		for _, event := range events {
+			customer, ok := owner[event.CustomerID]
+			if ok && int(event.Quantity) > policy.MaxEventQuantity && event.Quantity > maxQuantity[customer.CustomerID] {
+				maxQuantity[customer.CustomerID] = event.Quantity
+			}
+		}
+		for id, quantity := range maxQuantity {
+			outlier := flag(owner[id], fmt.Sprintf("single event quantity %d above %d", quantity, policy.MaxEventQuantity))
+			outlier.MaxEventQuantity = int(quantity)
+		}
+	}
+
+	for _, outlier := range flagged {
+		report.Outliers = append(report.Outliers, *outlier)
+	}
+	sort.Slice(report.Outliers, func(i, j int) bool {
+		if report.Outliers[i].Revenue != report.Outliers[j].Revenue {
+			return report.Outliers[i].Revenue > report.Outliers[j].Revenue
+		}
+		return report.Outliers[i].CustomerID < report.Outliers[j].CustomerID
+	})
+
+	p.log.Info("Flagged revenue outliers", logger.Rows(len(report.Outliers)), "customers", len(revenueMap),
+		logger.Duration(time.Since(startTime)))
+	for _, outlier := range report.Outliers {
+		p.log.Info("Revenue outlier", "customer_id", outlier.CustomerID, "revenue", outlier.Revenue, "reasons", outlier.Reasons)
+	}
+
+	return report
+}
+
+// outlierScorer returns the score function of the method over sorted values
+// and the value at which the score reaches threshold. ok is false when the
+// spread of the values is zero.
+func outlierScorer(values []float64, method string, threshold float64) (score func(float64) float64, bound float64, ok bool) {
+	if len(values) < 2 {
+		return nil, 0, false
+	}
+
+	switch method {
+	case OutlierMethodIQR:
+		q1, q3 := percentile(values, 0.25), percentile(values, 0.75)
+		iqr := q3 - q1
+		if iqr == 0 {
+			return nil, 0, false
+		}
+		return func(x float64) float64 { return (x - q3) / iqr }, q3 + threshold*iqr, true
+
+	case OutlierMethodMAD:
+		median := percentile(values, 0.5)
+		deviations := make([]float64, len(values))
+		for i, v := range values {
+			deviations[i] = math.Abs(v - median)
+		}
+		sort.Float64s(deviations)
+		mad := percentile(deviations, 0.5)
+		if mad == 0 {
+			return nil, 0, false
+		}
+		// 0.6745 makes the MAD consistent with the standard deviation of a normal distribution
+		return func(x float64) float64 { return 0.6745 * (x - median) / mad }, median + threshold*mad/0.6745, true
+
+	default:
+		mean := 0.0
+		for _, v := range values {
+			mean += v
+		}
+		mean /= float64(len(values))
+		variance := 0.0
+		for _, v := range values {
+			variance += (v - mean) * (v - mean)
+		}
+		std := math.Sqrt(variance / float64(len(values)))
+		if std == 0 {
+			return nil, 0, false
+		}
+		return func(x float64) float64 { return (x - mean) / std }, mean + threshold*std, true
+	}
+}
+
+// percentile interpolates linearly between the closest ranks of sorted values
+func percentile(sorted []float64, p float64) float64 {
+	pos := p * float64(len(sorted)-1)
+	lower := int(math.Floor(pos))
+	if lower >= len(sorted)-1 {
+		return sorted[len(sorted)-1]
+	}
+	return sorted[lower] + (pos-float64(lower))*(sorted[lower+1]-sorted[lower])
+}
+
+// ExcludeOutliers returns revenueMap without the flagged customers
+func (p *Processor) ExcludeOutliers(
+	revenueMap map[int64]*models.CustomerRevenue,
+	report *OutlierReport,
+) map[int64]*models.CustomerRevenue {
+
+	excluded := make(map[int64]bool, len(report.Outliers))
+	for _, outlier := range report.Outliers {
+		excluded[outlier.CustomerID] = true
+	}
+
+	kept := make(map[int64]*models.CustomerRevenue, len(revenueMap))
+	for id, customer := range revenueMap {
+		if !excluded[id] {
+			kept[id] = customer
+		}
+	}
+
+	p.log.Info("Excluded outliers from the ranking", logger.Rows(len(revenueMap)-len(kept)), "customers_left", len(kept))
+	return kept
+}
+
+// MaskEmails masks the emails of the flagged customers, for logs and files
+// that may be shared
+func (r *OutlierReport) MaskEmails() {
+	for i := range r.Outliers {
+		r.Outliers[i].Email = privacy.MaskEmail(r.Outliers[i].Email)
+	}
+}
+
+// WriteJSON writes the review report as indented JSON to path
+func (r *OutlierReport) WriteJSON(path string) error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error encoding outlier report: %w", err)
+	}
+
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("error writing outlier report: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+package processor
+
+import (
+	"math"
+	"testing"
+
+	"quanticfy-test/internal/models"
+	"quanticfy-test/pkg/logger"
+)
+
+func TestPercentile(t *testing.T) {
+	values := []float64{1, 2, 3, 4, 10}
+	tests := []struct {
+		p    float64
+		want float64
+	}{
+		{0, 1},
+		{0.25, 2},
+		{0.5, 3},
+		{0.6, 3.4},
+		{0.9, 7.6},
+		{1, 10},
+	}
+	for _, tt := range tests {
+		if got := percentile(values, tt.p); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("percentile(%v, %v) = %v, want %v", values, tt.p, got, tt.want)
+		}
+	}
+	if got := percentile([]float64{42}, 0.5); got != 42 {
+		t.Errorf("percentile of a single value = %v, want 42", got)
+	}
+}
+
+func TestOutlierScorer(t *testing.T) {
+	values := []float64{1, 2, 3, 4, 5, 6, 7, 8, 9}
+
+	tests := []struct {
+		method    string
+		threshold float64
+		x         float64
+		wantScore float64
+		wantBound float64
+	}{
+		// Q1 = 3, Q3 = 7, IQR = 4
+		{OutlierMethodIQR, 1.5, 15, 2, 13},
+		// median 5, MAD 2
+		{OutlierMethodMAD, 3.5, 9, 0.6745 * 4 / 2, 5 + 3.5*2/0.6745},
+		// mean 5, population std sqrt(60/9)
+		{OutlierMethodZScore, 3, 5 + math.Sqrt(60.0/9), 1, 5 + 3*math.Sqrt(60.0/9)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method, func(t *testing.T) {
+			score, bound, ok := outlierScorer(values, tt.method, tt.threshold)
+			if !ok {
+				t.Fatal("outlierScorer reported no spread")
+			}
+			if got := score(tt.x); math.Abs(got-tt.wantScore) > 1e-9 {
+				t.Errorf("score(%v) = %v, want %v", tt.x, got, tt.wantScore)
+			}
+			if math.Abs(bound-tt.wantBound) > 1e-9 {
+				t.Errorf("bound = %v, want %v", bound, tt.wantBound)
+			}
+			// The bound is where the score reaches the threshold
+			if got := score(bound); math.Abs(got-tt.threshold) > 1e-9 {
+				t.Errorf("score(bound) = %v, want the threshold %v", got, tt.threshold)
+			}
+		})
+	}
+}
+
+func TestOutlierScorerWithoutSpread(t *testing.T) {
+	for _, method := range []string{OutlierMethodIQR, OutlierMethodMAD, OutlierMethodZScore} {
+		for _, values := range [][]float64{{5}, {5, 5, 5, 5}} {
+			if _, _, ok := outlierScorer(values, method, 3); ok {
+				t.Errorf("%s over %v: ok = true, want false", method, values)
+			}
+		}
+	}
+}
+
+func TestDetectOutliers(t *testing.T) {
+	revenueMap := make(map[int64]*models.CustomerRevenue)
+	for id := int64(1); id <= 20; id++ {
+		revenueMap[id] = &models.CustomerRevenue{CustomerID: id, Revenue: 100 + float64(id)}
+	}
+	revenueMap[99] = &models.CustomerRevenue{CustomerID: 99, Revenue: 1e6, MemberIDs: []int64{99, 100}}
+	// A bulk line bought under a merged member ID is attributed to its identity
+	events := []models.CustomerEventData{
+		{CustomerID: 100, Quantity: 500},
+		{CustomerID: 5, Quantity: 40},
+		{CustomerID: 6, Quantity: 2},
+	}
+
+	report := NewProcessor(0.025, logger.Discard()).DetectOutliers(revenueMap, events, OutlierPolicy{
+		Method:           OutlierMethodMAD,
+		MaxEventQuantity: 10,
+	})
+
+	if report.Threshold != defaultOutlierThresholds[OutlierMethodMAD] {
+		t.Errorf("threshold = %v, want the MAD default", report.Threshold)
+	}
+	if len(report.Outliers) != 2 {
+		t.Fatalf("got %d outliers, want 2: %+v", len(report.Outliers), report.Outliers)
+	}
+	// Sorted by revenue, highest first
+	whale, bulk := report.Outliers[0], report.Outliers[1]
+	if whale.CustomerID != 99 || len(whale.Reasons) != 2 || whale.MaxEventQuantity != 500 {
+		t.Errorf("got %+v, want customer 99 flagged by score and quantity 500", whale)
+	}
+	if bulk.CustomerID != 5 || len(bulk.Reasons) != 1 || bulk.MaxEventQuantity != 40 {
+		t.Errorf("got %+v, want customer 5 flagged by quantity 40", bulk)
+	}
+	if report.UpperBound <= 120 || report.UpperBound >= 1e6 {
+		t.Errorf("upper bound %v is not between the regular customers and the outlier", report.UpperBound)
+	}
+}
@@ -1,23 +1,57 @@
 package processor
 
 import (
-	"fmt"
-	"log"
+	"context"
 	"math/rand"
 	"sort"
 	"time"
 
 	"quanticfy-test/internal/models"
+	"quanticfy-test/internal/privacy"
+	"quanticfy-test/internal/quality"
+	"quanticfy-test/pkg/logger"
+	"quanticfy-test/pkg/progress"
+	"quanticfy-test/pkg/tracing"
 
-	"github.com/schollz/progressbar/v3"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 type Processor struct {
 	quantile float64
+	log      *logger.Logger
+	report   *quality.Report
+	// maskEmails hides email local parts in the logs
+	maskEmails bool
+	progress   progress.Reporter
+	// ctx carries the span the processing spans are children of
+	ctx context.Context
 }
 
-func NewProcessor(quantile float64) *Processor {
-	return &Processor{quantile: quantile}
+func NewProcessor(quantile float64, log *logger.Logger) *Processor {
+	return &Processor{quantile: quantile, log: log, progress: progress.Noop(), ctx: context.Background()}
+}
+
+// SetTraceContext makes the processing spans children of the span in ctx
+func (p *Processor) SetTraceContext(ctx context.Context) {
+	p.ctx = ctx
+}
+
+// SetProgress reports the progress of the per-event loops through reporter
+func (p *Processor) SetProgress(reporter progress.Reporter) {
+	p.progress = reporter
+}
+
+// SetEmailMasking makes the processor log emails as j***@domain.com
+func (p *Processor) SetEmailMasking(enabled bool) {
+	p.maskEmails = enabled
+}
+
+// logEmail returns the email as it may appear in the logs
+func (p *Processor) logEmail(email string) string {
+	if p.maskEmails {
+		return privacy.MaskEmail(email)
+	}
+	return email
 }
 
 func (p *Processor) CalculateCustomerRevenue(
@@ -26,17 +60,21 @@ func (p *Processor) CalculateCustomerRevenue(
 	emails map[int64]string,
 ) (map[int64]*models.CustomerRevenue, error) {
 
-	log.Println("[INFO] Calculating customer revenues...")
+	p.log.Info("Calculating customer revenues")
 	startTime := time.Now()
+	_, span := tracing.Start(p.ctx, "CalculateCustomerRevenue", attribute.Int("events", len(events)))
+	defer span.End()
 
 	revenueMap := make(map[int64]*models.CustomerRevenue)
-	bar := progressbar.Default(int64(len(events)), "Processing events")
+	report := quality.NewReport(time.Now())
+	task := p.progress.Start("Processing events", len(events))
 
 	for _, event := range events {
 		price, exists := prices[event.ContentID]
 		if !exists {
 			price = 0
 		}
+		report.RecordEvent(event.ContentID, event.Quantity, event.EventDate, exists)
 
 		eventRevenue := float64(event.Quantity) * price
 
@@ -44,28 +82,33 @@ func (p *Processor) CalculateCustomerRevenue(
 			rev.Revenue += eventRevenue
 		} else {
 			email := emails[event.CustomerID]
-			if email == "" {
-				email = "no-email@unknown.com"
-			}
+			report.RecordCustomer(event.CustomerID, email != "")
 			revenueMap[event.CustomerID] = &models.CustomerRevenue{
 				CustomerID: event.CustomerID,
 				Email:      email,
 				Revenue:    eventRevenue,
 			}
 		}
-		bar.Add(1)
+		task.Add(1)
 	}
 
-	fmt.Println()
-	log.Printf("[INFO] Calculated revenue for %d customers in %v", len(revenueMap), time.Since(startTime))
+	task.Finish()
+	report.Finalize()
+	p.report = report
+	span.SetAttributes(attribute.Int("customers", len(revenueMap)))
+	p.log.Info("Calculated customer revenues", logger.Rows(len(revenueMap)), logger.Duration(time.Since(startTime)))
 
 	p.printRandomEntries(revenueMap, 10)
 
 	return revenueMap, nil
 }
 
+// DataQualityReport returns the metrics collected by the last CalculateCustomerRevenue call
+func (p *Processor) DataQualityReport() *quality.Report {
+	return p.report
+}
+
 func (p *Processor) printRandomEntries(revenueMap map[int64]*models.CustomerRevenue, count int) {
-	log.Printf("[INFO] Printing %d random customer revenue entries:", count)
 
 	customers := make([]*models.CustomerRevenue, 0, len(revenueMap))
 	for _, rev := range revenueMap {
@@ -83,10 +126,10 @@ func (p *Processor) printRandomEntries(revenueMap map[int64]*models.CustomerReve
 	}
 
 	for i := 0; i < printCount; i++ {
-		log.Printf("  CustomerID: %d | Email: %s | Revenue: %.2f",
-			customers[i].CustomerID,
-			customers[i].Email,
-			customers[i].Revenue)
+		p.log.Info("Sample customer revenue",
+			"customer_id", customers[i].CustomerID,
+			"email", p.logEmail(customers[i].Email),
+			"revenue", customers[i].Revenue)
 	}
 }
 
@@ -94,17 +137,22 @@ func (p *Processor) GetTopQuantileCustomers(
 	revenueMap map[int64]*models.CustomerRevenue,
 ) (map[int64]*models.CustomerRevenue, error) {
 
-	log.Printf("[INFO] Identifying top %.1f%% customers by revenue...", p.quantile*100)
+	p.log.Info("Identifying top customers by revenue", "quantile", p.quantile)
 	startTime := time.Now()
+	ctx, span := tracing.Start(p.ctx, "GetTopQuantileCustomers",
+		attribute.Int("customers", len(revenueMap)), attribute.Float64("quantile", p.quantile))
+	defer span.End()
 
 	customers := make([]*models.CustomerRevenue, 0, len(revenueMap))
 	for _, rev := range revenueMap {
 		customers = append(customers, rev)
 	}
 
+	_, sortSpan := tracing.Start(ctx, "sort by revenue", tracing.Rows(len(customers)))
 	sort.Slice(customers, func(i, j int) bool {
 		return customers[i].Revenue > customers[j].Revenue
 	})
+	sortSpan.End()
 
 	topCount := int(float64(len(customers)) * p.quantile)
 	if topCount == 0 && len(customers) > 0 {
@@ -116,32 +164,135 @@ func (p *Processor) GetTopQuantileCustomers(
 		topCustomers[customers[i].CustomerID] = customers[i]
 	}
 
-	log.Printf("[INFO] Found %d top customers (top %.1f%%) in %v",
-		len(topCustomers), p.quantile*100, time.Since(startTime))
+	var threshold float64
+	if topCount > 0 {
+		threshold = customers[topCount-1].Revenue
+	}
+	span.SetAttributes(attribute.Int("top_customers", len(topCustomers)), attribute.Float64("revenue_threshold", threshold))
+	p.log.Info("Found top customers", logger.Rows(len(topCustomers)), "quantile", p.quantile,
+		"revenue_threshold", threshold, logger.Duration(time.Since(startTime)))
 
 	return topCustomers, nil
 }
 
+// RevenueThreshold is the lowest revenue among the top customers, i.e. the
+// revenue needed to enter the top quantile
+func RevenueThreshold(topCustomers map[int64]*models.CustomerRevenue) float64 {
+	var threshold float64
+	first := true
+	for _, customer := range topCustomers {
+		if first || customer.Revenue < threshold {
+			threshold = customer.Revenue
+			first = false
+		}
+	}
+	return threshold
+}
+
+// Watermark is the latest InsertDate of the events, i.e. the most recent
+// source data a run saw (zero without events)
+func Watermark(events []models.CustomerEventData) time.Time {
+	var watermark time.Time
+	for _, event := range events {
+		if event.InsertDate.After(watermark) {
+			watermark = event.InsertDate
+		}
+	}
+	return watermark
+}
+
 func (p *Processor) CalculateQuantileStats(
 	revenueMap map[int64]*models.CustomerRevenue,
 ) ([]models.QuantileStats, error) {
 
-	log.Printf("[INFO] Calculating quantile statistics (quantile=%.3f)...", p.quantile)
+	p.log.Info("Calculating quantile statistics", "quantile", p.quantile)
 	startTime := time.Now()
+	ctx, span := tracing.Start(p.ctx, "CalculateQuantileStats",
+		attribute.Int("customers", len(revenueMap)), attribute.Float64("quantile", p.quantile))
+	defer span.End()
+
+	groups := p.quantileGroups(ctx, revenueMap)
+	stats := make([]models.QuantileStats, 0, len(groups))
+
+	for q, quantileCustomers := range groups {
+		if len(quantileCustomers) == 0 {
+			continue
+		}
+
+		stat := models.QuantileStats{
+			QuantileIndex: q,
+			CustomerCount: len(quantileCustomers),
+			MaxRevenue:    quantileCustomers[0].Revenue,
+			MinRevenue:    quantileCustomers[len(quantileCustomers)-1].Revenue,
+		}
+		addQuantileOrderStats(&stat, quantileCustomers)
+		stats = append(stats, stat)
+	}
+
+	span.SetAttributes(attribute.Int("quantiles", len(stats)))
+	p.log.Info("Calculated quantile statistics", logger.Rows(len(stats)), logger.Duration(time.Since(startTime)))
+
+	for _, stat := range stats {
+		attrs := []any{
+			"quantile_index", stat.QuantileIndex,
+			"from_pct", float64(stat.QuantileIndex) * p.quantile * 100,
+			"to_pct", float64(stat.QuantileIndex+1) * p.quantile * 100,
+			"customers", stat.CustomerCount,
+			"max_revenue", stat.MaxRevenue,
+			"min_revenue", stat.MinRevenue,
+		}
+		if stat.HasOrderMetrics {
+			attrs = append(attrs, "orders", stat.OrderCount,
+				"avg_order_value", stat.AvgOrderValue, "items_per_order", stat.ItemsPerOrder)
+		}
+		p.log.Info("Quantile statistics", attrs...)
+	}
+
+	return stats, nil
+}
+
+// addQuantileOrderStats aggregates the order metrics of a quantile's customers
+func addQuantileOrderStats(stat *models.QuantileStats, customers []*models.CustomerRevenue) {
+	var revenue float64
+	var items int
+	for _, customer := range customers {
+		if customer.Orders == nil {
+			continue
+		}
+		stat.HasOrderMetrics = true
+		stat.OrderCount += customer.Orders.OrderCount
+		revenue += customer.Orders.TotalRevenue
+		items += customer.Orders.TotalItems
+	}
+	if stat.OrderCount > 0 {
+		stat.AvgOrderValue = revenue / float64(stat.OrderCount)
+		stat.ItemsPerOrder = float64(items) / float64(stat.OrderCount)
+	}
+}
+
+// quantileGroups sorts customers by revenue (highest first) and splits them
+// into 1/quantile groups; the last group takes the remainder. Groups may be
+// empty when there are fewer customers than quantiles.
+func (p *Processor) quantileGroups(
+	ctx context.Context,
+	revenueMap map[int64]*models.CustomerRevenue,
+) [][]*models.CustomerRevenue {
 
 	customers := make([]*models.CustomerRevenue, 0, len(revenueMap))
 	for _, rev := range revenueMap {
 		customers = append(customers, rev)
 	}
 
+	_, sortSpan := tracing.Start(ctx, "sort by revenue", tracing.Rows(len(customers)))
 	sort.Slice(customers, func(i, j int) bool {
 		return customers[i].Revenue > customers[j].Revenue
 	})
+	sortSpan.End()
 
 	numQuantiles := int(1.0 / p.quantile)
 	customersPerQuantile := len(customers) / numQuantiles
 
-	stats := make([]models.QuantileStats, 0, numQuantiles)
+	groups := make([][]*models.CustomerRevenue, 0, numQuantiles)
 
 	for q := 0; q < numQuantiles; q++ {
 		startIdx := q * customersPerQuantile
@@ -155,32 +306,8 @@ func (p *Processor) CalculateQuantileStats(
 			break
 		}
 
-		quantileCustomers := customers[startIdx:endIdx]
-		if len(quantileCustomers) == 0 {
-			continue
-		}
-
-		stat := models.QuantileStats{
-			QuantileIndex: q,
-			CustomerCount: len(quantileCustomers),
-			MaxRevenue:    quantileCustomers[0].Revenue,
-			MinRevenue:    quantileCustomers[len(quantileCustomers)-1].Revenue,
-		}
-		stats = append(stats, stat)
-	}
-
-	log.Printf("[INFO] Calculated %d quantile statistics in %v", len(stats), time.Since(startTime))
-
-	log.Println("[INFO] Quantile Statistics:")
-	for _, stat := range stats {
-		log.Printf("  Quantile %d (%.1f%%-%.1f%%): %d customers | Max Revenue: %.2f | Min Revenue: %.2f",
-			stat.QuantileIndex,
-			float64(stat.QuantileIndex)*p.quantile*100,
-			float64(stat.QuantileIndex+1)*p.quantile*100,
-			stat.CustomerCount,
-			stat.MaxRevenue,
-			stat.MinRevenue)
+		groups = append(groups, customers[startIdx:endIdx])
 	}
 
-	return stats, nil
-}
\ No newline at end of file
+	return groups
+}
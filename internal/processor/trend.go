@@ -0,0 +1,121 @@
+package processor
+
+import (
+	"fmt"
+	"time"
+
+	"quanticfy-test/internal/models"
+	"quanticfy-test/internal/window"
+	"quanticfy-test/pkg/logger"
+)
+
+// Trend statuses of top customers
+const (
+	TrendGrowing   = "growing"
+	TrendStable    = "stable"
+	TrendDeclining = "declining"
+	TrendAtRisk    = "at_risk"
+)
+
+// TrendPolicy defines the compared periods and the thresholds of each status
+type TrendPolicy struct {
+	Current  window.Window
+	Previous window.Window
+	// AtRiskDays flags customers whose last purchase is at least this many days
+	// before Current.To, or who bought nothing in the current period
+	AtRiskDays int
+	// GrowingRate and DecliningRate are the growth rates (e.g. 0.1 = 10%) at or
+	// beyond which a customer is growing or declining
+	GrowingRate   float64
+	DecliningRate float64
+}
+
+func (p TrendPolicy) Validate() error {
+	if p.Current.From.IsZero() || p.Current.To.IsZero() || p.Previous.From.IsZero() {
+		return fmt.Errorf("trend periods must be bounded")
+	}
+	if p.AtRiskDays <= 0 {
+		return fmt.Errorf("trend at-risk days must be positive, got %d", p.AtRiskDays)
+	}
+	if p.GrowingRate < 0 || p.DecliningRate < 0 {
+		return fmt.Errorf("trend growing and declining rates must not be negative")
+	}
+	return nil
+}
+
+// CalculateTrends sets Trend on every customer, summing the CustomerIDs merged
+// into it by identity resolution. events must cover both periods.
+func (p *Processor) CalculateTrends(
+	customers map[int64]*models.CustomerRevenue,
+	events []models.CustomerEventData,
+	prices map[int32]float64,
+	policy TrendPolicy,
+) map[string]int {
+
+	p.log.Info("Calculating revenue trends", "current", policy.Current.String(), "previous", policy.Previous.String())
+	startTime := time.Now()
+
+	// Index the members of each customer so events are scanned once
+	owner := make(map[int64]*models.CustomerRevenue, len(customers))
+	for _, customer := range customers {
+		customer.Trend = &models.CustomerTrend{}
+		owner[customer.CustomerID] = customer
+		for _, id := range customer.MemberIDs {
+			owner[id] = customer
+		}
+	}
+
+	asOf := policy.Current.To
+	for _, event := range events {
+		customer, ok := owner[event.CustomerID]
+		if !ok || !event.EventDate.Before(asOf) {
+			continue
+		}
+		trend := customer.Trend
+		amount := float64(event.Quantity) * prices[event.ContentID]
+		if policy.Current.Contains(event.EventDate) {
+			trend.CurrentRevenue += amount
+		} else if policy.Previous.Contains(event.EventDate) {
+			trend.PreviousRevenue += amount
+		}
+		if event.EventDate.After(trend.LastPurchaseDate) {
+			trend.LastPurchaseDate = event.EventDate
+		}
+	}
+
+	statuses := make(map[string]int)
+	for _, customer := range customers {
+		trend := customer.Trend
+		if trend.PreviousRevenue > 0 {
+			trend.GrowthRate = (trend.CurrentRevenue - trend.PreviousRevenue) / trend.PreviousRevenue
+			trend.HasGrowthRate = true
+		}
+		if !trend.LastPurchaseDate.IsZero() {
+			trend.DaysSinceLastPurchase = int(asOf.Sub(trend.LastPurchaseDate).Hours() / 24)
+		}
+		trend.Status = trendStatus(trend, policy)
+		statuses[trend.Status]++
+	}
+
+	p.log.Info("Calculated revenue trends", logger.Rows(len(customers)), logger.Duration(time.Since(startTime)),
+		"growing", statuses[TrendGrowing], "stable", statuses[TrendStable],
+		"declining", statuses[TrendDeclining], "at_risk", statuses[TrendAtRisk])
+
+	return statuses
+}
+
+func trendStatus(trend *models.CustomerTrend, policy TrendPolicy) string {
+	switch {
+	case trend.LastPurchaseDate.IsZero() || trend.CurrentRevenue == 0:
+		return TrendAtRisk
+	case trend.DaysSinceLastPurchase >= policy.AtRiskDays:
+		return TrendAtRisk
+	case !trend.HasGrowthRate || trend.GrowthRate >= policy.GrowingRate:
+		// New spend with nothing in the previous period counts as growth
+		return TrendGrowing
+	case trend.GrowthRate <= -policy.DecliningRate:
+		return TrendDeclining
+	default:
+		return TrendStable
+	}
+}
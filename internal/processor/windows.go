@@ -0,0 +1,80 @@
+package processor
+
+import (
+	"time"
+
+	"quanticfy-test/internal/models"
+	"quanticfy-test/internal/window"
+	"quanticfy-test/pkg/logger"
+)
+
+// FilterEvents keeps the events dated inside the window
+func (p *Processor) FilterEvents(events []models.CustomerEventData, w window.Window) []models.CustomerEventData {
+	filtered := make([]models.CustomerEventData, 0, len(events))
+	for _, event := range events {
+		if w.Contains(event.EventDate) {
+			filtered = append(filtered, event)
+		}
+	}
+	return filtered
+}
+
+// CalculateWindowRevenue sums revenue per CustomerID and window name. Unknown
+// prices count as 0, as in CalculateCustomerRevenue.
+func (p *Processor) CalculateWindowRevenue(
+	events []models.CustomerEventData,
+	prices map[int32]float64,
+	windows []window.Window,
+) map[int64]map[string]float64 {
+
+	p.log.Info("Calculating revenue over analysis windows", "windows", len(windows))
+	startTime := time.Now()
+
+	revenue := make(map[int64]map[string]float64)
+	for _, event := range events {
+		amount := float64(event.Quantity) * prices[event.ContentID]
+		for _, w := range windows {
+			if !w.Contains(event.EventDate) {
+				continue
+			}
+			byWindow, exists := revenue[event.CustomerID]
+			if !exists {
+				byWindow = make(map[string]float64, len(windows))
+				revenue[event.CustomerID] = byWindow
+			}
+			byWindow[w.Name] += amount
+		}
+	}
+
+	for _, w := range windows {
+		p.log.Info("Revenue window", "window", w.Name, "range", w.String())
+	}
+	p.log.Info("Calculated window revenue", logger.Rows(len(revenue)), logger.Duration(time.Since(startTime)))
+
+	return revenue
+}
+
+// AttachWindowRevenue sets WindowRevenue on each customer, summing every
+// CustomerID merged into it by identity resolution. Windows without purchases
+// are set to 0.
+func (p *Processor) AttachWindowRevenue(
+	customers map[int64]*models.CustomerRevenue,
+	windowRevenue map[int64]map[string]float64,
+	windows []window.Window,
+) {
+	for _, customer := range customers {
+		ids := customer.MemberIDs
+		if len(ids) == 0 {
+			ids = []int64{customer.CustomerID}
+		}
+
+		customer.WindowRevenue = make(map[string]float64, len(windows))
+		for _, w := range windows {
+			total := 0.0
+			for _, id := range ids {
+				total += windowRevenue[id][w.Name]
+			}
+			customer.WindowRevenue[w.Name] = total
+		}
+	}
+}
@@ -0,0 +1,255 @@
+package quality
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"quanticfy-test/internal/models"
+	"quanticfy-test/internal/privacy"
+	"quanticfy-test/pkg/logger"
+)
+
+// maxSampleIDs caps the number of example IDs kept in the report
+const maxSampleIDs = 20
+
+// Report holds data-quality metrics collected while computing revenue
+type Report struct {
+	GeneratedAt time.Time `json:"generated_at"`
+
+	TotalEvents    int `json:"total_events"`
+	TotalCustomers int `json:"total_customers"`
+
+	EventsMissingPrice     int     `json:"events_missing_price"`
+	ContentsMissingPrice   int     `json:"contents_missing_price"`
+	MissingPriceContentIDs []int32 `json:"missing_price_content_ids,omitempty"`
+	// MissingPriceClientContentIDs maps MissingPriceContentIDs to their client-side IDs, when known
+	MissingPriceClientContentIDs map[int32]int64 `json:"missing_price_client_content_ids,omitempty"`
+
+	CustomersMissingEmail   int     `json:"customers_missing_email"`
+	MissingEmailCustomerIDs []int64 `json:"missing_email_customer_ids,omitempty"`
+
+	InvalidEmails       int                   `json:"invalid_emails"`
+	InvalidEmailSamples []models.InvalidEmail `json:"invalid_email_samples,omitempty"`
+
+	// Orphans are IDs found in CustomerEventData but missing from the Customer or Content table
+	OrphanCustomers   int     `json:"orphan_customers"`
+	OrphanCustomerIDs []int64 `json:"orphan_customer_ids,omitempty"`
+	OrphanContents    int     `json:"orphan_contents"`
+	OrphanContentIDs  []int32 `json:"orphan_content_ids,omitempty"`
+
+	NonPositiveQuantityEvents int `json:"non_positive_quantity_events"`
+	FutureDatedEvents         int `json:"future_dated_events"`
+
+	missingPriceContents map[int32]bool
+}
+
+// Thresholds are the maximum acceptable rates (0..1). A negative value disables the check.
+type Thresholds struct {
+	MaxMissingPriceRate    float64
+	MaxMissingEmailRate    float64
+	MaxInvalidQuantityRate float64
+	MaxFutureEventRate     float64
+}
+
+// Breach describes one threshold exceeded by the data
+type Breach struct {
+	Metric    string  `json:"metric"`
+	Rate      float64 `json:"rate"`
+	Threshold float64 `json:"threshold"`
+}
+
+func (b Breach) String() string {
+	return fmt.Sprintf("%s %.2f%% exceeds threshold %.2f%%", b.Metric, b.Rate*100, b.Threshold*100)
+}
+
+func NewReport(generatedAt time.Time) *Report {
+	return &Report{
+		GeneratedAt:          generatedAt,
+		missingPriceContents: make(map[int32]bool),
+	}
+}
+
+// RecordEvent counts one purchase line and whether its content had a price
+func (r *Report) RecordEvent(contentID int32, quantity int16, eventDate time.Time, hasPrice bool) {
+	r.TotalEvents++
+
+	if !hasPrice {
+		r.EventsMissingPrice++
+		if !r.missingPriceContents[contentID] {
+			r.missingPriceContents[contentID] = true
+			r.ContentsMissingPrice++
+		}
+	}
+	if quantity <= 0 {
+		r.NonPositiveQuantityEvents++
+	}
+	if eventDate.After(r.GeneratedAt) {
+		r.FutureDatedEvents++
+	}
+}
+
+// RecordCustomer counts one customer seen in the events and whether it has an email
+func (r *Report) RecordCustomer(customerID int64, hasEmail bool) {
+	r.TotalCustomers++
+	if !hasEmail {
+		r.CustomersMissingEmail++
+		r.MissingEmailCustomerIDs = append(r.MissingEmailCustomerIDs, customerID)
+	}
+}
+
+// RecordInvalidEmails counts email rows rejected by normalization
+func (r *Report) RecordInvalidEmails(invalid []models.InvalidEmail) {
+	r.InvalidEmails += len(invalid)
+	for _, email := range invalid {
+		if len(r.InvalidEmailSamples) >= maxSampleIDs {
+			break
+		}
+		r.InvalidEmailSamples = append(r.InvalidEmailSamples, email)
+	}
+}
+
+// RecordOrphans stores the IDs missing from the Customer and Content dimensions
+func (r *Report) RecordOrphans(customerIDs []int64, contentIDs []int32) {
+	sort.Slice(customerIDs, func(i, j int) bool { return customerIDs[i] < customerIDs[j] })
+	sort.Slice(contentIDs, func(i, j int) bool { return contentIDs[i] < contentIDs[j] })
+
+	r.OrphanCustomers = len(customerIDs)
+	r.OrphanContents = len(contentIDs)
+	if len(customerIDs) > maxSampleIDs {
+		customerIDs = customerIDs[:maxSampleIDs]
+	}
+	if len(contentIDs) > maxSampleIDs {
+		contentIDs = contentIDs[:maxSampleIDs]
+	}
+	r.OrphanCustomerIDs = customerIDs
+	r.OrphanContentIDs = contentIDs
+}
+
+// AttachClientContentIDs maps the missing-price content samples to client-side
+// IDs; contents missing from the Content table are left out of the map
+func (r *Report) AttachClientContentIDs(clientContentIDs map[int32]int64) {
+	r.MissingPriceClientContentIDs = make(map[int32]int64)
+	for _, id := range r.MissingPriceContentIDs {
+		if clientID, ok := clientContentIDs[id]; ok {
+			r.MissingPriceClientContentIDs[id] = clientID
+		}
+	}
+}
+
+// MaskEmails masks the invalid email samples, for logs and files that may be shared
+func (r *Report) MaskEmails() {
+	for i := range r.InvalidEmailSamples {
+		r.InvalidEmailSamples[i].Value = privacy.MaskEmail(r.InvalidEmailSamples[i].Value)
+	}
+}
+
+// Finalize sorts and truncates the sample ID lists
+func (r *Report) Finalize() {
+	contentIDs := make([]int32, 0, len(r.missingPriceContents))
+	for id := range r.missingPriceContents {
+		contentIDs = append(contentIDs, id)
+	}
+	sort.Slice(contentIDs, func(i, j int) bool { return contentIDs[i] < contentIDs[j] })
+	if len(contentIDs) > maxSampleIDs {
+		contentIDs = contentIDs[:maxSampleIDs]
+	}
+	r.MissingPriceContentIDs = contentIDs
+
+	sort.Slice(r.MissingEmailCustomerIDs, func(i, j int) bool {
+		return r.MissingEmailCustomerIDs[i] < r.MissingEmailCustomerIDs[j]
+	})
+	if len(r.MissingEmailCustomerIDs) > maxSampleIDs {
+		r.MissingEmailCustomerIDs = r.MissingEmailCustomerIDs[:maxSampleIDs]
+	}
+}
+
+func (r *Report) MissingPriceRate() float64 {
+	return rate(r.EventsMissingPrice, r.TotalEvents)
+}
+
+func (r *Report) MissingEmailRate() float64 {
+	return rate(r.CustomersMissingEmail, r.TotalCustomers)
+}
+
+func (r *Report) InvalidQuantityRate() float64 {
+	return rate(r.NonPositiveQuantityEvents, r.TotalEvents)
+}
+
+func (r *Report) FutureEventRate() float64 {
+	return rate(r.FutureDatedEvents, r.TotalEvents)
+}
+
+func rate(count, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(count) / float64(total)
+}
+
+// Check compares the report against the thresholds and returns every breach
+func (r *Report) Check(t Thresholds) []Breach {
+	checks := []Breach{
+		{Metric: "missing_price_rate", Rate: r.MissingPriceRate(), Threshold: t.MaxMissingPriceRate},
+		{Metric: "missing_email_rate", Rate: r.MissingEmailRate(), Threshold: t.MaxMissingEmailRate},
+		{Metric: "invalid_quantity_rate", Rate: r.InvalidQuantityRate(), Threshold: t.MaxInvalidQuantityRate},
+		{Metric: "future_event_rate", Rate: r.FutureEventRate(), Threshold: t.MaxFutureEventRate},
+	}
+
+	var breaches []Breach
+	for _, c := range checks {
+		if c.Threshold >= 0 && c.Rate > c.Threshold {
+			breaches = append(breaches, c)
+		}
+	}
+	return breaches
+}
+
+// Log writes the report's metrics as one line, plus one line per invalid email sample
+func (r *Report) Log(log *logger.Logger) {
+	log.Info("Data quality report",
+		"events", r.TotalEvents,
+		"events_missing_price", r.EventsMissingPrice,
+		"missing_price_rate", r.MissingPriceRate(),
+		"contents_missing_price", r.ContentsMissingPrice,
+		"customers", r.TotalCustomers,
+		"customers_missing_email", r.CustomersMissingEmail,
+		"missing_email_rate", r.MissingEmailRate(),
+		"invalid_emails", r.InvalidEmails,
+		"orphan_customers", r.OrphanCustomers,
+		"orphan_contents", r.OrphanContents,
+		"non_positive_quantity_events", r.NonPositiveQuantityEvents,
+		"invalid_quantity_rate", r.InvalidQuantityRate(),
+		"future_dated_events", r.FutureDatedEvents,
+		"future_event_rate", r.FutureEventRate())
+	for _, email := range r.InvalidEmailSamples {
+		log.Info("Invalid email sample", "customer_id", email.CustomerID, "value", email.Value, "reason", email.Reason)
+	}
+}
+
+// WriteJSON writes the report as indented JSON to path
+func (r *Report) WriteJSON(path string) error {
+	data, err := json.MarshalIndent(struct {
+		*Report
+		MissingPriceRate    float64 `json:"missing_price_rate"`
+		MissingEmailRate    float64 `json:"missing_email_rate"`
+		InvalidQuantityRate float64 `json:"invalid_quantity_rate"`
+		FutureEventRate     float64 `json:"future_event_rate"`
+	}{
+		Report:              r,
+		MissingPriceRate:    r.MissingPriceRate(),
+		MissingEmailRate:    r.MissingEmailRate(),
+		InvalidQuantityRate: r.InvalidQuantityRate(),
+		FutureEventRate:     r.FutureEventRate(),
+	}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error encoding data quality report: %w", err)
+	}
+
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("error writing data quality report: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,117 @@
+package quality
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestReport has 100 events (5 without price, 2 with a zero quantity, 1
+// in the future) and 20 customers (4 without email)
+func newTestReport() *Report {
+	now := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	r := NewReport(now)
+	for i := 0; i < 100; i++ {
+		quantity := int16(1)
+		if i < 2 {
+			quantity = 0
+		}
+		eventDate := now.Add(-time.Hour)
+		if i == 99 {
+			eventDate = now.Add(time.Hour)
+		}
+		r.RecordEvent(int32(i%3), quantity, eventDate, i%20 != 0)
+	}
+	for id := int64(1); id <= 20; id++ {
+		r.RecordCustomer(id, id%5 != 0)
+	}
+	r.Finalize()
+	return r
+}
+
+func TestReportRates(t *testing.T) {
+	r := newTestReport()
+	tests := []struct {
+		name string
+		got  float64
+		want float64
+	}{
+		{"missing price", r.MissingPriceRate(), 0.05},
+		{"missing email", r.MissingEmailRate(), 0.2},
+		{"invalid quantity", r.InvalidQuantityRate(), 0.02},
+		{"future event", r.FutureEventRate(), 0.01},
+		{"empty report", NewReport(time.Now()).MissingPriceRate(), 0},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s rate = %v, want %v", tt.name, tt.got, tt.want)
+		}
+	}
+
+	// Events 0, 20, 40, 60 and 80 lack a price: contents 0, 2, 1, 0 and 2
+	if r.ContentsMissingPrice != 3 || !reflect.DeepEqual(r.MissingPriceContentIDs, []int32{0, 1, 2}) {
+		t.Errorf("contents missing price = %d %v, want 3 [0 1 2]", r.ContentsMissingPrice, r.MissingPriceContentIDs)
+	}
+	if !reflect.DeepEqual(r.MissingEmailCustomerIDs, []int64{5, 10, 15, 20}) {
+		t.Errorf("customers missing email = %v, want [5 10 15 20]", r.MissingEmailCustomerIDs)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	disabled := Thresholds{MaxMissingPriceRate: -1, MaxMissingEmailRate: -1, MaxInvalidQuantityRate: -1, MaxFutureEventRate: -1}
+
+	tests := []struct {
+		name       string
+		thresholds func(*Thresholds)
+		want       []string
+	}{
+		{"all disabled", func(*Thresholds) {}, nil},
+		{"rate equal to threshold passes", func(t *Thresholds) { t.MaxMissingPriceRate = 0.05 }, nil},
+		{"rate above threshold fails", func(t *Thresholds) { t.MaxMissingPriceRate = 0.04 }, []string{"missing_price_rate"}},
+		{"zero tolerates nothing", func(t *Thresholds) { t.MaxFutureEventRate = 0 }, []string{"future_event_rate"}},
+		{"every breach is returned", func(t *Thresholds) {
+			*t = Thresholds{MaxMissingPriceRate: 0.01, MaxMissingEmailRate: 0.1, MaxInvalidQuantityRate: 0.01, MaxFutureEventRate: 0.5}
+		}, []string{"missing_price_rate", "missing_email_rate", "invalid_quantity_rate"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			thresholds := disabled
+			tt.thresholds(&thresholds)
+			var got []string
+			for _, breach := range newTestReport().Check(thresholds) {
+				got = append(got, breach.Metric)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("breaches = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBreachString(t *testing.T) {
+	b := Breach{Metric: "missing_price_rate", Rate: 0.0216, Threshold: 0.01}
+	if got, want := b.String(), "missing_price_rate 2.16% exceeds threshold 1.00%"; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+}
+
+func TestAttachClientContentIDs(t *testing.T) {
+	r := newTestReport()
+	// Content 1 is an orphan, missing from the Content table
+	r.AttachClientContentIDs(map[int32]int64{0: 500000, 2: 500006, 7: 500021})
+
+	want := map[int32]int64{0: 500000, 2: 500006}
+	if !reflect.DeepEqual(r.MissingPriceClientContentIDs, want) {
+		t.Errorf("client content IDs = %v, want %v", r.MissingPriceClientContentIDs, want)
+	}
+
+	data, err := json.Marshal(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"missing_price_client_content_ids":{"0":500000,"2":500006}`) {
+		t.Errorf("report JSON does not pair content IDs: %s", data)
+	}
+}
@@ -0,0 +1,195 @@
+package window
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DateFormat is the format of explicit window bounds
+const DateFormat = "2006-01-02"
+
+// Window is a half-open [From, To) range of event dates. A zero From or To
+// leaves that side unbounded.
+type Window struct {
+	Name string
+	From time.Time
+	To   time.Time
+}
+
+var (
+	rollingSpec = regexp.MustCompile(`^(\d+)([dwmy])$`)
+	validName   = regexp.MustCompile(`^[A-Za-z0-9_]+$`)
+)
+
+// Between returns the explicit window [from, to); either bound may be zero
+func Between(name string, from, to time.Time) (Window, error) {
+	if !from.IsZero() && !to.IsZero() && !to.After(from) {
+		return Window{}, fmt.Errorf("window %q: end %s must be after start %s",
+			name, to.Format(DateFormat), from.Format(DateFormat))
+	}
+	return Window{Name: name, From: from, To: to}, nil
+}
+
+// Parse reads one window specification, optionally prefixed by "name=":
+//
+//	all                    every event
+//	90d, 12w, 12m, 1y      the last N days, weeks, months or years before the run date
+//	2024-01-01..2024-07-01 an explicit [from, to) range; either side may be empty
+//
+// Rolling windows end at midnight UTC of the run date, so the run day itself
+// is excluded and two runs on the same day use the same range. Without a
+// name prefix the specification itself names the window.
+func Parse(spec string, now time.Time) (Window, error) {
+	spec = strings.TrimSpace(spec)
+	name := spec
+	if before, after, found := strings.Cut(spec, "="); found {
+		name, spec = strings.TrimSpace(before), strings.TrimSpace(after)
+	}
+
+	var w Window
+	var err error
+	switch {
+	case spec == "all":
+		w = Window{}
+	case rollingSpec.MatchString(spec):
+		w, err = rolling(spec, now)
+	case strings.Contains(spec, ".."):
+		w, err = explicit(spec)
+	default:
+		err = fmt.Errorf("expected all, a rolling length such as 90d or 12m, or from..to")
+	}
+	if err != nil {
+		return Window{}, fmt.Errorf("invalid window %q: %w", spec, err)
+	}
+
+	if !validName.MatchString(name) {
+		return Window{}, fmt.Errorf("invalid window name %q: use letters, digits and underscores", name)
+	}
+	w.Name = name
+	return w, nil
+}
+
+// ParseList parses several window specifications and rejects duplicate names
+func ParseList(specs []string, now time.Time) ([]Window, error) {
+	windows := make([]Window, 0, len(specs))
+	seen := make(map[string]bool, len(specs))
+	for _, spec := range specs {
+		w, err := Parse(spec, now)
+		if err != nil {
+			return nil, err
+		}
+		if seen[w.Name] {
+			return nil, fmt.Errorf("window %q is defined twice", w.Name)
+		}
+		seen[w.Name] = true
+		windows = append(windows, w)
+	}
+	return windows, nil
+}
+
+func rolling(spec string, now time.Time) (Window, error) {
+	match := rollingSpec.FindStringSubmatch(spec)
+	n, err := strconv.Atoi(match[1])
+	if err != nil || n <= 0 {
+		return Window{}, fmt.Errorf("length must be a positive number")
+	}
+
+	now = now.UTC()
+	to := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	var from time.Time
+	switch match[2] {
+	case "d":
+		from = to.AddDate(0, 0, -n)
+	case "w":
+		from = to.AddDate(0, 0, -7*n)
+	case "m":
+		from = to.AddDate(0, -n, 0)
+	case "y":
+		from = to.AddDate(-n, 0, 0)
+	}
+	return Window{From: from, To: to}, nil
+}
+
+func explicit(spec string) (Window, error) {
+	fromText, toText, _ := strings.Cut(spec, "..")
+	from, err := parseDate(fromText)
+	if err != nil {
+		return Window{}, err
+	}
+	to, err := parseDate(toText)
+	if err != nil {
+		return Window{}, err
+	}
+	return Between("", from, to)
+}
+
+// parseDate parses a YYYY-MM-DD date; an empty string gives the zero time
+func parseDate(text string) (time.Time, error) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(DateFormat, text)
+}
+
+// ParseDate parses an optional YYYY-MM-DD bound, as used by ANALYSIS_FROM and ANALYSIS_TO
+func ParseDate(text string) (time.Time, error) {
+	t, err := parseDate(text)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date %q, expected YYYY-MM-DD", text)
+	}
+	return t, nil
+}
+
+// IsRolling reports whether spec (without a name) is a rolling length such as 90d
+func IsRolling(spec string) bool {
+	return rollingSpec.MatchString(strings.TrimSpace(spec))
+}
+
+// Contains reports whether t falls inside the window
+func (w Window) Contains(t time.Time) bool {
+	if !w.From.IsZero() && t.Before(w.From) {
+		return false
+	}
+	if !w.To.IsZero() && !t.Before(w.To) {
+		return false
+	}
+	return true
+}
+
+// Union returns the smallest window covering every given window
+func Union(windows ...Window) Window {
+	if len(windows) == 0 {
+		return Window{}
+	}
+	union := windows[0]
+	for _, w := range windows[1:] {
+		if union.From.IsZero() || w.From.IsZero() {
+			union.From = time.Time{}
+		} else if w.From.Before(union.From) {
+			union.From = w.From
+		}
+		if union.To.IsZero() || w.To.IsZero() {
+			union.To = time.Time{}
+		} else if w.To.After(union.To) {
+			union.To = w.To
+		}
+	}
+	union.Name = ""
+	return union
+}
+
+// String formats the window as [from, to) with "-inf" / "+inf" for open sides
+func (w Window) String() string {
+	from, to := "-inf", "+inf"
+	if !w.From.IsZero() {
+		from = w.From.Format(DateFormat)
+	}
+	if !w.To.IsZero() {
+		to = w.To.Format(DateFormat)
+	}
+	return "[" + from + ", " + to + ")"
+}
@@ -0,0 +1,113 @@
+package window
+
+import (
+	"testing"
+	"time"
+)
+
+func date(s string) time.Time {
+	t, err := time.Parse(DateFormat, s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func TestParse(t *testing.T) {
+	// Rolling windows end at midnight UTC of the run date
+	now := time.Date(2024, 3, 31, 15, 4, 5, 0, time.UTC)
+
+	tests := []struct {
+		spec    string
+		want    Window
+		wantErr bool
+	}{
+		{spec: "all", want: Window{Name: "all"}},
+		{spec: "90d", want: Window{Name: "90d", From: date("2024-01-01"), To: date("2024-03-31")}},
+		{spec: "2w", want: Window{Name: "2w", From: date("2024-03-17"), To: date("2024-03-31")}},
+		// One month before March 31 is February 31, which AddDate normalises to March 2
+		{spec: "1m", want: Window{Name: "1m", From: date("2024-03-02"), To: date("2024-03-31")}},
+		{spec: "1y", want: Window{Name: "1y", From: date("2023-03-31"), To: date("2024-03-31")}},
+		{spec: "h1=2024-01-01..2024-07-01", want: Window{Name: "h1", From: date("2024-01-01"), To: date("2024-07-01")}},
+		{spec: " since = 2024-01-01.. ", want: Window{Name: "since", From: date("2024-01-01")}},
+		{spec: "until=..2024-01-01", want: Window{Name: "until", To: date("2024-01-01")}},
+		{spec: "0d", wantErr: true},
+		{spec: "90x", wantErr: true},
+		{spec: "2024-07-01..2024-01-01", wantErr: true},
+		{spec: "2024-01-01..2024-01-01", wantErr: true},
+		{spec: "2024-13-01..", wantErr: true},
+		{spec: "last quarter", wantErr: true},
+		// Unnamed explicit ranges are not valid column names
+		{spec: "2024-01-01..2024-07-01", wantErr: true},
+		{spec: "bad-name=90d", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			got, err := Parse(tt.spec, now)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Parse(%q) error = %v, want error %v", tt.spec, err, tt.wantErr)
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("Parse(%q) = %+v %s, want %+v %s", tt.spec, got, got, tt.want, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseListRejectsDuplicateNames(t *testing.T) {
+	now := time.Date(2024, 3, 31, 0, 0, 0, 0, time.UTC)
+	if _, err := ParseList([]string{"90d", "q=90d", "12m"}, now); err != nil {
+		t.Errorf("ParseList with distinct names: %v", err)
+	}
+	if _, err := ParseList([]string{"90d", "90d"}, now); err == nil {
+		t.Error("ParseList accepted two windows named 90d")
+	}
+}
+
+func TestUnion(t *testing.T) {
+	q1 := Window{Name: "q1", From: date("2024-01-01"), To: date("2024-04-01")}
+	q3 := Window{Name: "q3", From: date("2024-07-01"), To: date("2024-10-01")}
+	since := Window{Name: "since", From: date("2024-02-01")}
+	until := Window{Name: "until", To: date("2024-02-01")}
+
+	tests := []struct {
+		name    string
+		windows []Window
+		want    Window
+	}{
+		{"none", nil, Window{}},
+		{"single", []Window{q1}, Window{From: q1.From, To: q1.To}},
+		{"disjoint", []Window{q3, q1}, Window{From: q1.From, To: q3.To}},
+		{"open end", []Window{q1, since}, Window{From: q1.From}},
+		{"open start", []Window{q3, until}, Window{To: q3.To}},
+		{"unbounded", []Window{since, until}, Window{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Union(tt.windows...); got != tt.want {
+				t.Errorf("Union = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestContains(t *testing.T) {
+	w := Window{From: date("2024-01-01"), To: date("2024-02-01")}
+	tests := []struct {
+		at   time.Time
+		want bool
+	}{
+		{date("2023-12-31").Add(23 * time.Hour), false},
+		{date("2024-01-01"), true},
+		{date("2024-01-31").Add(23 * time.Hour), true},
+		{date("2024-02-01"), false},
+	}
+	for _, tt := range tests {
+		if got := w.Contains(tt.at); got != tt.want {
+			t.Errorf("%s.Contains(%s) = %v, want %v", w, tt.at, got, tt.want)
+		}
+	}
+	if !(Window{}).Contains(date("1970-01-01")) {
+		t.Error("an unbounded window does not contain 1970-01-01")
+	}
+}
@@ -0,0 +1,207 @@
+// Package logger is the structured logger shared by every command and
+// pipeline phase. It wraps log/slog: each line carries the run ID, phases add
+// their name with Phase, and row counts and durations use the Rows and
+// Duration attributes so they can be aggregated from JSON output.
+package logger
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Output formats
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+// Attribute keys shared by the pipeline
+const (
+	RunIDKey    = "run_id"
+	PhaseKey    = "phase"
+	RowsKey     = "rows"
+	DurationKey = "duration"
+	ErrorKey    = "error"
+)
+
+// Options configures a Logger
+type Options struct {
+	// Level is debug, info, warn or error (default info)
+	Level string
+	// Format is text or json (default text)
+	Format string
+	// Output defaults to os.Stderr
+	Output io.Writer
+	// RunID is added to every line when set
+	RunID string
+}
+
+// Logger is a slog.Logger whose With and Phase keep the Logger type
+type Logger struct {
+	*slog.Logger
+	// phase is the name given to Phase, reported to the OnFatal hooks
+	phase string
+}
+
+func New(opts Options) (*Logger, error) {
+	level, err := ParseLevel(opts.Level)
+	if err != nil {
+		return nil, err
+	}
+
+	output := opts.Output
+	if output == nil {
+		output = os.Stderr
+	}
+
+	handlerOptions := &slog.HandlerOptions{Level: level}
+	var handler slog.Handler
+	switch strings.ToLower(opts.Format) {
+	case "", FormatText:
+		handler = slog.NewTextHandler(output, handlerOptions)
+	case FormatJSON:
+		handler = slog.NewJSONHandler(output, handlerOptions)
+	default:
+		return nil, fmt.Errorf("unknown log format %q (expected %s or %s)", opts.Format, FormatText, FormatJSON)
+	}
+
+	l := slog.New(handler)
+	if opts.RunID != "" {
+		l = l.With(RunIDKey, opts.RunID)
+	}
+	return &Logger{Logger: l}, nil
+}
+
+// Default is a text logger at info level on stderr, for use before the
+// configuration is loaded
+func Default() *Logger {
+	return &Logger{Logger: slog.New(slog.NewTextHandler(os.Stderr, nil))}
+}
+
+// Discard drops every line
+func Discard() *Logger {
+	return &Logger{Logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+}
+
+// ParseLevel reads debug, info, warn (or warning) and error; "" is info
+func ParseLevel(level string) (slog.Level, error) {
+	switch strings.ToLower(strings.TrimSpace(level)) {
+	case "debug":
+		return slog.LevelDebug, nil
+	case "", "info":
+		return slog.LevelInfo, nil
+	case "warn", "warning":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	default:
+		return slog.LevelInfo, fmt.Errorf("unknown log level %q (expected debug, info, warn or error)", level)
+	}
+}
+
+// NewRunID returns a sortable, unique run identifier such as
+// 20240101T020000Z-1a2b3c4d
+func NewRunID() string {
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		// Uniqueness then only relies on the timestamp
+		return time.Now().UTC().Format("20060102T150405.000000Z")
+	}
+	return time.Now().UTC().Format("20060102T150405Z") + "-" + hex.EncodeToString(suffix)
+}
+
+// With returns a Logger adding the given attributes to every line
+func (l *Logger) With(args ...any) *Logger {
+	return &Logger{Logger: l.Logger.With(args...), phase: l.phase}
+}
+
+// Phase returns a Logger tagging every line with the pipeline phase
+func (l *Logger) Phase(name string) *Logger {
+	phased := l.With(PhaseKey, name)
+	phased.phase = name
+	return phased
+}
+
+// WithHandler returns a Logger whose handler is wrapped by wrap, for
+// instance to count error lines
+func (l *Logger) WithHandler(wrap func(slog.Handler) slog.Handler) *Logger {
+	return &Logger{Logger: slog.New(wrap(l.Handler())), phase: l.phase}
+}
+
+// Failure is the reason Fatal gives to the OnFatal hooks
+type Failure struct {
+	// Phase is the pipeline phase of the logger, empty outside the phases
+	Phase   string
+	Message string
+	// Err is the Err attribute of the line, if any
+	Err error
+}
+
+func (f Failure) Error() string {
+	if f.Err != nil {
+		return f.Message + ": " + f.Err.Error()
+	}
+	return f.Message
+}
+
+var (
+	fatalHooksMu sync.Mutex
+	fatalHooks   []func(Failure)
+)
+
+// OnFatal registers fn to run before Fatal exits, such as writing the metrics
+// of the failed run. Hooks run in the reverse order of registration.
+func OnFatal(fn func(Failure)) {
+	fatalHooksMu.Lock()
+	defer fatalHooksMu.Unlock()
+	fatalHooks = append(fatalHooks, fn)
+}
+
+// Fatal logs at error level, runs the OnFatal hooks and exits with status 1
+func (l *Logger) Fatal(msg string, args ...any) {
+	l.Error(msg, args...)
+
+	failure := Failure{Phase: l.phase, Message: msg}
+	for _, arg := range args {
+		if attr, ok := arg.(slog.Attr); ok && attr.Key == ErrorKey {
+			failure.Err, _ = attr.Value.Any().(error)
+		}
+	}
+
+	fatalHooksMu.Lock()
+	hooks := fatalHooks
+	fatalHooks = nil
+	fatalHooksMu.Unlock()
+	for i := len(hooks) - 1; i >= 0; i-- {
+		hooks[i](failure)
+	}
+	os.Exit(1)
+}
+
+// SetDefault routes slog's default logger, and with it the standard log
+// package, through l
+func SetDefault(l *Logger) {
+	slog.SetDefault(l.Logger)
+}
+
+// Rows is the number of rows read, computed or written
+func Rows(n int) slog.Attr {
+	return slog.Int(RowsKey, n)
+}
+
+// Duration is the time an operation took
+func Duration(d time.Duration) slog.Attr {
+	return slog.Duration(DurationKey, d)
+}
+
+// Err is the error that made an operation fail
+func Err(err error) slog.Attr {
+	return slog.Any(ErrorKey, err)
+}
@@ -0,0 +1,60 @@
+package progress
+
+import (
+	"io"
+	"time"
+
+	"github.com/schollz/progressbar/v3"
+)
+
+type barReporter struct {
+	output io.Writer
+}
+
+type barTask struct {
+	bar      *progressbar.ProgressBar
+	finished bool
+}
+
+// NewBar draws a terminal progress bar on output, as progressbar.Default does
+func NewBar(output io.Writer) Reporter {
+	return &barReporter{output: output}
+}
+
+func (r *barReporter) Start(description string, total int) Task {
+	bar := progressbar.NewOptions64(
+		int64(total),
+		progressbar.OptionSetDescription(description),
+		progressbar.OptionSetWriter(r.output),
+		progressbar.OptionSetWidth(10),
+		progressbar.OptionThrottle(65*time.Millisecond),
+		progressbar.OptionShowCount(),
+		progressbar.OptionShowIts(),
+		progressbar.OptionOnCompletion(func() {
+			io.WriteString(r.output, "\n")
+		}),
+		progressbar.OptionSpinnerType(14),
+		progressbar.OptionFullWidth(),
+		progressbar.OptionSetRenderBlankState(true),
+	)
+	return &barTask{bar: bar}
+}
+
+func (t *barTask) Add(n int) {
+	if t.finished {
+		return
+	}
+	t.bar.Add(n)
+}
+
+// Finish leaves the bar where it stopped, so a failed operation does not
+// show as complete
+func (t *barTask) Finish() {
+	if t.finished {
+		return
+	}
+	t.finished = true
+	if !t.bar.IsFinished() {
+		t.bar.Exit()
+	}
+}
@@ -0,0 +1,74 @@
+package progress
+
+import (
+	"time"
+
+	"quanticfy-test/pkg/logger"
+)
+
+// DefaultLogInterval is the time between two progress lines of NewLog
+const DefaultLogInterval = 10 * time.Second
+
+type logReporter struct {
+	log      *logger.Logger
+	interval time.Duration
+}
+
+type logTask struct {
+	log      *logger.Logger
+	interval time.Duration
+	total    int
+	done     int
+	started  time.Time
+	lastLine time.Time
+	finished bool
+}
+
+// NewLog writes a progress line every interval (DefaultLogInterval when not
+// positive) instead of redrawing a bar, for output that is not a terminal.
+// Operations shorter than the interval only log at debug level.
+func NewLog(log *logger.Logger, interval time.Duration) Reporter {
+	if interval <= 0 {
+		interval = DefaultLogInterval
+	}
+	return &logReporter{log: log, interval: interval}
+}
+
+func (r *logReporter) Start(description string, total int) Task {
+	now := time.Now()
+	task := &logTask{
+		log:      r.log.With("operation", description),
+		interval: r.interval,
+		total:    total,
+		started:  now,
+		lastLine: now,
+	}
+	task.log.Debug("Progress started", "total", total)
+	return task
+}
+
+func (t *logTask) Add(n int) {
+	if t.finished {
+		return
+	}
+	t.done += n
+	if now := time.Now(); now.Sub(t.lastLine) >= t.interval {
+		t.lastLine = now
+		t.log.Info("Progress", logger.Rows(t.done), "total", t.total, "percent", t.percent(), logger.Duration(now.Sub(t.started)))
+	}
+}
+
+func (t *logTask) Finish() {
+	if t.finished {
+		return
+	}
+	t.finished = true
+	t.log.Debug("Progress finished", logger.Rows(t.done), "total", t.total, logger.Duration(time.Since(t.started)))
+}
+
+func (t *logTask) percent() float64 {
+	if t.total <= 0 {
+		return 100
+	}
+	return float64(int(float64(t.done)*1000/float64(t.total))) / 10
+}
@@ -0,0 +1,139 @@
+package progress
+
+import (
+	"sync"
+	"time"
+)
+
+// Sink receives the progress of every task of a metrics Reporter
+type Sink interface {
+	// Progress is called after each Add with the units done so far
+	Progress(operation string, done, total int)
+	// Finished is called once when the task ends
+	Finished(operation string, done int, elapsed time.Duration)
+}
+
+type metricsReporter struct {
+	next Reporter
+	sink Sink
+}
+
+type metricsTask struct {
+	next      Task
+	sink      Sink
+	operation string
+	total     int
+	done      int
+	started   time.Time
+	finished  bool
+}
+
+// WithMetrics sends the progress of every task to sink in addition to
+// reporting it through next
+func WithMetrics(next Reporter, sink Sink) Reporter {
+	return &metricsReporter{next: next, sink: sink}
+}
+
+func (r *metricsReporter) Start(description string, total int) Task {
+	r.sink.Progress(description, 0, total)
+	return &metricsTask{
+		next:      r.next.Start(description, total),
+		sink:      r.sink,
+		operation: description,
+		total:     total,
+		started:   time.Now(),
+	}
+}
+
+func (t *metricsTask) Add(n int) {
+	if t.finished {
+		return
+	}
+	t.done += n
+	t.sink.Progress(t.operation, t.done, t.total)
+	t.next.Add(n)
+}
+
+func (t *metricsTask) Finish() {
+	if t.finished {
+		return
+	}
+	t.finished = true
+	t.sink.Finished(t.operation, t.done, time.Since(t.started))
+	t.next.Finish()
+}
+
+// Counter is the state of one operation recorded by Counters
+type Counter struct {
+	Operation string
+	Done      int
+	Total     int
+	Elapsed   time.Duration
+	Finished  bool
+}
+
+// Rate is the number of units done per second
+func (c Counter) Rate() float64 {
+	if c.Elapsed <= 0 {
+		return 0
+	}
+	return float64(c.Done) / c.Elapsed.Seconds()
+}
+
+// Counters is an in-memory Sink, safe for concurrent use, keeping the last
+// state of each operation. Operations run several times (one per export
+// table, for instance) add up.
+type Counters struct {
+	mu         sync.Mutex
+	counters   map[string]*Counter
+	order      []string
+	inProgress map[string]Counter
+}
+
+func NewCounters() *Counters {
+	return &Counters{
+		counters:   make(map[string]*Counter),
+		inProgress: make(map[string]Counter),
+	}
+}
+
+func (c *Counters) Progress(operation string, done, total int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.counter(operation)
+	c.inProgress[operation] = Counter{Done: done, Total: total}
+}
+
+func (c *Counters) Finished(operation string, done int, elapsed time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	counter := c.counter(operation)
+	counter.Done += done
+	counter.Total += c.inProgress[operation].Total
+	counter.Elapsed += elapsed
+	counter.Finished = true
+	delete(c.inProgress, operation)
+}
+
+// Snapshot returns the finished operations in the order they first started
+func (c *Counters) Snapshot() []Counter {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	snapshot := make([]Counter, 0, len(c.order))
+	for _, operation := range c.order {
+		if counter := c.counters[operation]; counter.Finished {
+			snapshot = append(snapshot, *counter)
+		}
+	}
+	return snapshot
+}
+
+func (c *Counters) counter(operation string) *Counter {
+	counter, exists := c.counters[operation]
+	if !exists {
+		counter = &Counter{Operation: operation}
+		c.counters[operation] = counter
+		c.order = append(c.order, operation)
+	}
+	return counter
+}
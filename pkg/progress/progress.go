@@ -0,0 +1,81 @@
+// Package progress reports the advancement of long-running operations such as
+// loading events or writing export batches. The pipeline only sees Reporter;
+// the implementation is a terminal bar, periodic log lines, nothing at all,
+// or any of those with metrics on top.
+package progress
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"quanticfy-test/pkg/logger"
+)
+
+// Modes accepted by New
+const (
+	ModeAuto = "auto"
+	ModeBar  = "bar"
+	ModeLog  = "log"
+	ModeNone = "none"
+)
+
+// Reporter starts a Task per tracked operation
+type Reporter interface {
+	// Start begins an operation of total units (rows, events...)
+	Start(description string, total int) Task
+}
+
+// Task is one operation in progress. Tasks are not safe for concurrent use.
+type Task interface {
+	// Add records n more units done
+	Add(n int)
+	// Finish ends the operation; further calls to Add are ignored
+	Finish()
+}
+
+// New returns the Reporter for mode. ModeAuto draws a bar when stderr is a
+// terminal and falls back to log lines every interval otherwise, so cron
+// logs do not fill up with carriage returns.
+func New(mode string, log *logger.Logger, interval time.Duration) (Reporter, error) {
+	switch strings.ToLower(mode) {
+	case "", ModeAuto:
+		if IsTerminal(os.Stderr) {
+			return NewBar(os.Stderr), nil
+		}
+		return NewLog(log, interval), nil
+	case ModeBar:
+		return NewBar(os.Stderr), nil
+	case ModeLog:
+		return NewLog(log, interval), nil
+	case ModeNone:
+		return Noop(), nil
+	default:
+		return nil, fmt.Errorf("unknown progress mode %q (expected %s, %s, %s or %s)", mode, ModeAuto, ModeBar, ModeLog, ModeNone)
+	}
+}
+
+// IsTerminal reports whether f is a character device such as a terminal
+func IsTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+type noopReporter struct{}
+
+type noopTask struct{}
+
+// Noop reports nothing
+func Noop() Reporter {
+	return noopReporter{}
+}
+
+func (noopReporter) Start(string, int) Task { return noopTask{} }
+
+func (noopTask) Add(int) {}
+
+func (noopTask) Finish() {}
@@ -0,0 +1,127 @@
+// Package tracing sets up OpenTelemetry tracing for a run. Spans are written
+// as JSON lines to stdout or to a file so a slow run can be analysed offline;
+// with the none exporter, Start returns no-op spans.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Exporters accepted by Setup
+const (
+	ExporterNone   = "none"
+	ExporterStdout = "stdout"
+	ExporterFile   = "file"
+)
+
+// instrumentationName identifies the spans of this module
+const instrumentationName = "quanticfy-test"
+
+// Options configures Setup
+type Options struct {
+	// Exporter is none, stdout or file
+	Exporter string
+	// File receives the spans, appended, with the file exporter
+	File string
+	// ServiceName and RunID are attached to every span
+	ServiceName string
+	RunID       string
+}
+
+// Setup installs the global tracer provider described by opts. The returned
+// function flushes the pending spans and must be called before exiting.
+func Setup(opts Options) (func(context.Context) error, error) {
+	var output io.Writer
+	var file *os.File
+	switch strings.ToLower(opts.Exporter) {
+	case "", ExporterNone:
+		return func(context.Context) error { return nil }, nil
+	case ExporterStdout:
+		output = os.Stdout
+	case ExporterFile:
+		if opts.File == "" {
+			return nil, fmt.Errorf("the file trace exporter needs a file path")
+		}
+		f, err := os.OpenFile(opts.File, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+		if err != nil {
+			return nil, fmt.Errorf("error opening trace file: %w", err)
+		}
+		output, file = f, f
+	default:
+		return nil, fmt.Errorf("unknown trace exporter %q (expected %s, %s or %s)", opts.Exporter, ExporterNone, ExporterStdout, ExporterFile)
+	}
+
+	exporter, err := stdouttrace.New(stdouttrace.WithWriter(output))
+	if err != nil {
+		return nil, fmt.Errorf("error creating trace exporter: %w", err)
+	}
+
+	attrs := []attribute.KeyValue{attribute.String("service.name", opts.ServiceName)}
+	if opts.RunID != "" {
+		attrs = append(attrs, attribute.String("run.id", opts.RunID))
+	}
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewSchemaless(attrs...)),
+	)
+	otel.SetTracerProvider(provider)
+
+	return func(ctx context.Context) error {
+		err := provider.Shutdown(ctx)
+		if file != nil {
+			if closeErr := file.Close(); err == nil {
+				err = closeErr
+			}
+		}
+		if err != nil {
+			return fmt.Errorf("error flushing traces: %w", err)
+		}
+		return nil
+	}, nil
+}
+
+// Start starts a span named name as a child of the span in ctx
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(instrumentationName).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// StartAt starts a span that began at start, for operations that ran before
+// Setup such as loading the configuration
+func StartAt(ctx context.Context, name string, start time.Time, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(instrumentationName).Start(ctx, name, trace.WithTimestamp(start), trace.WithAttributes(attrs...))
+}
+
+// Record adds the span of an operation that ran from start to end
+func Record(ctx context.Context, name string, start, end time.Time, attrs ...attribute.KeyValue) {
+	_, span := StartAt(ctx, name, start, attrs...)
+	span.End(trace.WithTimestamp(end))
+}
+
+// Fail marks span as failed with err
+func Fail(span trace.Span, err error) {
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
+
+// Rows is the number of rows a span read or wrote
+func Rows(n int) attribute.KeyValue {
+	return attribute.Int("rows", n)
+}
+
+// Table is the database table a span queried
+func Table(name string) attribute.KeyValue {
+	return attribute.String("db.sql.table", name)
+}